@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"partition_growth/quantile"
+)
+
+// ── -age-histogram ────────────────────────────────────────────────────────────
+//
+// The age of a filtered event is -asof minus its date: how stale it was when
+// this run processed it. Ages are bucketed at the -age-buckets boundaries,
+// and the p95 comes from a t-digest so memory stays bounded. Events dated
+// after -asof have a negative age; they get a bucket of their own and are
+// left out of the p95.
+
+const defaultAgeBuckets = "1h,6h,24h,7d"
+
+// parseAgeBuckets reads ascending, positive bucket boundaries such as
+// "1h,6h,24h,7d".
+func parseAgeBuckets(spec string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, tok := range strings.Split(spec, ",") {
+		d, err := parseSpan(strings.TrimSpace(tok))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 || (len(out) > 0 && d <= out[len(out)-1]) {
+			return nil, fmt.Errorf("boundaries must be positive and ascending, got %q", spec)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+type ageHistogram struct {
+	asof   time.Time
+	bounds []time.Duration
+	counts []int // len(bounds)+1: under bounds[0], ..., at or over the last
+	future int
+	digest *quantile.TDigest // ages in seconds, future events excluded
+}
+
+func newAgeHistogram(asof time.Time, bounds []time.Duration) *ageHistogram {
+	return &ageHistogram{asof: asof, bounds: bounds, counts: make([]int, len(bounds)+1),
+		digest: quantile.New(quantile.DefaultCompression)}
+}
+
+func (h *ageHistogram) add(dt time.Time, w int) {
+	age := h.asof.Sub(dt)
+	if age < 0 {
+		h.future += w
+		return
+	}
+	i := 0
+	for i < len(h.bounds) && age >= h.bounds[i] {
+		i++
+	}
+	h.counts[i] += w
+	h.digest.AddWeighted(age.Seconds(), float64(w))
+}
+
+// ageBucket is one row of the histogram in JSON; bounds are in seconds,
+// From inclusive and To exclusive, with To omitted for the open last bucket.
+type ageBucket struct {
+	Label  string   `json:"label"`
+	From   *float64 `json:"from_seconds,omitempty"`
+	To     *float64 `json:"to_seconds,omitempty"`
+	Events int      `json:"events"`
+	Share  float64  `json:"share"`
+}
+
+type ageHistogramData struct {
+	Asof       string      `json:"asof"`
+	Buckets    []ageBucket `json:"buckets"`
+	P95Seconds *float64    `json:"p95_seconds"` // null without past events
+}
+
+// section prints the histogram, and warns on stderr about future events.
+func (h *ageHistogram) section() section {
+	total := h.future
+	for _, n := range h.counts {
+		total += n
+	}
+	s := section{
+		Key:     "age_histogram",
+		Title:   "Event Age at -asof",
+		Columns: []string{"Age", "Events", "Share"},
+	}
+	d := ageHistogramData{Asof: h.asof.Format(time.RFC3339)}
+	row := func(label string, from, to *float64, n int) {
+		s.Rows = append(s.Rows, []string{label, strconv.Itoa(n), pct(n, total)})
+		share := 0.0
+		if total > 0 {
+			share = float64(n) / float64(total)
+		}
+		d.Buckets = append(d.Buckets, ageBucket{label, from, to, n, share})
+	}
+	secs := func(d time.Duration) *float64 {
+		v := d.Seconds()
+		return &v
+	}
+	row("future (after -asof)", nil, secs(0), h.future)
+	for i, n := range h.counts {
+		switch {
+		case i == 0:
+			row("under "+shortSpan(h.bounds[0]), secs(0), secs(h.bounds[0]), n)
+		case i == len(h.bounds):
+			row(shortSpan(h.bounds[i-1])+" and over", secs(h.bounds[i-1]), nil, n)
+		default:
+			row(shortSpan(h.bounds[i-1])+" to "+shortSpan(h.bounds[i]), secs(h.bounds[i-1]), secs(h.bounds[i]), n)
+		}
+	}
+	p95 := "-"
+	if h.digest.Count() > 0 {
+		v := h.digest.Quantile(0.95)
+		d.P95Seconds = &v
+		p95 = shortSpan(time.Duration(v * float64(time.Second)))
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Ages relative to -asof %s; p95 age: %s (t-digest estimate, future events excluded).",
+		d.Asof, p95))
+	if h.future > 0 {
+		fmt.Fprintf(noteOut, "warning: %d matching events are dated after -asof %s\n", h.future, d.Asof)
+		s.Notes = append(s.Notes, fmt.Sprintf("%d events are dated after -asof; check the producer's clock or -tz.", h.future))
+	}
+	s.Data = d
+	return s
+}
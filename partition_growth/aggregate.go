@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// reportOptions carries the command-line settings that reports read.
+type reportOptions struct {
+	loc               *time.Location // -tz; every bucket uses this zone's calendar
+	dates             dateStyle      // -date-style, ISO for JSON output
+	year, month, day  int
+	parentDistBuckets string
+	minParentEvents   int // 0 means each report's default
+	minCohort         int
+	maxDayLeaders     int
+	gap               time.Duration
+	minGap            time.Duration // leader-event-gap -min-gap
+	overlapParents    int           // event-overlap-window -min-parents
+	spikeSigma        float64       // event-spike-context -spike
+	maxSegments       int
+	spillDir          string
+	sortMem           int
+	spillLimit        int64
+	groupTop          int
+	sort              sortOrder // -sort of grouped listings
+	aggregates        []string  // -aggregate functions
+	statsExact        int       // -stats-exact
+
+	asof              time.Time // -asof; checks measure recency against it
+	freshness         time.Duration
+	freshnessCrit     time.Duration
+	freshnessLookback time.Duration
+	ignoreLeaders     patternList
+}
+
+// aggregates holds the per-report aggregations built from filtered events.
+// Maps are nil until a report enables them, so unused reports cost nothing.
+type aggregates struct {
+	opts reportOptions
+
+	// Always collected; see processEvent in main.go for which are filtered.
+	perDay        map[string]int // "YYYY-MM-DD", filtered
+	perDayAll     map[string]int // "YYYY-MM-DD", all events
+	perWeek       map[string]int // "YYYY-Www" (calendar year), filtered
+	perMonth      map[string]int // "YYYY-MM", all events
+	perYear       map[int]int    // all events
+	perQuarter    map[string]int // "YYYY-QN", all events
+	perISOWeekAll map[string]int // "YYYY-Www" (ISO week-year), all events
+
+	perParent     map[int]int // ParentID -> filtered event count
+	parentBuckets []countBucket
+	parentSpans   map[int]*parentSpan // ParentID -> first/last filtered event
+	coActivity    *coActivity
+	overlap       *overlapScan
+	movers        *movers        // -movers
+	parentMonths  map[int]uint16 // ParentID -> bit per active month of -y, all events
+	isoWeekDays   *[54]uint8     // ISO week of -y -> bit per weekday with events, all events
+
+	monthParentCounts  map[string]map[int]int            // "YYYY-MM" -> ParentID -> filtered count
+	parentMonthLeaders map[int]map[string]map[string]int // ParentID -> "YYYY-MM" -> leader -> filtered count
+
+	replay   *replayer // nil unless an ordered report is enabled
+	segments *segmenter
+
+	monthLeaders      map[string]map[string]bool // "YYYY-MM" -> leaders seen, all events
+	leaderDays        map[string]map[string]bool // leader -> "YYYY-MM-DD" days active, filtered
+	monthLeaderCounts map[string]map[string]int  // "YYYY-MM" -> leader -> filtered count
+	leaderDayCounts   map[string]map[string]int  // leader -> "YYYY-MM-DD" -> filtered count
+	leaderParents     map[string]map[int]int     // leader -> ParentID -> filtered count
+	weekLeaders       map[int]map[string]int     // ISO week of -y -> leader -> filtered count
+	leaderCoActivity  *leaderCoActivity
+	leaderGaps        leaderGaps // leader -> longest gap, filtered
+
+	perWeekdayLeader *[7]map[string]int // time.Weekday -> leader -> filtered count
+	perHourWeekday   *[24][7]int        // hour -> weekday (Monday first) -> filtered count
+
+	perDayLeaders    map[string][]string // "YYYY-MM-DD" -> leaders active, filtered, capped
+	dayLeadersCapped map[string]bool     // days that hit -max-day-leaders
+
+	leaderLastSeen map[string]time.Time // leader -> latest event at or before -asof, all events
+
+	checks []checkResult
+
+	recordsAll, recordsFiltered int // events passed to addAll and add, for -telemetry and the "No events matched." notes
+
+	groups *groupCounter // -group-by
+
+	values map[string]valueStats // -aggregate, keyed by valuePeriod
+
+	fieldSummary *fieldSummary // distinct values per field, filtered
+
+	dailyQuantiles *dailyQuantiles // stats report, filtered
+	eventSizes     *eventSizes     // event-size-stats report, filtered
+}
+
+func newAggregates(opts reportOptions) *aggregates {
+	return &aggregates{
+		opts:          opts,
+		perDay:        make(map[string]int),
+		perDayAll:     make(map[string]int),
+		perWeek:       make(map[string]int),
+		perMonth:      make(map[string]int),
+		perYear:       make(map[int]int),
+		perQuarter:    make(map[string]int),
+		perISOWeekAll: make(map[string]int),
+	}
+}
+
+func (a *aggregates) enablePerParent() {
+	if a.perParent == nil {
+		a.perParent = make(map[int]int)
+	}
+}
+
+// enableReplay sets up the shared ordered replay and returns it so the
+// caller can register a consumer.
+func (a *aggregates) enableReplay() *replayer {
+	if a.replay == nil {
+		a.replay = newReplayer(a.opts.spillDir, a.opts.sortMem, a.opts.spillLimit)
+	}
+	return a.replay
+}
+
+func (a *aggregates) enableMonthLeaders() {
+	if a.monthLeaders == nil {
+		a.monthLeaders = make(map[string]map[string]bool)
+	}
+}
+
+func (a *aggregates) enableLeaderDays() {
+	if a.leaderDays == nil {
+		a.leaderDays = make(map[string]map[string]bool)
+	}
+}
+
+// addAll records one event before the -y/-m/-d filters are applied. Reports
+// that compare against history (first-seen, churn) read these aggregations.
+func (a *aggregates) addAll(dt time.Time, evt Event) {
+	a.recordsAll++
+	if a.parentMonths != nil {
+		a.addParentMonth(dt, evt.ParentID)
+	}
+	if a.isoWeekDays != nil {
+		if y, w := dt.ISOWeek(); y == a.opts.year {
+			a.isoWeekDays[w] |= 1 << ((uint(dt.Weekday()) + 6) % 7)
+		}
+	}
+	if a.leaderLastSeen != nil && !dt.After(a.opts.asof) {
+		if last, ok := a.leaderLastSeen[evt.LeaderNodeInfo]; !ok || dt.After(last) {
+			a.leaderLastSeen[evt.LeaderNodeInfo] = dt
+		}
+	}
+	if a.monthLeaders != nil {
+		mk := dt.Format("2006-01")
+		set := a.monthLeaders[mk]
+		if set == nil {
+			set = make(map[string]bool)
+			a.monthLeaders[mk] = set
+		}
+		set[evt.LeaderNodeInfo] = true
+	}
+}
+
+// add records one event that passed the -y/-m/-d filters. Count maps add
+// evt.Weight, which is 1 unless -weight-field is set.
+func (a *aggregates) add(dt time.Time, evt Event) {
+	a.recordsFiltered++
+	if a.perParent != nil {
+		a.perParent[evt.ParentID] += evt.Weight
+	}
+	if a.parentSpans != nil {
+		a.addParentSpan(dt, evt.ParentID)
+	}
+	if a.parentMonthLeaders != nil {
+		a.addParentMonthLeader(dt, evt)
+	}
+	if a.monthParentCounts != nil {
+		mk := dt.Format("2006-01")
+		m := a.monthParentCounts[mk]
+		if m == nil {
+			m = make(map[int]int)
+			a.monthParentCounts[mk] = m
+		}
+		m[evt.ParentID] += evt.Weight
+	}
+	if a.leaderDays != nil {
+		set := a.leaderDays[evt.LeaderNodeInfo]
+		if set == nil {
+			set = make(map[string]bool)
+			a.leaderDays[evt.LeaderNodeInfo] = set
+		}
+		set[dt.Format("2006-01-02")] = true
+	}
+	if a.movers != nil {
+		a.movers.add(dt.Format("2006-01"), evt)
+	}
+	if a.leaderDayCounts != nil {
+		m := a.leaderDayCounts[evt.LeaderNodeInfo]
+		if m == nil {
+			m = make(map[string]int)
+			a.leaderDayCounts[evt.LeaderNodeInfo] = m
+		}
+		m[dt.Format("2006-01-02")] += evt.Weight
+	}
+	if a.weekLeaders != nil {
+		if y, w := dt.ISOWeek(); y == a.opts.year {
+			m := a.weekLeaders[w]
+			if m == nil {
+				m = make(map[string]int)
+				a.weekLeaders[w] = m
+			}
+			m[evt.LeaderNodeInfo] += evt.Weight
+		}
+	}
+	if a.leaderParents != nil {
+		m := a.leaderParents[evt.LeaderNodeInfo]
+		if m == nil {
+			m = make(map[int]int)
+			a.leaderParents[evt.LeaderNodeInfo] = m
+		}
+		m[evt.ParentID] += evt.Weight
+	}
+	if a.monthLeaderCounts != nil {
+		mk := dt.Format("2006-01")
+		m := a.monthLeaderCounts[mk]
+		if m == nil {
+			m = make(map[string]int)
+			a.monthLeaderCounts[mk] = m
+		}
+		m[evt.LeaderNodeInfo] += evt.Weight
+	}
+	if a.perDayLeaders != nil {
+		a.addDayLeader(dt.Format("2006-01-02"), evt.LeaderNodeInfo)
+	}
+	if a.perWeekdayLeader != nil {
+		a.perWeekdayLeader[dt.Weekday()][evt.LeaderNodeInfo] += evt.Weight
+	}
+	if a.perHourWeekday != nil {
+		a.perHourWeekday[dt.Hour()][(dt.Weekday()+6)%7] += evt.Weight
+	}
+	if a.groups != nil {
+		a.groups.add(evt)
+	}
+	if a.values != nil {
+		a.addValue(dt, evt)
+	}
+	if a.fieldSummary != nil {
+		a.fieldSummary.add(evt)
+	}
+	if a.dailyQuantiles != nil {
+		a.dailyQuantiles.add(dt.Format("2006-01-02"), evt.ParentID, evt.Weight)
+	}
+	if a.eventSizes != nil {
+		a.eventSizes.add(dt, evt.Size)
+	}
+	if a.replay != nil {
+		a.replay.add(orderedRecord{
+			T:      dt.UnixNano(),
+			Parent: evt.ParentID,
+			First:  evt.FirstChildID,
+			Second: evt.SecondChildID,
+			Leader: evt.LeaderNodeInfo,
+		})
+	}
+}
+
+// bucketCounts returns the filtered counts per day, week ("YYYY-Www",
+// calendar year) or month and their keys in order. Months are summed from
+// the days.
+func bucketCounts(a *aggregates, period string) ([]string, map[string]int) {
+	counts := a.perDay
+	switch period {
+	case "week":
+		counts = a.perWeek
+	case "month":
+		counts = make(map[string]int)
+		for d, n := range a.perDay {
+			counts[d[:7]] += n
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, counts
+}
+
+// finish runs the post-pass stages once all input has been read.
+func (a *aggregates) finish() error {
+	if a.replay != nil {
+		if err := a.replay.replay(); err != nil {
+			return fmt.Errorf("ordered replay: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ── -max-output-bytes ─────────────────────────────────────────────────────────
+//
+// Webhooks, chat messages and monitoring plugin output have size limits. When
+// a budget is set the report is rendered, measured and, while it is too big,
+// degraded in fixed steps:
+//
+//  1. drop the example lists (section Details);
+//  2. halve the rows kept in every Truncatable table, down to one row, with a
+//     note saying how many rows are shown;
+//  3. drop Optional sections, last first.
+//
+// Every step re-renders the whole document, so the result is always a
+// complete, valid document in the chosen format; tables are never cut
+// mid-row. If even the smallest form does not fit it is written anyway and
+// fitBudget reports that the budget was missed.
+
+// fitBudget renders sections within max bytes (0 means unlimited) and returns
+// the rendered output and whether it fits.
+func fitBudget(format, title string, sections []section, max int) ([]byte, bool) {
+	renderTo := func(secs []section) []byte {
+		var buf bytes.Buffer
+		render(&buf, format, title, secs)
+		return buf.Bytes()
+	}
+	out := renderTo(sections)
+	if max <= 0 || len(out) <= max {
+		return out, true
+	}
+	logf("output: %d bytes exceeds -max-output-bytes %d", len(out), max)
+
+	secs := make([]section, len(sections))
+	copy(secs, sections)
+	dropped := 0
+	for i := range secs {
+		if len(secs[i].Details) > 0 {
+			dropped += len(secs[i].Details)
+			secs[i].Details = nil
+		}
+	}
+	if dropped > 0 {
+		out = renderTo(secs)
+		logf("output: dropped %d example lists: %d bytes", dropped, len(out))
+		if len(out) <= max {
+			return out, true
+		}
+	}
+
+	longest := 0
+	for _, s := range secs {
+		if s.Truncatable && len(s.Rows) > longest {
+			longest = len(s.Rows)
+		}
+	}
+	for keep := longest / 2; keep >= 1 && len(out) > max; keep /= 2 {
+		out = renderTo(truncateTables(secs, keep))
+		logf("output: truncated tables to %d rows: %d bytes", keep, len(out))
+		if len(out) <= max {
+			return out, true
+		}
+		if keep == 1 {
+			break
+		}
+	}
+	if longest > 1 {
+		secs = truncateTables(secs, 1)
+	}
+
+	for i := len(secs) - 1; i >= 0 && len(out) > max; i-- {
+		if !secs[i].Optional {
+			continue
+		}
+		logf("output: dropped section %s", secs[i].Key)
+		secs = append(secs[:i:i], secs[i+1:]...)
+		out = renderTo(secs)
+	}
+	if len(out) > max {
+		logf("output: %d bytes is the smallest form; budget not met", len(out))
+		return out, false
+	}
+	return out, true
+}
+
+// truncateTables returns a copy of secs with every Truncatable table cut to
+// keep rows and marked as truncated.
+func truncateTables(secs []section, keep int) []section {
+	out := make([]section, len(secs))
+	copy(out, secs)
+	for i, s := range out {
+		if !s.Truncatable || len(s.Rows) <= keep {
+			continue
+		}
+		out[i].Rows = s.Rows[:keep]
+		out[i].Notes = append([]string{fmt.Sprintf("(truncated: showing %d of %d rows)", keep, len(s.Rows))}, s.Notes...)
+	}
+	return out
+}
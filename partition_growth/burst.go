@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── Burst days ────────────────────────────────────────────────────────────────
+
+const defaultBurstSigma = 2.0
+
+// parseBurstSigma reads a burst report argument: the number of standard
+// deviations above the mean daily count that makes a day a burst day.
+func parseBurstSigma(arg string) (float64, error) {
+	if arg == "" {
+		return defaultBurstSigma, nil
+	}
+	k, err := strconv.ParseFloat(strings.TrimSuffix(arg, "sigma"), 64)
+	if err != nil || k <= 0 {
+		return 0, fmt.Errorf("invalid sigma %q", arg)
+	}
+	return k, nil
+}
+
+// burstDays returns the indexes of counts above mean + sigma·stddev of the
+// whole series, with the threshold. Days without events take part in the
+// mean, so the series should be zero-filled (see daySeries).
+func burstDays(counts []int, sigma float64) (idx []int, threshold float64) {
+	mean, sd := meanStddev(counts)
+	threshold = mean + sigma*sd
+	for i, c := range counts {
+		if sd > 0 && float64(c) > threshold {
+			idx = append(idx, i)
+		}
+	}
+	return idx, threshold
+}
+
+// ── event-burst-sequence ──────────────────────────────────────────────────────
+
+func prepareEventBurstSequence(_ *aggregates, arg string) error {
+	_, err := parseBurstSigma(arg)
+	return err
+}
+
+// burstRun is a run of consecutive burst days.
+type burstRun struct {
+	Start, End time.Time
+	Days       int
+	Peak       int
+	PeakDay    time.Time
+}
+
+// buildEventBurstSequence groups the burst days of the filtered daily series
+// into runs of consecutive days, in date order.
+func buildEventBurstSequence(a *aggregates, arg string) []section {
+	sigma, _ := parseBurstSigma(arg)
+	s := section{
+		Key:     "event_burst_sequence",
+		Title:   "Burst Day Sequences",
+		Columns: []string{"Run", "Length", "Peak", "Peak day"},
+		LineFmt: "%s (%s, peak: %s on %s)",
+
+		Truncatable: true,
+	}
+	keys, counts := daySeries(a.perDay)
+	idx, threshold := burstDays(counts, sigma)
+	var runs []burstRun
+	for _, i := range idx {
+		t, _ := time.Parse("2006-01-02", keys[i])
+		if n := len(runs); n > 0 && t.Sub(runs[n-1].End) == 24*time.Hour {
+			r := &runs[n-1]
+			r.End = t
+			r.Days++
+			if counts[i] > r.Peak {
+				r.Peak, r.PeakDay = counts[i], t
+			}
+			continue
+		}
+		runs = append(runs, burstRun{Start: t, End: t, Days: 1, Peak: counts[i], PeakDay: t})
+	}
+	for _, r := range runs {
+		label, length := a.opts.dates.day(r.Start), "1 burst day"
+		if r.Days > 1 {
+			label = a.opts.dates.span(r.Start, r.End)
+			length = fmt.Sprintf("%d consecutive burst days", r.Days)
+		}
+		s.Rows = append(s.Rows, []string{label, length, strconv.Itoa(r.Peak), a.opts.dates.day(r.PeakDay)})
+	}
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Burst days: %d in %d runs; a burst day has more than %.1f events (mean + %g standard deviations)",
+		len(idx), len(runs), threshold, sigma))
+	return []section{s}
+}
+
+// ── event-spike-context ───────────────────────────────────────────────────────
+
+const defaultSpikeContext = 5
+
+func parseSpikeContext(arg string) (int, error) {
+	if arg == "" {
+		return defaultSpikeContext, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid day count %q", arg)
+	}
+	return n, nil
+}
+
+func prepareEventSpikeContext(_ *aggregates, arg string) error {
+	_, err := parseSpikeContext(arg)
+	return err
+}
+
+// buildEventSpikeContext lists, for each spike day of the filtered daily
+// series (more than -spike standard deviations above the mean), the days
+// around it with their counts and the difference from the spike.
+func buildEventSpikeContext(a *aggregates, arg string) []section {
+	n, _ := parseSpikeContext(arg)
+	sigma := a.opts.spikeSigma
+	s := section{
+		Key:     "event_spike_context",
+		Title:   fmt.Sprintf("Spike Days with %d Days Either Side", n),
+		Columns: []string{"Spike", "Day", "Offset", "Events", "Delta"},
+	}
+	keys, counts := daySeries(a.perDay)
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	idx, threshold := burstDays(counts, sigma)
+	for _, i := range idx {
+		spike, _ := time.Parse("2006-01-02", keys[i])
+		label := a.opts.dates.day(spike)
+		for off := -n; off <= n; off++ {
+			t := spike.AddDate(0, 0, off)
+			c := a.perDay[t.Format("2006-01-02")]
+			s.Rows = append(s.Rows, []string{label, a.opts.dates.day(t), fmt.Sprintf("%+d", off),
+				strconv.Itoa(c), fmt.Sprintf("%+d", c-counts[i])})
+		}
+	}
+	if len(idx) == 0 {
+		s.Notes = append(s.Notes, "No spike days.")
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("%d spike days; a spike day has more than %.1f events (mean + %g standard deviations, -spike)",
+		len(idx), threshold, sigma))
+	return []section{s}
+}
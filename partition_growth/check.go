@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── Checks ────────────────────────────────────────────────────────────────────
+//
+// A check is a report that also yields a status. The process exit code is the
+// worst status of all checks run, following the monitoring-plugin convention
+// (0 OK, 1 WARN, 2 CRIT), so runchk.sh and cron wrappers can alert on it.
+
+type checkStatus int
+
+const (
+	statusOK checkStatus = iota
+	statusWarn
+	statusCrit
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case statusWarn:
+		return "WARN"
+	case statusCrit:
+		return "CRIT"
+	}
+	return "OK"
+}
+
+// checkResult is the outcome of one check.
+type checkResult struct {
+	Name    string
+	Status  checkStatus
+	Message string
+}
+
+// line renders the result as "[WARN] name: message".
+func (r checkResult) line() string {
+	return fmt.Sprintf("[%s] %s: %s", r.Status, r.Name, r.Message)
+}
+
+// addCheck records a check result and returns its rendered line for the
+// check's own section.
+func (a *aggregates) addCheck(name string, status checkStatus, msg string) string {
+	r := checkResult{name, status, msg}
+	a.checks = append(a.checks, r)
+	return r.line()
+}
+
+// exitStatus is the worst status among the checks run.
+func (a *aggregates) exitStatus() checkStatus {
+	worst := statusOK
+	for _, c := range a.checks {
+		if c.Status > worst {
+			worst = c.Status
+		}
+	}
+	return worst
+}
+
+// ── Check flags ───────────────────────────────────────────────────────────────
+
+// spanValue is a flag.Value for durations that also accepts a day unit, so
+// windows can be written as "14d" or "1d12h".
+type spanValue time.Duration
+
+func (v *spanValue) String() string { return time.Duration(*v).String() }
+
+func (v *spanValue) Set(s string) error {
+	d, err := parseSpan(s)
+	if err != nil {
+		return err
+	}
+	*v = spanValue(d)
+	return nil
+}
+
+// parseSpan parses a Go duration with an optional leading whole-day part.
+func parseSpan(s string) (time.Duration, error) {
+	var days time.Duration
+	if i := strings.Index(s, "d"); i > 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		if s = s[i+1:]; s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return days + d, nil
+}
+
+// shortSpan renders a window like formatSpan but omits zero units ("14d",
+// "1d 12h").
+func shortSpan(d time.Duration) string {
+	fields := strings.Fields(formatSpan(d))
+	var parts []string
+	for _, f := range fields {
+		if f[0] != '0' {
+			parts = append(parts, f)
+		}
+	}
+	if len(parts) == 0 {
+		return fields[len(fields)-1]
+	}
+	return strings.Join(parts, " ")
+}
+
+// patternList collects repeated glob patterns (path.Match syntax).
+type patternList []string
+
+func (l *patternList) String() string { return strings.Join(*l, ",") }
+
+func (l *patternList) Set(v string) error {
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad pattern %q", p)
+		}
+		*l = append(*l, p)
+	}
+	return nil
+}
+
+func (l patternList) match(s string) bool {
+	for _, p := range l {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ── -leader-freshness ─────────────────────────────────────────────────────────
+
+func (a *aggregates) enableLeaderLastSeen() {
+	if a.leaderLastSeen == nil {
+		a.leaderLastSeen = make(map[string]time.Time)
+	}
+}
+
+// buildLeaderFreshness lists leaders that were active in the lookback window
+// before -asof but have been silent for longer than -leader-freshness. A
+// silent leader is WARN, or CRIT once silent longer than -leader-freshness-crit.
+func buildLeaderFreshness(a *aggregates) []section {
+	o := a.opts
+	s := section{
+		Key:     "leader_freshness",
+		Title:   fmt.Sprintf("Leader Freshness as of %s", o.asof.In(o.loc).Format("2006-01-02 15:04:05")),
+		Columns: []string{"Leader", "Last seen", "Silent for", "Status"},
+	}
+	since := o.asof.Add(-o.freshnessLookback)
+	var active, silent, ignored int
+	status := statusOK
+	leaders := make([]string, 0, len(a.leaderLastSeen))
+	for l := range a.leaderLastSeen {
+		leaders = append(leaders, l)
+	}
+	sortLeadersByLastSeen(leaders, a.leaderLastSeen)
+	for _, l := range leaders {
+		last := a.leaderLastSeen[l]
+		if last.Before(since) {
+			continue
+		}
+		if o.ignoreLeaders.match(l) {
+			ignored++
+			continue
+		}
+		active++
+		quiet := o.asof.Sub(last)
+		if quiet <= o.freshness {
+			continue
+		}
+		st := statusWarn
+		if o.freshnessCrit > 0 && quiet > o.freshnessCrit {
+			st = statusCrit
+		}
+		if st > status {
+			status = st
+		}
+		silent++
+		s.Rows = append(s.Rows, []string{l, last.In(o.loc).Format("2006-01-02 15:04:05"), formatSpan(quiet), st.String()})
+	}
+	msg := fmt.Sprintf("%d of %d leaders active in the last %s reported within %s",
+		active-silent, active, shortSpan(o.freshnessLookback), shortSpan(o.freshness))
+	if ignored > 0 {
+		msg += fmt.Sprintf(" (%d ignored)", ignored)
+	}
+	s.Notes = append(s.Notes, a.addCheck("leader-freshness", status, msg))
+	return []section{s}
+}
+
+// sortLeadersByLastSeen orders leaders longest-silent first.
+func sortLeadersByLastSeen(leaders []string, last map[string]time.Time) {
+	sort.Slice(leaders, func(i, j int) bool {
+		x, y := leaders[i], leaders[j]
+		if !last[x].Equal(last[y]) {
+			return last[x].Before(last[y])
+		}
+		return x < y
+	})
+}
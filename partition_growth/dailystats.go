@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"partition_growth/quantile"
+)
+
+// ── stats ─────────────────────────────────────────────────────────────────────
+//
+// Quantiles of the filtered event count per day, or per ParentID and day with
+// stats=parent. Counts are exact while at most -stats-exact keys are open.
+// Beyond that the older half of the open days is closed into a t-digest, so
+// memory stays bounded however long the range; the quantiles are then
+// approximate and marked "≈". Closing assumes roughly time-ordered input: an
+// event for a day already closed starts a fresh partial count, and such late
+// events are reported.
+
+// dailyKey is a day, or a ParentID's day for stats=parent.
+type dailyKey struct {
+	day    string
+	parent int
+}
+
+type dailyQuantiles struct {
+	byParent bool
+	limit    int
+	open     map[dailyKey]int
+	digest   *quantile.TDigest // nil while exact
+	closed   string            // days before this have been closed
+	late     int               // events for days already closed
+	total    int
+}
+
+func parseStatsArg(arg string) (bool, error) {
+	switch arg {
+	case "", "day":
+		return false, nil
+	case "parent":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown breakdown %q (want day or parent)", arg)
+}
+
+func prepareStats(a *aggregates, arg string) error {
+	byParent, err := parseStatsArg(arg)
+	if err != nil {
+		return err
+	}
+	if a.dailyQuantiles == nil {
+		a.dailyQuantiles = &dailyQuantiles{byParent: byParent, limit: a.opts.statsExact, open: make(map[dailyKey]int)}
+	} else if a.dailyQuantiles.byParent != byParent {
+		return fmt.Errorf("only one of stats and stats=parent per run")
+	}
+	return nil
+}
+
+func (d *dailyQuantiles) add(day string, parent, w int) {
+	k := dailyKey{day: day}
+	if d.byParent {
+		k.parent = parent
+	}
+	if d.digest != nil && day < d.closed {
+		d.late += w
+	}
+	d.open[k] += w
+	d.total += w
+	if d.limit > 0 && len(d.open) > d.limit {
+		d.closeOlder()
+	}
+}
+
+// closeOlder moves the keys of the older half of the open days into the
+// digest. With a single open day there is nothing to close.
+func (d *dailyQuantiles) closeOlder() {
+	seen := make(map[string]bool)
+	days := make([]string, 0, len(d.open))
+	for k := range d.open {
+		if !seen[k.day] {
+			seen[k.day] = true
+			days = append(days, k.day)
+		}
+	}
+	if len(days) < 2 {
+		return
+	}
+	sort.Strings(days)
+	cut := days[(len(days)+1)/2]
+	if d.digest == nil {
+		d.digest = quantile.New(quantile.DefaultCompression)
+	}
+	for k, n := range d.open {
+		if k.day < cut {
+			d.digest.Add(float64(n))
+			delete(d.open, k)
+		}
+	}
+	if cut > d.closed {
+		d.closed = cut
+	}
+}
+
+func (d *dailyQuantiles) size() (int, int64) {
+	n := len(d.open)
+	b := int64(n) * (stringHeader + 10 + 8 + 8 + mapEntryOverhead)
+	if d.digest != nil {
+		b += int64(d.digest.Len()) * 16
+	}
+	return n, b
+}
+
+var statsQuantiles = []struct {
+	label string
+	p     float64
+}{{"P50", 50}, {"P90", 90}, {"P99", 99}}
+
+// buildStats reports the key count, mean, quantiles and maximum. Exact
+// quantiles use the nearest rank, as the other percentile reports do.
+func buildStats(a *aggregates, _ string) []section {
+	d := a.dailyQuantiles
+	unit := "day"
+	if d.byParent {
+		unit = "ParentID-day"
+	}
+	s := section{
+		Key:     "stats",
+		Title:   fmt.Sprintf("Events per %s", unit),
+		Columns: []string{"Statistic", "Value"},
+	}
+	var keys, max int
+	values := make([]int, 0, len(d.open))
+	for _, n := range d.open {
+		values = append(values, n)
+	}
+	if d.digest == nil {
+		sort.Ints(values)
+		keys = len(values)
+		if keys > 0 {
+			max = values[keys-1]
+		}
+		s.Rows = append(s.Rows, []string{unit + "s with events", strconv.Itoa(keys)}, []string{"Mean", formatMean(d.total, keys)})
+		for _, q := range statsQuantiles {
+			s.Rows = append(s.Rows, []string{q.label, strconv.Itoa(percentile(values, q.p))})
+		}
+		s.Rows = append(s.Rows, []string{"Max", strconv.Itoa(max)})
+	} else {
+		for _, n := range values {
+			d.digest.Add(float64(n))
+		}
+		d.open = make(map[dailyKey]int)
+		keys = int(d.digest.Count())
+		s.Rows = append(s.Rows, []string{unit + "s with events", strconv.Itoa(keys)},
+			[]string{"Mean", formatMean(d.total, keys)})
+		for _, q := range statsQuantiles {
+			s.Rows = append(s.Rows, []string{q.label, "≈" + strconv.FormatFloat(d.digest.Quantile(q.p/100), 'f', 1, 64)})
+		}
+		s.Rows = append(s.Rows, []string{"Max", strconv.Itoa(int(math.Round(d.digest.Max())))})
+		s.Notes = append(s.Notes, fmt.Sprintf("≈ approximate: more than %d open %ss, so older ones were summarized in a t-digest (rank error typically under 0.5%%).",
+			d.limit, unit))
+		if d.late > 0 {
+			s.Notes = append(s.Notes, fmt.Sprintf("%d events arrived after their day was summarized and were counted as separate %ss.", d.late, unit))
+		}
+	}
+	if keys == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
+
+func formatMean(sum, n int) string {
+	if n == 0 {
+		return "0.0"
+	}
+	return strconv.FormatFloat(float64(sum)/float64(n), 'f', 1, 64)
+}
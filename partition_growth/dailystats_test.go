@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestStatsExactAndApproximate fills stats with the same days under several
+// -stats-exact limits: within the limit the quantiles are exact nearest
+// ranks, beyond it they carry the ≈ marker, stay close, and keep the exact
+// count, mean and maximum.
+func TestStatsExactAndApproximate(t *testing.T) {
+	// Day i of 200 has i%20+1 events, so P50 is 10, P90 18 and P99 20.
+	fill := func(limit int) section {
+		a := &aggregates{opts: reportOptions{statsExact: limit}}
+		if err := prepareStats(a, "day"); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 200; i++ {
+			day := fmt.Sprintf("2025-%03d", i)
+			for j := 0; j <= i%20; j++ {
+				a.dailyQuantiles.add(day, 0, 1)
+			}
+		}
+		return buildStats(a, "day")[0]
+	}
+	tests := []struct {
+		name   string
+		limit  int
+		approx bool
+	}{
+		{"no limit", 0, false},
+		{"limit above the days", 500, false},
+		{"limit of exactly the days", 200, false},
+		{"limit below the days", 50, true},
+		{"limit of one", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fill(tt.limit)
+			rows := make(map[string]string)
+			for _, r := range s.Rows {
+				rows[r[0]] = r[1]
+			}
+			for k, want := range map[string]string{"days with events": "200", "Mean": "10.5", "Max": "20"} {
+				if rows[k] != want {
+					t.Errorf("%s = %q, want %q", k, rows[k], want)
+				}
+			}
+			noted := strings.Contains(strings.Join(s.Notes, "\n"), "≈ approximate")
+			for label, exact := range map[string]float64{"P50": 10, "P90": 18, "P99": 20} {
+				v := rows[label]
+				if strings.HasPrefix(v, "≈") != tt.approx {
+					t.Errorf("%s = %q, approximate marker should be %v", label, v, tt.approx)
+				}
+				var got float64
+				if _, err := fmt.Sscan(strings.TrimPrefix(v, "≈"), &got); err != nil {
+					t.Fatalf("%s = %q: %v", label, v, err)
+				}
+				if d := got - exact; d > 1 || d < -1 || (!tt.approx && d != 0) {
+					t.Errorf("%s = %g, exact %g", label, got, exact)
+				}
+			}
+			if noted != tt.approx {
+				t.Errorf("approximation note present = %v, want %v: %q", noted, tt.approx, s.Notes)
+			}
+		})
+	}
+}
+
+// TestStatsLateEvents adds an event for a day already summarized: it counts
+// as a separate day and is reported.
+func TestStatsLateEvents(t *testing.T) {
+	a := &aggregates{opts: reportOptions{statsExact: 2}}
+	if err := prepareStats(a, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-04", "2025-01-01"} {
+		a.dailyQuantiles.add(day, 0, 1)
+	}
+	s := buildStats(a, "")[0]
+	if got := s.Rows[0][1]; got != "5" {
+		t.Errorf("days with events = %s, want 5 with the late partial day", got)
+	}
+	if !strings.Contains(strings.Join(s.Notes, "\n"), "1 events arrived after their day was summarized") {
+		t.Errorf("late event not noted: %q", s.Notes)
+	}
+}
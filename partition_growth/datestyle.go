@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ── -date-style ───────────────────────────────────────────────────────────────
+
+// dateStyle selects how human-readable date labels are written. It applies to
+// the text, jira and html formats; JSON always uses ISO so that consumers can
+// parse it.
+type dateStyle string
+
+const (
+	dateUS  dateStyle = "us"  // Mar 14, 2025 / Mar 1–7, 2025 (default)
+	dateISO dateStyle = "iso" // 2025-03-14 / 2025-03-01..07
+	dateEU  dateStyle = "eu"  // 14 Mar 2025 / 1–7 Mar 2025
+)
+
+var dateStyles = []string{string(dateISO), string(dateUS), string(dateEU)}
+
+func validDateStyle(s string) bool {
+	for _, d := range dateStyles {
+		if d == s {
+			return true
+		}
+	}
+	return false
+}
+
+// month labels a calendar month.
+func (s dateStyle) month(year, month int) string {
+	if s == dateISO {
+		return fmt.Sprintf("%04d-%02d", year, month)
+	}
+	return fmt.Sprintf("%s %d", monthName(month), year)
+}
+
+// day labels a single date.
+func (s dateStyle) day(t time.Time) string {
+	switch s {
+	case dateISO:
+		return t.Format("2006-01-02")
+	case dateEU:
+		return t.Format("2 Jan 2006")
+	}
+	return t.Format("Jan 2, 2006")
+}
+
+// span labels the inclusive range of days from a to b, leaving out the parts
+// both ends share.
+func (s dateStyle) span(a, b time.Time) string {
+	sameYear := a.Year() == b.Year()
+	sameMonth := sameYear && a.Month() == b.Month()
+	switch s {
+	case dateISO:
+		switch {
+		case sameMonth:
+			return a.Format("2006-01-02") + ".." + b.Format("02")
+		case sameYear:
+			return a.Format("2006-01-02") + ".." + b.Format("01-02")
+		}
+		return a.Format("2006-01-02") + ".." + b.Format("2006-01-02")
+	case dateEU:
+		switch {
+		case sameMonth:
+			return a.Format("2") + "–" + b.Format("2 Jan 2006")
+		case sameYear:
+			return a.Format("2 Jan") + "–" + b.Format("2 Jan 2006")
+		}
+		return a.Format("2 Jan 2006") + "–" + b.Format("2 Jan 2006")
+	}
+	switch {
+	case sameMonth:
+		return a.Format("Jan 2") + "–" + b.Format("2, 2006")
+	case sameYear:
+		return a.Format("Jan 2") + "–" + b.Format("Jan 2, 2006")
+	}
+	return a.Format("Jan 2, 2006") + "–" + b.Format("Jan 2, 2006")
+}
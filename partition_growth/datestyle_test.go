@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateStyle(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		style                                        dateStyle
+		month, day, sameMonth, sameYear, acrossYears string
+	}{
+		{dateUS, "Mar 2025", "Mar 4, 2025", "Mar 1–7, 2025", "Mar 29–Apr 4, 2025", "Dec 29, 2025–Jan 4, 2026"},
+		{dateISO, "2025-03", "2025-03-04", "2025-03-01..07", "2025-03-29..04-04", "2025-12-29..2026-01-04"},
+		{dateEU, "Mar 2025", "4 Mar 2025", "1–7 Mar 2025", "29 Mar–4 Apr 2025", "29 Dec 2025–4 Jan 2026"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			for _, c := range []struct{ what, got, want string }{
+				{"month", tt.style.month(2025, 3), tt.month},
+				{"day", tt.style.day(day(2025, 3, 4)), tt.day},
+				{"span in a month", tt.style.span(day(2025, 3, 1), day(2025, 3, 7)), tt.sameMonth},
+				{"span in a year", tt.style.span(day(2025, 3, 29), day(2025, 4, 4)), tt.sameYear},
+				{"span across years", tt.style.span(day(2025, 12, 29), day(2026, 1, 4)), tt.acrossYears},
+			} {
+				if c.got != c.want {
+					t.Errorf("%s = %q, want %q", c.what, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// TestDateStyleReport renders one fixture in all three styles: every label
+// follows the chosen style and none of another style's leaks through.
+func TestDateStyleReport(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(
+		event(1, "2025-11-02T10:00:00Z", "a"),
+		event(10, "2025-11-03T10:00:00Z", "a"),
+		event(20, "2025-11-10T10:00:00Z", "b"),
+	))
+	tests := []struct {
+		style string
+		want  []string
+		not   []string
+	}{
+		{"us", []string{"Nov 2025 weekly summary", "Week 1: Nov 1–7, 2025: 2", "Total for Nov 2025: 3", "Nov 2, 2025  "},
+			[]string{"2025-11-0", "1–7 Nov"}},
+		{"iso", []string{"2025-11 weekly summary", "Week 1: 2025-11-01..07: 2", "Total for 2025-11: 3", "2025-11-02  "},
+			[]string{"Nov"}},
+		{"eu", []string{"Nov 2025 weekly summary", "Week 1: 1–7 Nov 2025: 2", "Total for Nov 2025: 3", "2 Nov 2025  "},
+			[]string{"2025-11-0", "Nov 1–7", "Nov 2,"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2025", "-m", "11", "-report=daily-top-leaders", "-date-style", tt.style)
+			if status != 0 {
+				t.Fatalf("exit %d\n%s", status, errOut)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output lacks %q:\n%s", w, out)
+				}
+			}
+			for _, n := range tt.not {
+				if strings.Contains(out, n) {
+					t.Errorf("output has %q from another style:\n%s", n, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ── -output dot-timeline ──────────────────────────────────────────────────────
+
+// writeDotTimeline writes the filtered counts per period as a Graphviz chain
+// of nodes, oldest first, each filled from white to red in proportion to its
+// count. Periods without events have no node, so the chain skips them.
+// Rendered with dot -Grankdir=LR it is a left-to-right timeline.
+func writeDotTimeline(w io.Writer, title, period string, a *aggregates) error {
+	keys, counts := bucketCounts(a, period)
+	max := 0
+	for _, k := range keys {
+		if counts[k] > max {
+			max = counts[k]
+		}
+	}
+	fmt.Fprintln(w, "digraph timeline {")
+	fmt.Fprintf(w, "  label=%s;\n", strconv.Quote(title))
+	fmt.Fprintln(w, "  node [shape=box, style=filled];")
+	for i, k := range keys {
+		shade := 255
+		if max > 0 {
+			shade = 255 - counts[k]*255/max
+		}
+		fmt.Fprintf(w, "  n%d [label=%s, fillcolor=\"#ff%02x%02x\"];\n", i, strconv.Quote(fmt.Sprintf("%s\n%d", k, counts[k])), shade, shade)
+	}
+	for i := 1; i < len(keys); i++ {
+		fmt.Fprintf(w, "  n%d -> n%d;\n", i-1, i)
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ── -dump-daily ───────────────────────────────────────────────────────────────
+
+// dumpDailyCSV writes "date,count" rows for every day from the first to the
+// last day in perDay, zero-filled, to path ("-" is stdout). Rows are written
+// as the days are walked, so only the per-day map is held in memory.
+func dumpDailyCSV(path string, perDay map[string]int) error {
+	if path == "-" {
+		return writeDailyCSV(reportOut, perDay)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeDailyCSV(f, perDay); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeDailyCSV(w io.Writer, perDay map[string]int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "date,count")
+	var first, last string
+	for k := range perDay {
+		if first == "" || k < first {
+			first = k
+		}
+		if k > last {
+			last = k
+		}
+	}
+	if first != "" {
+		t, _ := time.Parse("2006-01-02", first)
+		for k := first; k <= last; k = t.Format("2006-01-02") {
+			fmt.Fprintf(bw, "%s,%d\n", k, perDay[k])
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return bw.Flush()
+}
+
+// ── -dump ─────────────────────────────────────────────────────────────────────
+
+// eventDump writes the events that pass the report filters, and -dump-where,
+// as canonical JSON lines. Unless the dump goes to stdout, a sidecar
+// <path>.meta.json records both filters so the extract's provenance is clear.
+type eventDump struct {
+	path  string
+	f     *os.File
+	w     *bufio.Writer
+	where whereExpr
+	n     int
+	err   error
+}
+
+// dumpMeta is the sidecar written next to a -dump file.
+type dumpMeta struct {
+	Inputs       []string `json:"inputs"`
+	ReportFilter string   `json:"report_filter"`
+	Where        string   `json:"where,omitempty"`
+	DumpWhere    string   `json:"dump_where,omitempty"`
+	Midnight     string   `json:"midnight_policy"`
+	ISOOffsets   string   `json:"iso_offsets"`
+	Events       int      `json:"events"`
+}
+
+func openEventDump(path string, where whereExpr) (*eventDump, error) {
+	d := &eventDump{path: path, where: where}
+	out := reportOut
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		d.f, out = f, f
+	}
+	d.w = bufio.NewWriter(out)
+	return d, nil
+}
+
+// add writes evt if it matches -dump-where. The first write error is kept
+// and reported by close.
+func (d *eventDump) add(dt time.Time, evt Event) {
+	if d.err != nil || !d.where.matches(dt, evt) {
+		return
+	}
+	evt.Date = dt.Format(time.RFC3339Nano)
+	b, err := evt.Canonical()
+	if err == nil {
+		b = append(b, '\n')
+		_, err = d.w.Write(b)
+	}
+	d.err = err
+	d.n++
+}
+
+// close flushes the dump and writes its sidecar.
+func (d *eventDump) close(meta dumpMeta) error {
+	if err := d.w.Flush(); d.err == nil {
+		d.err = err
+	}
+	if d.path == "-" {
+		return d.err
+	}
+	if err := d.f.Close(); d.err == nil {
+		d.err = err
+	}
+	if d.err != nil {
+		return d.err
+	}
+	meta.DumpWhere = d.where.String()
+	meta.Events = d.n
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.path+".meta.json", append(b, '\n'), 0o644)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -enrich-cmd ───────────────────────────────────────────────────────────────
+//
+// An enrichment command adds fields to the filtered events, e.g. a tenant
+// looked up from the parentId, without the lookup living in this tool. The
+// command is started once and speaks NDJSON over its stdin and stdout:
+//
+//   - In: one line per event that passed -y/-m/-d and -from/-to, in the
+//     canonical -dump form (date in UTC RFC 3339, parentId, firstChildId,
+//     secondChildId, leaderNodeInfo, value and size when non-zero). Events are sent in
+//     batches of -enrich-batch lines.
+//   - Out: exactly one JSON object line per input line, in the same order.
+//     Every member other than the input fields is an added field; its value
+//     must be a string, number, boolean or null (no field). Echoing the input
+//     fields back is allowed and they are ignored.
+//
+// Added fields are read as enrich.<name> by -where and -group-by; -where is
+// applied after enrichment. The command must answer each batch within
+// -enrich-timeout and exit 0 once its stdin is closed. A timeout, an early
+// exit, a non-zero status or a malformed line fails the run, quoting the
+// command's stderr.
+
+// enrichPrefix marks an added field in -where and -group-by.
+const enrichPrefix = "enrich."
+
+// enrichInputFields are the members of an input line, ignored on output.
+var enrichInputFields = map[string]bool{
+	"date": true, "parentId": true, "firstChildId": true, "secondChildId": true, "leaderNodeInfo": true, "value": true, "size": true,
+}
+
+// enrichField returns the added field named by an enrich.<name> dimension.
+func enrichField(dim string) (string, bool) {
+	name, ok := strings.CutPrefix(dim, enrichPrefix)
+	return name, ok && name != ""
+}
+
+// pendingEvent is a filtered event waiting for its batch to be enriched.
+type pendingEvent struct {
+	dt    time.Time
+	k     eventKeys
+	w, sw int
+	evt   Event
+}
+
+// enricher runs the enrichment command and collects events into batches.
+type enricher struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	lines     chan enrichLine
+	stderr    *capBuffer
+	timeout   time.Duration
+	batchSize int
+	pending   []pendingEvent
+	sent      int
+}
+
+type enrichLine struct {
+	text []byte
+	err  error
+}
+
+func startEnricher(path string, batchSize int, timeout time.Duration) (*enricher, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	e := &enricher{cmd: cmd, stdin: stdin, lines: make(chan enrichLine), stderr: &capBuffer{max: 4096},
+		timeout: timeout, batchSize: batchSize}
+	cmd.Stderr = e.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for sc.Scan() {
+			e.lines <- enrichLine{text: append([]byte(nil), sc.Bytes()...)}
+		}
+		err := sc.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		e.lines <- enrichLine{err: err}
+		close(e.lines)
+	}()
+	return e, nil
+}
+
+// add queues p and returns a full batch, enriched, once batchSize events are
+// waiting; otherwise it returns nil.
+func (e *enricher) add(p pendingEvent) ([]pendingEvent, error) {
+	e.pending = append(e.pending, p)
+	if len(e.pending) < e.batchSize {
+		return nil, nil
+	}
+	return e.flush()
+}
+
+// flush enriches and returns the queued events.
+func (e *enricher) flush() ([]pendingEvent, error) {
+	batch := e.pending
+	e.pending = nil
+	if len(batch) == 0 {
+		return nil, nil
+	}
+	var in bytes.Buffer
+	for _, p := range batch {
+		evt := p.evt
+		evt.Date = p.dt.Format(time.RFC3339Nano)
+		b, err := evt.Canonical()
+		if err != nil {
+			return nil, err
+		}
+		in.Write(b)
+		in.WriteByte('\n')
+	}
+	// Write from a goroutine so a command that answers line by line cannot
+	// block on a full stdout pipe while we are still writing.
+	werr := make(chan error, 1)
+	go func() {
+		_, err := e.stdin.Write(in.Bytes())
+		werr <- err
+	}()
+	deadline := time.NewTimer(e.timeout)
+	defer deadline.Stop()
+	for i := range batch {
+		select {
+		case l := <-e.lines:
+			if l.err != nil {
+				return nil, e.fail(fmt.Errorf("output ended after %d of %d records: %v", e.sent+i, e.sent+len(batch), l.err))
+			}
+			fields, err := parseEnrichLine(l.text)
+			if err != nil {
+				return nil, e.fail(fmt.Errorf("output line %d: %v", e.sent+i+1, err))
+			}
+			batch[i].evt.Enriched = fields
+		case <-deadline.C:
+			return nil, e.fail(fmt.Errorf("no answer for record %d within -enrich-timeout %s", e.sent+i+1, e.timeout))
+		}
+	}
+	if err := <-werr; err != nil {
+		return nil, e.fail(err)
+	}
+	e.sent += len(batch)
+	return batch, nil
+}
+
+// close ends the command's input and waits for it to exit cleanly.
+func (e *enricher) close() error {
+	e.stdin.Close()
+	select {
+	case l := <-e.lines:
+		if l.err == nil {
+			return e.fail(errors.New("output has more lines than records sent"))
+		}
+		if l.err != io.EOF {
+			return e.fail(l.err)
+		}
+	case <-time.After(e.timeout):
+		return e.fail(fmt.Errorf("did not exit within -enrich-timeout %s", e.timeout))
+	}
+	if err := e.cmd.Wait(); err != nil {
+		return e.describe(err)
+	}
+	return nil
+}
+
+// fail stops the command and describes err with its stderr.
+func (e *enricher) fail(err error) error {
+	e.cmd.Process.Kill()
+	e.cmd.Wait()
+	return e.describe(err)
+}
+
+func (e *enricher) describe(err error) error {
+	if s := strings.TrimSpace(e.stderr.String()); s != "" {
+		return fmt.Errorf("%s: %v; stderr: %s", e.cmd.Path, err, s)
+	}
+	return fmt.Errorf("%s: %v", e.cmd.Path, err)
+}
+
+// parseEnrichLine returns the added fields of one output line.
+func parseEnrichLine(line []byte) (map[string]string, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(line, &obj); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %v", err)
+	}
+	fields := make(map[string]string)
+	for k, raw := range obj {
+		if enrichInputFields[k] {
+			continue
+		}
+		var v interface{}
+		json.Unmarshal(raw, &v)
+		switch v := v.(type) {
+		case nil:
+		case string:
+			fields[k] = v
+		case float64:
+			fields[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			fields[k] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("field %q is not a string, number or boolean", k)
+		}
+	}
+	return fields, nil
+}
+
+// capBuffer keeps the first max bytes written to it.
+type capBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *capBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// enricherSource is the -enrich-cmd used by the tests. It answers each line
+// with the parentId's parity as "tenant" and the line number as "seq";
+// ENRICH_MODE makes it fail in one of the ways the protocol rejects.
+const enricherSource = `package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	sc := bufio.NewScanner(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	for n := 1; sc.Scan(); n++ {
+		var in struct {
+			ParentID int ` + "`json:\"parentId\"`" + `
+		}
+		if err := json.Unmarshal(sc.Bytes(), &in); err != nil {
+			fmt.Fprintln(os.Stderr, "bad input:", err)
+			os.Exit(2)
+		}
+		switch os.Getenv("ENRICH_MODE") {
+		case "fail":
+			fmt.Fprintln(os.Stderr, "tenant service unavailable")
+			os.Exit(3)
+		case "malformed":
+			fmt.Fprintln(out, "not json")
+		case "nested":
+			fmt.Fprintln(out, ` + "`" + `{"tenant":{"id":1}}` + "`" + `)
+		case "slow":
+			time.Sleep(10 * time.Second)
+		case "extra":
+			fmt.Fprintln(out, "{}")
+			fmt.Fprintln(out, "{}")
+		default:
+			tenant := "odd"
+			if in.ParentID%2 == 0 {
+				tenant = "even"
+			}
+			fmt.Fprintf(out, "{\"parentId\":%d,\"tenant\":%q,\"seq\":%d,\"gone\":null}\n", in.ParentID, tenant, n)
+		}
+		out.Flush()
+	}
+}
+`
+
+var (
+	enricherOnce sync.Once
+	enricherPath string
+	enricherErr  error
+)
+
+// buildEnricher builds enricherSource once and returns the executable.
+func buildEnricher(t *testing.T) string {
+	t.Helper()
+	enricherOnce.Do(func() {
+		dir := filepath.Dir(toolPath)
+		src := filepath.Join(dir, "enricher.go")
+		if enricherErr = os.WriteFile(src, []byte(enricherSource), 0o644); enricherErr != nil {
+			return
+		}
+		enricherPath = filepath.Join(dir, "enricher") + filepath.Ext(toolPath)
+		if out, err := exec.Command("go", "build", "-o", enricherPath, src).CombinedOutput(); err != nil {
+			enricherErr = fmt.Errorf("%v\n%s", err, out)
+		}
+	})
+	if enricherErr != nil {
+		t.Fatalf("building the enricher: %v", enricherErr)
+	}
+	return enricherPath
+}
+
+// enrichInput writes seven events with parentIds 10, 21, 30, 41, 50, 61 and
+// 70 to dir/in.json.
+func enrichInput(t *testing.T, dir string) {
+	t.Helper()
+	var records []string
+	for i := 0; i < 7; i++ {
+		records = append(records, event(10*(i+1)+i%2, fmt.Sprintf("2025-01-%02dT12:00:00Z", i+1), "n"))
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+}
+
+// TestEnrichOrder selects each event by the line number the command saw it
+// on: with any batch size the n-th event sent must come back as the n-th
+// answer.
+func TestEnrichOrder(t *testing.T) {
+	cmd := buildEnricher(t)
+	dir := t.TempDir()
+	enrichInput(t, dir)
+	for _, batch := range []string{"1", "3", "7", "100"} {
+		for seq, parent := range []string{"10", "21", "30", "41", "50", "61", "70"} {
+			out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2025", "-enrich-cmd", cmd, "-enrich-batch", batch,
+				"-where", fmt.Sprintf("enrich.seq == %d", seq+1), "-group-by", "parent")
+			if status != 0 {
+				t.Fatalf("batch %s: exit %d\n%s", batch, status, errOut)
+			}
+			if want := "\n" + parent + " "; !strings.Contains(out, want) || !strings.Contains(out, "Distinct parent values: 1\n") {
+				t.Errorf("batch %s: enrich.seq %d should select parent %s only:\n%s", batch, seq+1, parent, out)
+			}
+		}
+	}
+}
+
+func TestEnrichGroupBy(t *testing.T) {
+	cmd := buildEnricher(t)
+	dir := t.TempDir()
+	enrichInput(t, dir)
+	out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2025", "-enrich-cmd", cmd, "-enrich-batch", "2",
+		"-group-by", "enrich.tenant", "-where", "enrich.gone == \"\"")
+	if status != 0 {
+		t.Fatalf("exit %d\n%s", status, errOut)
+	}
+	for _, want := range []string{"even           4       57.1%", "odd            3       42.9%", "Distinct enrich.tenant values: 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEnrichFailures(t *testing.T) {
+	cmd := buildEnricher(t)
+	dir := t.TempDir()
+	enrichInput(t, dir)
+	tests := []struct {
+		mode string
+		args []string
+		msg  string
+	}{
+		{"fail", nil, "output ended after 0 of 7 records: EOF; stderr: tenant service unavailable"},
+		{"malformed", nil, "output line 1: not a JSON object"},
+		{"nested", nil, `output line 1: field "tenant" is not a string, number or boolean`},
+		{"slow", []string{"-enrich-timeout", "200ms"}, "no answer for record 1 within -enrich-timeout 200ms"},
+		{"extra", []string{"-enrich-batch", "7"}, "output has more lines than records sent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			t.Setenv("ENRICH_MODE", tt.mode)
+			args := append([]string{"-f", "in.json", "-y", "2025", "-enrich-cmd", cmd}, tt.args...)
+			out, errOut, status := runTool(t, dir, args...)
+			if status != codeExternal.exitStatus() {
+				t.Errorf("exit %d, want %d", status, codeExternal.exitStatus())
+			}
+			if !strings.Contains(errOut, "error ["+string(codeExternal)+"]: -enrich-cmd: ") || !strings.Contains(errOut, tt.msg) {
+				t.Errorf("stderr lacks %q:\n%s", tt.msg, errOut)
+			}
+			if out != "" {
+				t.Errorf("report printed despite the failure:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestParseEnrichLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]string
+		err  string
+	}{
+		{`{"tenant":"a","n":1.5,"ok":true,"none":null}`, map[string]string{"tenant": "a", "n": "1.5", "ok": "true"}, ""},
+		{`{"parentId":7,"date":"x","leaderNodeInfo":"n","tenant":"a"}`, map[string]string{"tenant": "a"}, ""},
+		{`{"n":12345678901}`, map[string]string{"n": "12345678901"}, ""},
+		{`{}`, map[string]string{}, ""},
+		{`["a"]`, nil, "not a JSON object"},
+		{`{"list":[1]}`, nil, `field "list"`},
+	}
+	for _, tt := range tests {
+		got, err := parseEnrichLine([]byte(tt.line))
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want one mentioning %q", tt.line, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.line, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%s: %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"partition_growth/events"
+)
+
+// ── Error codes ───────────────────────────────────────────────────────────────
+//
+// Every error shown to the user carries a stable code, printed in brackets in
+// text ("error [E_USAGE]: ...") and as a JSON line on stderr with -output
+// json. Fatal errors exit with the code's exit status; checks keep the 0 OK,
+// 1 WARN, 2 CRIT convention, so errors never collide with a check result.
+// Scripts may rely on the codes and exit statuses: renaming a code or moving
+// it to another status is a breaking change.
+
+type errCode string
+
+const (
+	codeUsage     errCode = "E_USAGE"
+	codeInputOpen errCode = "E_INPUT_OPEN"
+	codeEmpty     errCode = "E_EMPTY"
+	codeDecode    errCode = "E_DECODE"
+	codeDateParse errCode = "E_DATE_PARSE"
+	codeUnsorted  errCode = "E_UNSORTED"
+	codeThreshold errCode = "E_THRESHOLD"
+	codeExternal  errCode = "E_EXTERNAL"
+	codeOutput    errCode = "E_OUTPUT"
+	codeIO        errCode = "E_IO"
+	codeNetwork   errCode = "E_NETWORK"
+)
+
+// errorCodes lists the codes with their exit status and meaning, for the
+// usage text.
+var errorCodes = []struct {
+	code errCode
+	exit int
+	desc string
+}{
+	{codeThreshold, 1, "a check reported WARN (exit 1) or CRIT (exit 2)"},
+	{codeUsage, 64, "invalid flags or flag combinations"},
+	{codeDecode, 65, "malformed JSON stops an input"},
+	{codeDateParse, 65, "a record's date is missing or unreadable; the record is skipped"},
+	{codeUnsorted, 65, "-assert-sorted found an event out of order"},
+	{codeInputOpen, 66, "an input cannot be found, opened or listed"},
+	{codeEmpty, 67, "no input files, or no dated events to work from"},
+	{codeExternal, 69, "-enrich-cmd, -plugin or -post failed"},
+	{codeOutput, 73, "an output file or directory cannot be written"},
+	{codeIO, 74, "journal or spill file I/O failed"},
+	{codeNetwork, 75, "an -input-format http input failed after -http-retries, or its connection dropped"},
+}
+
+// exitStatus is the process exit status of a fatal error with code c.
+func (c errCode) exitStatus() int {
+	for _, e := range errorCodes {
+		if e.code == c {
+			return e.exit
+		}
+	}
+	return 1
+}
+
+// codedError attaches a code to an error from a helper, so the caller that
+// reports it need not guess the category from the message.
+type codedError struct {
+	code errCode
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+func withCode(code errCode, err error) error {
+	return &codedError{code, err}
+}
+
+// codeOf returns the code carried by err, recognizing decode and file
+// errors, or def when err has none.
+func codeOf(err error, def errCode) errCode {
+	var ce *codedError
+	var de *events.DecodeError
+	var pe *fs.PathError
+	switch {
+	case errors.As(err, &ce):
+		return ce.code
+	case errors.As(err, &de):
+		return codeDecode
+	case errors.As(err, &pe):
+		return codeInputOpen
+	}
+	return def
+}
+
+// errorsJSON writes errors to stderr as JSON lines; set for -output json.
+var errorsJSON bool
+
+// reportError is an error in the "errors" array of the JSON document.
+type reportError struct {
+	Code    errCode `json:"code"`
+	Message string  `json:"message"`
+	Count   int     `json:"count,omitempty"`
+}
+
+// runErrors collects the non-fatal errors of the run for the JSON document.
+var runErrors []reportError
+
+// logError prints an error with its code, without exiting.
+func logError(code errCode, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if errorsJSON {
+		b, _ := json.Marshal(struct {
+			Level   string  `json:"level"`
+			Code    errCode `json:"code"`
+			Message string  `json:"message"`
+		}{"error", code, msg})
+		fmt.Fprintln(os.Stderr, string(b))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "error [%s]: %s\n", code, msg)
+}
+
+// fail prints an error with its code and exits with the code's status.
+func fail(code errCode, format string, args ...interface{}) {
+	logError(code, format, args...)
+	os.Exit(code.exitStatus())
+}
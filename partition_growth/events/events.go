@@ -0,0 +1,693 @@
+// Package events decodes partition split event files.
+//
+// An event file is a sequence of top-level JSON documents, each either an
+// array of event objects or a single event object, one per line or otherwise
+// whitespace separated. The common cases are one array and a stream of
+// objects; several concatenated arrays, as left by merging rotated files,
+// are read to the end as well. A leading UTF-8 byte order mark is skipped.
+// Reader yields one Event at a time, so callers never hold the whole file in
+// memory.
+//
+// Decoding is tolerant in the ways the producers of these files require:
+//
+//   - ID fields may be JSON numbers or strings holding a number ("42").
+//   - Dates may use any layout in DateLayouts; ParseDate tries them in order.
+//
+// Canonical renders an Event in a normalized JSON form suitable for hashing,
+// diffing and re-ingestion by other tools.
+//
+// A Reader can report progress to the embedding program through
+// SetProgress. The callback runs on the goroutine calling Next, after every
+// interval of records or bytes and once more, with Done set, when the input
+// ends or fails. See the Progress example for forwarding it to a channel.
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is one partition split record.
+type Event struct {
+	Date           string `json:"date"`
+	ParentID       int    `json:"parentId"`
+	FirstChildID   int    `json:"firstChildId"`
+	SecondChildID  int    `json:"secondChildId"`
+	LeaderNodeInfo string `json:"leaderNodeInfo"`
+	Value          int    `json:"value,omitempty"` // optional numeric payload, e.g. a duration
+	Size           int64  `json:"size,omitempty"`  // optional payload size in bytes; 0 when absent
+
+	// Weight is the record's value of the Reader's weight field (see
+	// SetWeightField); HasWeight is false when the field is absent or null.
+	Weight    int  `json:"-"`
+	HasWeight bool `json:"-"`
+
+	// Type is the record's value of the Reader's type field (see
+	// SetTypeField); HasType is false when the field is absent or null.
+	Type    string `json:"-"`
+	HasType bool   `json:"-"`
+
+	// Enriched holds fields added after decoding by the caller; the Reader
+	// never sets it.
+	Enriched map[string]string `json:"-"`
+
+	// Overflow has an Overflow* bit for each ID field whose value did not
+	// fit in an int; that field holds the nearest int instead.
+	Overflow uint8 `json:"-"`
+}
+
+// Overflow bits of Event.
+const (
+	OverflowParentID uint8 = 1 << iota
+	OverflowFirstChildID
+	OverflowSecondChildID
+)
+
+// errOutOfRange is returned by decodeID for an integer beyond int64, and by
+// decodeInt for one beyond int.
+var errOutOfRange = errors.New("out of range")
+
+// DecodeError is returned by Reader.Next for input that is not valid JSON or
+// whose records do not decode.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return e.Err.Error() }
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// ErrMissingDate is returned for an empty or blank date, which is what an
+// absent or null "date" field decodes to.
+var ErrMissingDate = errors.New("missing date")
+
+// DateLayouts lists the accepted date layouts, most common first. Layouts
+// without a zone are interpreted as UTC.
+var DateLayouts = []string{
+	"Jan 2, 2006, 3:04:05 PM",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseDate parses s with the first matching layout in DateLayouts. On
+// failure the error from the primary layout is returned.
+func ParseDate(s string) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return time.Time{}, ErrMissingDate
+	}
+	var first error
+	for _, layout := range DateLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return time.Time{}, first
+}
+
+// ParseDateIn parses s like ParseDate but reads zone-less timestamps as wall
+// clock time in loc; timestamps that carry an offset are converted to loc.
+// Around daylight-saving transitions the policy is:
+//
+//   - A repeated wall time (fall-back fold) resolves to its first, earlier
+//     occurrence.
+//   - A wall time skipped by spring-forward is moved forward by the length of
+//     the gap (02:30 becomes 03:30 when clocks jump from 02:00 to 03:00).
+//
+// Every input therefore maps to exactly one instant; no event is dropped or
+// counted twice because of DST.
+func ParseDateIn(s string, loc *time.Location) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return time.Time{}, ErrMissingDate
+	}
+	var first error
+	for _, layout := range DateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		if layoutHasZone(layout) {
+			return t.In(loc), nil
+		}
+		return resolveWall(t, loc), nil
+	}
+	return time.Time{}, first
+}
+
+// DateOffset returns the UTC offset, in seconds east, written in s, and false
+// when s matches no layout with a zone.
+func DateOffset(s string) (int, bool) {
+	for _, layout := range DateLayouts {
+		if !layoutHasZone(layout) {
+			continue
+		}
+		if t, err := time.Parse(layout, s); err == nil {
+			_, off := t.Zone()
+			return off, true
+		}
+	}
+	return 0, false
+}
+
+// YearOptions opts in to parsing dates whose year is abbreviated or missing.
+// The zero value accepts neither, so such dates stay parse errors.
+type YearOptions struct {
+	// TwoDigitPivot reads a two-digit year yy as the year in
+	// [TwoDigitPivot, TwoDigitPivot+99] ending in yy; 0 disables.
+	TwoDigitPivot int
+	// AssumeYear is used for dates that have no year; 0 disables.
+	AssumeYear int
+}
+
+// Inference says what ParseDateYears had to assume about a date's year.
+type Inference int
+
+const (
+	InferNone         Inference = iota
+	InferTwoDigitYear           // year expanded from two digits
+	InferAssumedYear            // year supplied by YearOptions.AssumeYear
+)
+
+// TwoDigitYearLayouts and YearlessLayouts are tried by ParseDateYears, in
+// order, after DateLayouts.
+var (
+	TwoDigitYearLayouts = []string{"Jan 2, 06, 3:04:05 PM", "06-01-02 15:04:05"}
+	YearlessLayouts     = []string{"Jan 2, 3:04:05 PM", "01-02 15:04:05"}
+)
+
+// ParseDateYears parses s like ParseDateIn and, when opts allow it, dates
+// with a two-digit or missing year. It reports which assumption, if any, was
+// needed. On failure the error is the one ParseDateIn returns.
+func ParseDateYears(s string, loc *time.Location, opts YearOptions) (time.Time, Inference, error) {
+	t, err := ParseDateIn(s, loc)
+	if err == nil || errors.Is(err, ErrMissingDate) {
+		return t, InferNone, err
+	}
+	if opts.TwoDigitPivot != 0 {
+		for _, layout := range TwoDigitYearLayouts {
+			w, perr := time.Parse(layout, s)
+			if perr != nil {
+				continue
+			}
+			yy := w.Year() % 100
+			year := opts.TwoDigitPivot - opts.TwoDigitPivot%100 + yy
+			if year < opts.TwoDigitPivot {
+				year += 100
+			}
+			if w, ok := withYear(w, year); ok {
+				return resolveWall(w, loc), InferTwoDigitYear, nil
+			}
+		}
+	}
+	if opts.AssumeYear != 0 {
+		for _, layout := range YearlessLayouts {
+			w, perr := time.Parse(layout, s)
+			if perr != nil {
+				continue
+			}
+			if w, ok := withYear(w, opts.AssumeYear); ok {
+				return resolveWall(w, loc), InferAssumedYear, nil
+			}
+		}
+	}
+	return time.Time{}, InferNone, err
+}
+
+// withYear moves the UTC wall time w to year; it fails for Feb 29 outside
+// leap years rather than rolling over to Mar 1.
+func withYear(w time.Time, year int) (time.Time, bool) {
+	t := time.Date(year, w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), w.Nanosecond(), time.UTC)
+	return t, t.Day() == w.Day()
+}
+
+func layoutHasZone(layout string) bool {
+	return strings.Contains(layout, "Z07") || strings.Contains(layout, "MST") || strings.Contains(layout, "-07")
+}
+
+// resolveWall interprets the UTC wall clock fields of w as local time in loc
+// following the DST policy of ParseDateIn.
+func resolveWall(w time.Time, loc *time.Location) time.Time {
+	if loc == time.UTC {
+		return w
+	}
+	// The offsets in effect half a day either side cover both sides of any
+	// transition near w.
+	_, before := w.Add(-12 * time.Hour).In(loc).Zone()
+	_, after := w.Add(12 * time.Hour).In(loc).Zone()
+	var match []time.Time
+	for _, off := range []int{before, after} {
+		t := w.Add(-time.Duration(off) * time.Second).In(loc)
+		if sameWall(t, w) && (len(match) == 0 || !match[0].Equal(t)) {
+			match = append(match, t)
+		}
+	}
+	switch len(match) {
+	case 0: // in a gap: keep the pre-transition offset, which lands after the gap
+		return w.Add(-time.Duration(before) * time.Second).In(loc)
+	case 2:
+		if match[1].Before(match[0]) {
+			return match[1]
+		}
+	}
+	return match[0]
+}
+
+func sameWall(t, w time.Time) bool {
+	ty, tm, td := t.Date()
+	wy, wm, wd := w.Date()
+	return ty == wy && tm == wm && td == wd && t.Hour() == w.Hour() &&
+		t.Minute() == w.Minute() && t.Second() == w.Second() && t.Nanosecond() == w.Nanosecond()
+}
+
+// Time parses the event date with ParseDate.
+func (e Event) Time() (time.Time, error) {
+	return ParseDate(e.Date)
+}
+
+// UnmarshalJSON accepts ID, value and size fields as numbers or numeric
+// strings, including integers in exponent form such as 1.5e3. An ID beyond
+// int64 is not an error; it is clamped and flagged in Overflow.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Date           string          `json:"date"`
+		ParentID       json.RawMessage `json:"parentId"`
+		FirstChildID   json.RawMessage `json:"firstChildId"`
+		SecondChildID  json.RawMessage `json:"secondChildId"`
+		LeaderNodeInfo string          `json:"leaderNodeInfo"`
+		Value          json.RawMessage `json:"value"`
+		Size           json.RawMessage `json:"size"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var ev Event
+	ev.Date = raw.Date
+	ev.LeaderNodeInfo = raw.LeaderNodeInfo
+	for _, f := range []struct {
+		name     string
+		raw      json.RawMessage
+		dst      *int
+		overflow uint8 // 0 when overflow is an error
+	}{
+		{"parentId", raw.ParentID, &ev.ParentID, OverflowParentID},
+		{"firstChildId", raw.FirstChildID, &ev.FirstChildID, OverflowFirstChildID},
+		{"secondChildId", raw.SecondChildID, &ev.SecondChildID, OverflowSecondChildID},
+		{"value", raw.Value, &ev.Value, 0},
+	} {
+		v, err := decodeInt(f.raw)
+		if errors.Is(err, errOutOfRange) && f.overflow != 0 {
+			ev.Overflow |= f.overflow
+			err = nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.name, err)
+		}
+		*f.dst = v
+	}
+	size, err := decodeID(raw.Size)
+	if err != nil {
+		return fmt.Errorf("size: %v", err)
+	}
+	ev.Size = size
+	*e = ev
+	return nil
+}
+
+// decodeField reads the numeric field name of a record, reporting whether it
+// was present and not null.
+func decodeField(fields map[string]json.RawMessage, name string) (int, bool, error) {
+	v, ok := fields[name]
+	if !ok || string(bytes.TrimSpace(v)) == "null" {
+		return 0, false, nil
+	}
+	n, err := decodeInt(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %v", name, err)
+	}
+	return n, true, nil
+}
+
+// decodeStringField reads the field name of a record as a string; a number is
+// taken as written. It reports whether the field was present and not null.
+func decodeStringField(fields map[string]json.RawMessage, name string) (string, bool, error) {
+	v := bytes.TrimSpace(fields[name])
+	if len(v) == 0 || string(v) == "null" {
+		return "", false, nil
+	}
+	if v[0] != '"' {
+		if _, err := strconv.ParseFloat(string(v), 64); err != nil {
+			return "", false, fmt.Errorf("%s: want a string, got %s", name, v)
+		}
+		return string(v), true, nil
+	}
+	var s string
+	if err := json.Unmarshal(v, &s); err != nil {
+		return "", false, fmt.Errorf("%s: %v", name, err)
+	}
+	return s, true, nil
+}
+
+// decodeID accepts a JSON number, a string holding a number, null or nothing.
+// The number must be an integer but may be written with a fraction or an
+// exponent (9.2e18); it is parsed exactly, never through a float64. Beyond
+// int64 it returns the nearest int64 and an error wrapping errOutOfRange.
+func decodeID(raw json.RawMessage) (int64, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+	s := string(raw)
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, err
+		}
+		s = strings.TrimSpace(s)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return n, nil
+	}
+	if !errors.Is(err, strconv.ErrRange) {
+		f, _, perr := big.ParseFloat(s, 10, 256, big.ToZero)
+		if perr != nil || strings.Trim(s, "+-.0123456789eE") != "" || !f.IsInt() {
+			return 0, fmt.Errorf("invalid number %s", raw)
+		}
+		if i, acc := f.Int64(); acc == big.Exact {
+			return i, nil
+		}
+		if f.Sign() < 0 {
+			n = math.MinInt64
+		} else {
+			n = math.MaxInt64
+		}
+	} else {
+		n, _ = strconv.ParseInt(s, 10, 64) // clamped by ParseInt
+	}
+	return n, fmt.Errorf("%s: %w", raw, errOutOfRange)
+}
+
+// decodeInt is decodeID for an int field. Beyond int, which on 32-bit
+// platforms is narrower than int64, it returns the nearest int and an error
+// wrapping errOutOfRange.
+func decodeInt(raw json.RawMessage) (int, error) {
+	n, err := decodeID(raw)
+	switch {
+	case n > math.MaxInt:
+		return math.MaxInt, fmt.Errorf("%s: %w", bytes.TrimSpace(raw), errOutOfRange)
+	case n < math.MinInt:
+		return math.MinInt, fmt.Errorf("%s: %w", bytes.TrimSpace(raw), errOutOfRange)
+	}
+	return int(n), err
+}
+
+// canonicalEvent fixes the field order and types of Canonical output.
+type canonicalEvent struct {
+	Date           string `json:"date"`
+	ParentID       int    `json:"parentId"`
+	FirstChildID   int    `json:"firstChildId"`
+	SecondChildID  int    `json:"secondChildId"`
+	LeaderNodeInfo string `json:"leaderNodeInfo"`
+	Value          int    `json:"value,omitempty"`
+	Size           int64  `json:"size,omitempty"`
+}
+
+// Canonical returns the normalized JSON form of e: the date in RFC 3339 UTC,
+// numeric IDs and a fixed field order. Decoding the result and calling
+// Canonical again yields identical bytes.
+func (e Event) Canonical() ([]byte, error) {
+	t, err := e.Time()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(canonicalEvent{
+		Date:           t.UTC().Format(time.RFC3339),
+		ParentID:       e.ParentID,
+		FirstChildID:   e.FirstChildID,
+		SecondChildID:  e.SecondChildID,
+		LeaderNodeInfo: e.LeaderNodeInfo,
+		Value:          e.Value,
+		Size:           e.Size,
+	})
+}
+
+// Provenance locates a record within its input.
+type Provenance struct {
+	Source string // name passed to NewReader, usually the file path
+	Index  int    // 0-based record number within the source
+	Offset int64  // byte offset of the end of the record
+}
+
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s record %d", p.Source, p.Index+1)
+}
+
+// Progress is the state passed to a progress callback.
+type Progress struct {
+	Source    string // name given to NewReader
+	Records   int    // events decoded so far
+	Documents int    // top-level arrays and objects started so far
+	Bytes     int64  // input bytes consumed so far
+	Errors    int    // decode errors; a decode error ends the input
+	Done      bool   // set on the final call
+}
+
+// ProgressOptions sets how often the progress callback runs. A call is made
+// when either interval has passed since the previous one; zero disables that
+// interval. With both zero only the final call is made.
+type ProgressOptions struct {
+	EveryRecords int
+	EveryBytes   int64
+}
+
+// Reader yields events from a sequence of JSON arrays and objects.
+type Reader struct {
+	src     string
+	br      *bufio.Reader
+	dec     *json.Decoder
+	base    int64 // input offset at which dec started reading
+	inArray bool
+	index   int
+	docs    int
+	done    bool
+
+	progress     func(Progress)
+	progressOpts ProgressOptions
+	lastRecords  int
+	lastBytes    int64
+	errors       int
+
+	keepRaw     bool
+	raw         json.RawMessage
+	weightField string
+	typeField   string
+}
+
+// SetProgress registers fn to receive progress as described in the package
+// documentation. It must be called before the first Next.
+func (r *Reader) SetProgress(opts ProgressOptions, fn func(Progress)) {
+	r.progressOpts = opts
+	r.progress = fn
+}
+
+// SetKeepRaw makes Next retain the bytes of each record for Raw. Decoding
+// is somewhat slower, so it is off by default.
+func (r *Reader) SetKeepRaw(keep bool) {
+	r.keepRaw = keep
+}
+
+// Raw returns the JSON of the record last returned by Next when SetKeepRaw is
+// on. The slice is only valid until the next call to Next.
+func (r *Reader) Raw() json.RawMessage {
+	return r.raw
+}
+
+// SetWeightField makes Next read the named numeric field of each record into
+// Event.Weight. Like the ID fields it may be a number or a numeric string.
+func (r *Reader) SetWeightField(name string) {
+	r.weightField = name
+}
+
+// SetTypeField makes Next read the named field of each record into
+// Event.Type.
+func (r *Reader) SetTypeField(name string) {
+	r.typeField = name
+}
+
+// extraFields fills the Weight and Type of ev from the raw record.
+func (r *Reader) extraFields(ev *Event) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(r.raw, &fields); err != nil {
+		return err
+	}
+	var err error
+	if r.weightField != "" {
+		if ev.Weight, ev.HasWeight, err = decodeField(fields, r.weightField); err != nil {
+			return err
+		}
+	}
+	if r.typeField != "" {
+		ev.Type, ev.HasType, err = decodeStringField(fields, r.typeField)
+	}
+	return err
+}
+
+func (r *Reader) bytesRead() int64 {
+	if r.dec == nil {
+		return r.base
+	}
+	return r.base + r.dec.InputOffset()
+}
+
+// Documents returns the number of top-level arrays and objects read so far.
+func (r *Reader) Documents() int {
+	return r.docs
+}
+
+// report calls the progress callback if an interval has passed, or always
+// when done.
+func (r *Reader) report(done bool) {
+	if r.progress == nil {
+		return
+	}
+	p := Progress{Source: r.src, Records: r.index, Documents: r.docs, Bytes: r.bytesRead(), Errors: r.errors, Done: done}
+	o := r.progressOpts
+	if !done &&
+		(o.EveryRecords <= 0 || p.Records-r.lastRecords < o.EveryRecords) &&
+		(o.EveryBytes <= 0 || p.Bytes-r.lastBytes < o.EveryBytes) {
+		return
+	}
+	r.lastRecords, r.lastBytes = p.Records, p.Bytes
+	r.progress(p)
+}
+
+// finish marks the input as ended and makes the final progress call.
+func (r *Reader) finish(err error) error {
+	if !r.done {
+		r.done = true
+		if err != nil && !errors.Is(err, io.EOF) {
+			r.errors++
+		}
+		r.report(true)
+	}
+	return err
+}
+
+// NewReader returns a Reader over r. source names the input in Provenance
+// and error messages.
+func NewReader(r io.Reader, source string) *Reader {
+	return &Reader{src: source, br: bufio.NewReader(r)}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// peek returns the first byte of the next top-level document without
+// consuming it, or io.EOF at the end of the input. The byte is looked for in
+// what the decoder has buffered; when that is only whitespace, the input is
+// read directly and a fresh decoder takes over from there, so pipes work as
+// well as files.
+func (r *Reader) peek() (byte, error) {
+	if r.dec != nil {
+		buf := r.dec.Buffered()
+		var one [1]byte
+		skipped := int64(0)
+		for {
+			if n, _ := buf.Read(one[:]); n == 0 {
+				break
+			}
+			if !isSpace(one[0]) {
+				return one[0], nil
+			}
+			skipped++
+		}
+		r.base += r.dec.InputOffset() + skipped
+		r.dec = nil
+	} else if r.base == 0 {
+		if b, err := r.br.Peek(3); err == nil && string(b) == "\xef\xbb\xbf" {
+			r.br.Discard(3)
+			r.base = 3
+		}
+	}
+	for {
+		b, err := r.br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !isSpace(b[0]) {
+			r.dec = json.NewDecoder(r.br)
+			return b[0], nil
+		}
+		r.br.ReadByte()
+		r.base++
+	}
+}
+
+// Next returns the next event. It returns io.EOF after the last record; any
+// other error is a *DecodeError and ends the input, because JSON cannot be
+// resynchronized after malformed data.
+func (r *Reader) Next() (Event, Provenance, error) {
+	var ev Event
+	prov := Provenance{Source: r.src, Index: r.index}
+	if r.done {
+		return ev, prov, io.EOF
+	}
+	for !r.inArray || !r.dec.More() {
+		if r.inArray {
+			if _, err := r.dec.Token(); err != nil {
+				return ev, prov, r.finish(&DecodeError{fmt.Errorf("%s: error closing array: %v", r.src, err)})
+			}
+			r.inArray = false
+		}
+		first, err := r.peek()
+		if errors.Is(err, io.EOF) {
+			return ev, prov, r.finish(io.EOF)
+		}
+		if err != nil {
+			return ev, prov, r.finish(&DecodeError{fmt.Errorf("%s: error reading JSON: %v", r.src, err)})
+		}
+		r.docs++
+		if first != '[' {
+			break
+		}
+		if _, err := r.dec.Token(); err != nil {
+			return ev, prov, r.finish(&DecodeError{fmt.Errorf("%s: error reading JSON: %v", r.src, err)})
+		}
+		r.inArray = true
+	}
+	var err error
+	if r.keepRaw || r.weightField != "" || r.typeField != "" {
+		r.raw = r.raw[:0]
+		if err = r.dec.Decode(&r.raw); err == nil {
+			err = json.Unmarshal(r.raw, &ev)
+		}
+		if err == nil && (r.weightField != "" || r.typeField != "") {
+			err = r.extraFields(&ev)
+		}
+	} else {
+		err = r.dec.Decode(&ev)
+	}
+	if err != nil {
+		return ev, prov, r.finish(&DecodeError{fmt.Errorf("error decoding JSON %s: %v", prov, err)})
+	}
+	prov.Offset = r.bytesRead()
+	r.index++
+	r.report(false)
+	return ev, prov, nil
+}
@@ -0,0 +1,445 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll returns the events of input and the error that ended it, nil at
+// io.EOF.
+func readAll(t *testing.T, input string) ([]Event, error) {
+	t.Helper()
+	r := NewReader(strings.NewReader(input), "test")
+	var out []Event
+	for {
+		ev, _, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, ev)
+	}
+}
+
+func TestReader(t *testing.T) {
+	one := Event{Date: "2025-01-02T03:04:05Z", ParentID: 1, FirstChildID: 2, SecondChildID: 3, LeaderNodeInfo: "n1"}
+	two := Event{Date: "Jan 2, 2025, 3:04:05 AM", ParentID: 4, FirstChildID: 5, SecondChildID: 6, LeaderNodeInfo: "n2"}
+	const (
+		rec1 = `{"date":"2025-01-02T03:04:05Z","parentId":1,"firstChildId":2,"secondChildId":3,"leaderNodeInfo":"n1"}`
+		rec2 = `{"date":"Jan 2, 2025, 3:04:05 AM","parentId":"4","firstChildId":" 5 ","secondChildId":6,"leaderNodeInfo":"n2"}`
+	)
+	tests := []struct {
+		name  string
+		input string
+		want  []Event
+	}{
+		{"array", "[" + rec1 + "," + rec2 + "]", []Event{one, two}},
+		{"object stream", rec1 + "\n" + rec2 + "\n", []Event{one, two}},
+		{"objects on one line", rec1 + rec2, []Event{one, two}},
+		{"leading whitespace", "\n\t  [" + rec1 + "]", []Event{one}},
+		{"CRLF object stream", rec1 + "\r\n" + rec2 + "\r\n", []Event{one, two}},
+		{"CRLF array", "[\r\n" + rec1 + ",\r\n" + rec2 + "\r\n]\r\n", []Event{one, two}},
+		{"empty array", "[]", nil},
+		{"empty input", "", nil},
+		{"whitespace only", " \n ", nil},
+		{"null IDs", `{"date":"2025-01-02","parentId":null,"leaderNodeInfo":"x"}`,
+			[]Event{{Date: "2025-01-02", LeaderNodeInfo: "x"}}},
+		{"value and size", `{"date":"2025-01-02","value":"7","size":1.5e3}`,
+			[]Event{{Date: "2025-01-02", Value: 7, Size: 1500}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		records int    // decoded before the error
+		msg     string // in the error text
+	}{
+		{"malformed record", `[{"parentId":1},{"parentId":]`, 1, "test record 2"},
+		{"non-numeric ID", `{"parentId":"abc"}`, 0, "parentId: invalid number"},
+		{"fractional ID", `{"parentId":1.5}`, 0, "parentId: invalid number"},
+		{"truncated array", `[{"parentId":1}`, 1, "test"},
+		{"bare value", `42`, 0, "test record 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(t, tt.input)
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("error = %v, want a *DecodeError", err)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("error %q does not mention %q", err, tt.msg)
+			}
+			if len(got) != tt.records {
+				t.Errorf("decoded %d records before the error, want %d", len(got), tt.records)
+			}
+		})
+	}
+}
+
+// TestReaderIDRange decodes IDs written with exponents and IDs beyond the
+// platform int: these set the field's Overflow bit and hold the nearest int,
+// while a value beyond int is an error.
+func TestReaderIDRange(t *testing.T) {
+	big := "3e9" // beyond a 32-bit int
+	if strconv.IntSize == 64 {
+		big = "1e19"
+	}
+	tests := []struct {
+		name     string
+		input    string
+		want     Event
+		overflow bool
+	}{
+		{"exponent", `{"parentId":1e3,"firstChildId":"2.5e1","secondChildId":1000.0}`,
+			Event{ParentID: 1000, FirstChildID: 25, SecondChildID: 1000}, false},
+		{"max int", fmt.Sprintf(`{"parentId":%d,"firstChildId":%d}`, math.MaxInt, math.MinInt),
+			Event{ParentID: math.MaxInt, FirstChildID: math.MinInt}, false},
+		{"beyond int", `{"parentId":` + big + `,"firstChildId":"-` + big + `","secondChildId":` + big + `}`,
+			Event{ParentID: math.MaxInt, FirstChildID: math.MinInt, SecondChildID: math.MaxInt,
+				Overflow: OverflowParentID | OverflowFirstChildID | OverflowSecondChildID}, true},
+		{"size beyond int32", `{"size":3e9}`, Event{Size: 3e9}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(t, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := readAll(t, `{"value":`+big+`}`); err == nil || !strings.Contains(err.Error(), "value: "+big+": out of range") {
+		t.Errorf("value beyond int: error %v", err)
+	}
+}
+
+// TestReaderDocuments reads inputs made of several top-level documents, as
+// merging rotated files produces, and counts them: no document after the
+// first may be dropped.
+func TestReaderDocuments(t *testing.T) {
+	rec := func(id int) string { return fmt.Sprintf(`{"parentId":%d}`, id) }
+	arr := func(ids ...int) string {
+		var recs []string
+		for _, id := range ids {
+			recs = append(recs, rec(id))
+		}
+		return "[" + strings.Join(recs, ",") + "]"
+	}
+	tests := []struct {
+		name    string
+		input   string
+		parents []int
+		docs    int
+	}{
+		{"three concatenated arrays", arr(1, 2) + arr(3) + arr(4, 5, 6), []int{1, 2, 3, 4, 5, 6}, 3},
+		{"arrays on separate lines", arr(1) + "\n" + arr(2) + "\r\n\n" + arr(3) + "\n", []int{1, 2, 3}, 3},
+		{"empty arrays between", arr(1) + "[]" + " [ ] " + arr(2), []int{1, 2}, 4},
+		{"array then objects", arr(1, 2) + "\n" + rec(3) + "\n" + rec(4), []int{1, 2, 3, 4}, 3},
+		{"objects then array", rec(1) + arr(2, 3), []int{1, 2, 3}, 2},
+		{"BOM before an array", "\xef\xbb\xbf" + arr(1, 2), []int{1, 2}, 1},
+		{"BOM and whitespace before arrays", "\xef\xbb\xbf \r\n\t" + arr(1) + arr(2), []int{1, 2}, 2},
+		{"BOM before a stream", "\xef\xbb\xbf" + rec(1) + "\n" + rec(2), []int{1, 2}, 2},
+		{"only a BOM", "\xef\xbb\xbf", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input), "test")
+			var parents []int
+			for {
+				ev, _, err := r.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("after %v: %v", parents, err)
+				}
+				parents = append(parents, ev.ParentID)
+			}
+			if !reflect.DeepEqual(parents, tt.parents) {
+				t.Errorf("parents %v, want %v", parents, tt.parents)
+			}
+			if r.Documents() != tt.docs {
+				t.Errorf("Documents() = %d, want %d", r.Documents(), tt.docs)
+			}
+		})
+	}
+}
+
+// TestReaderBOMOnlyAtStart rejects a byte order mark anywhere but the start.
+func TestReaderBOMOnlyAtStart(t *testing.T) {
+	_, err := readAll(t, `[{"parentId":1}]`+"\xef\xbb\xbf"+`[{"parentId":2}]`)
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("error = %v, want a *DecodeError", err)
+	}
+}
+
+func TestReaderProvenance(t *testing.T) {
+	input := `[{"parentId":1}, {"parentId":2}]`
+	r := NewReader(strings.NewReader(input), "f.json")
+	for i := 0; i < 2; i++ {
+		_, prov, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prov.Source != "f.json" || prov.Index != i {
+			t.Errorf("record %d: provenance %+v", i, prov)
+		}
+		if got, want := prov.String(), fmt.Sprintf("f.json record %d", i+1); got != want {
+			t.Errorf("record %d: String() = %q, want %q", i, got, want)
+		}
+		if prov.Offset <= 0 || prov.Offset > int64(len(input)) {
+			t.Errorf("record %d: offset %d outside the input", i, prov.Offset)
+		}
+	}
+	if _, _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("after the last record: %v, want io.EOF", err)
+	}
+	if _, _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next after io.EOF: %v, want io.EOF again", err)
+	}
+}
+
+func TestReaderFields(t *testing.T) {
+	input := `{"parentId":1,"w":"3","kind":"split"}
+{"parentId":2,"w":null,"kind":7}
+{"parentId":3}`
+	r := NewReader(strings.NewReader(input), "test")
+	r.SetWeightField("w")
+	r.SetTypeField("kind")
+	want := []struct {
+		weight    int
+		hasWeight bool
+		typ       string
+		hasType   bool
+	}{
+		{3, true, "split", true},
+		{0, false, "7", true},
+		{0, false, "", false},
+	}
+	for i, w := range want {
+		ev, _, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Weight != w.weight || ev.HasWeight != w.hasWeight || ev.Type != w.typ || ev.HasType != w.hasType {
+			t.Errorf("record %d: weight %d/%v type %q/%v, want %d/%v %q/%v", i+1,
+				ev.Weight, ev.HasWeight, ev.Type, ev.HasType, w.weight, w.hasWeight, w.typ, w.hasType)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Mar 4, 2025, 5:06:07 AM", want},
+		{"2025-03-04T05:06:07Z", want},
+		{"2025-03-04T07:06:07+02:00", want},
+		{"2025-03-04 05:06:07", want},
+		{"2025-03-04T05:06:07", want},
+		{"2025-03-04", time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"2025-03-04T05:06:07.5Z", want.Add(500 * time.Millisecond)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.in)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"garbage", "2025-13-01", "04/03/2025"} {
+		if _, err := ParseDate(bad); err == nil || errors.Is(err, ErrMissingDate) {
+			t.Errorf("ParseDate(%q) = %v, want a parse error", bad, err)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "string IDs and local layout",
+			input: `{"leaderNodeInfo":"n1","secondChildId":"3","firstChildId":2,"parentId":"1","date":"Jan 2, 2025, 3:04:05 PM"}`,
+			want:  `{"date":"2025-01-02T15:04:05Z","parentId":1,"firstChildId":2,"secondChildId":3,"leaderNodeInfo":"n1"}`,
+		},
+		{
+			name:  "offset converted to UTC",
+			input: `{"date":"2025-01-02T01:00:00+02:00","parentId":1}`,
+			want:  `{"date":"2025-01-01T23:00:00Z","parentId":1,"firstChildId":0,"secondChildId":0,"leaderNodeInfo":""}`,
+		},
+		{
+			name:  "value and size kept",
+			input: `{"date":"2025-01-02","value":"9","size":"1e2"}`,
+			want:  `{"date":"2025-01-02T00:00:00Z","parentId":0,"firstChildId":0,"secondChildId":0,"leaderNodeInfo":"","value":9,"size":100}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ev Event
+			if err := json.Unmarshal([]byte(tt.input), &ev); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ev.Canonical()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Canonical() = %s\nwant %s", got, tt.want)
+			}
+			var again Event
+			if err := json.Unmarshal(got, &again); err != nil {
+				t.Fatal(err)
+			}
+			round, err := again.Canonical()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(round) != string(got) {
+				t.Errorf("round trip changed the bytes:\n%s\n%s", got, round)
+			}
+		})
+	}
+	if _, err := (Event{Date: "garbage"}).Canonical(); err == nil {
+		t.Error("Canonical of an unparseable date succeeded")
+	}
+}
+
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+// TestParseDateInDST pins the fold and gap policy of ParseDateIn to the 2025
+// transitions: New York springs forward on Mar 9 at 02:00 EST and falls back
+// on Nov 2 at 02:00 EDT; Berlin on Mar 30 at 02:00 CET and Oct 26 at 03:00
+// CEST.
+func TestParseDateInDST(t *testing.T) {
+	tests := []struct {
+		zone string
+		in   string
+		want string // RFC 3339 instant
+	}{
+		// Ordinary times either side of the transitions.
+		{"America/New_York", "2025-03-09 01:59:59", "2025-03-09T06:59:59Z"},
+		{"America/New_York", "2025-03-09 03:00:00", "2025-03-09T07:00:00Z"},
+		// Spring-forward gap: moved forward by the gap's length.
+		{"America/New_York", "2025-03-09 02:00:00", "2025-03-09T07:00:00Z"},
+		{"America/New_York", "2025-03-09 02:30:00", "2025-03-09T07:30:00Z"},
+		// Fall-back fold: the first, daylight-time occurrence.
+		{"America/New_York", "2025-11-02 01:30:00", "2025-11-02T05:30:00Z"},
+		{"America/New_York", "2025-11-02 00:59:59", "2025-11-02T04:59:59Z"},
+		{"America/New_York", "2025-11-02 02:00:00", "2025-11-02T07:00:00Z"},
+		{"Europe/Berlin", "2025-03-30 01:59:59", "2025-03-30T00:59:59Z"},
+		{"Europe/Berlin", "2025-03-30 02:30:00", "2025-03-30T01:30:00Z"},
+		{"Europe/Berlin", "2025-03-30 03:00:00", "2025-03-30T01:00:00Z"},
+		{"Europe/Berlin", "2025-10-26 02:30:00", "2025-10-26T00:30:00Z"},
+		{"Europe/Berlin", "2025-10-26 03:00:00", "2025-10-26T02:00:00Z"},
+		// Zoned input keeps its instant whatever the zone.
+		{"America/New_York", "2025-11-02T06:30:00Z", "2025-11-02T06:30:00Z"},
+		{"Europe/Berlin", "2025-10-26T02:30:00+01:00", "2025-10-26T01:30:00Z"},
+	}
+	for _, tt := range tests {
+		loc := mustZone(t, tt.zone)
+		got, err := ParseDateIn(tt.in, loc)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.zone, tt.in, err)
+			continue
+		}
+		if got.Location() != loc {
+			t.Errorf("%s %s: location %v, want %v", tt.zone, tt.in, got.Location(), loc)
+		}
+		if s := got.UTC().Format(time.RFC3339); s != tt.want {
+			t.Errorf("%s %s = %s, want %s", tt.zone, tt.in, s, tt.want)
+		}
+	}
+}
+
+// TestParseDateInDayBoundary covers instants whose local date differs from
+// their UTC date.
+func TestParseDateInDayBoundary(t *testing.T) {
+	tests := []struct {
+		zone, in, day string
+	}{
+		{"America/New_York", "2025-03-10T03:30:00Z", "2025-03-09"},
+		{"America/New_York", "2025-11-03T04:59:59Z", "2025-11-02"},
+		{"America/New_York", "2025-11-03T05:00:00Z", "2025-11-03"},
+		{"Europe/Berlin", "2025-03-29T23:30:00Z", "2025-03-30"},
+		{"Europe/Berlin", "2025-10-25T22:30:00Z", "2025-10-26"},
+		{"Europe/Berlin", "2025-12-31T23:00:00Z", "2026-01-01"},
+	}
+	for _, tt := range tests {
+		got, err := ParseDateIn(tt.in, mustZone(t, tt.zone))
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.zone, tt.in, err)
+			continue
+		}
+		if d := got.Format("2006-01-02"); d != tt.day {
+			t.Errorf("%s %s falls on %s, want %s", tt.zone, tt.in, d, tt.day)
+		}
+	}
+}
+
+// TestParseDateInEveryMinute walks every wall-clock minute of the
+// transition days. Each must read back as the same wall time in the zone,
+// except the minutes of the spring-forward gap, which read back one hour
+// later.
+func TestParseDateInEveryMinute(t *testing.T) {
+	for _, tc := range []struct {
+		zone, day string
+		gap       int // hour of the spring-forward gap, -1 for none
+	}{
+		{"America/New_York", "2025-03-09", 2}, {"America/New_York", "2025-11-02", -1},
+		{"Europe/Berlin", "2025-03-30", 2}, {"Europe/Berlin", "2025-10-26", -1},
+	} {
+		loc := mustZone(t, tc.zone)
+		for m := 0; m < 24*60; m++ {
+			in := fmt.Sprintf("%s %02d:%02d:00", tc.day, m/60, m%60)
+			got, err := ParseDateIn(in, loc)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tc.zone, in, err)
+			}
+			want := in
+			if m/60 == tc.gap {
+				want = fmt.Sprintf("%s %02d:%02d:00", tc.day, m/60+1, m%60)
+			}
+			if wall := got.Format("2006-01-02 15:04:05"); wall != want {
+				t.Errorf("%s %s reads back as %s, want %s", tc.zone, in, wall, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package events_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"partition_growth/events"
+)
+
+// ExampleProgress forwards progress to a channel, as a UI on another
+// goroutine would read it. The callback runs on the goroutine calling Next,
+// so it must not block decoding on a slow reader.
+func ExampleProgress() {
+	input := `[
+{"date":"2025-03-14T01:00:00Z","parentId":1,"firstChildId":2,"secondChildId":3,"leaderNodeInfo":"a"},
+{"date":"2025-03-14T02:00:00Z","parentId":4,"firstChildId":5,"secondChildId":6,"leaderNodeInfo":"b"},
+{"date":"2025-03-14T03:00:00Z","parentId":7,"firstChildId":8,"secondChildId":9,"leaderNodeInfo":"a"}
+]`
+	updates := make(chan events.Progress, 16)
+	r := events.NewReader(strings.NewReader(input), "splits.json")
+	r.SetProgress(events.ProgressOptions{EveryRecords: 2}, func(p events.Progress) {
+		select {
+		case updates <- p:
+		default: // never block decoding on a slow UI
+		}
+	})
+	for {
+		if _, _, err := r.Next(); err != nil {
+			if err != io.EOF {
+				fmt.Println(err)
+			}
+			break
+		}
+	}
+	close(updates)
+	for p := range updates {
+		fmt.Printf("%s: %d records, %d bytes, done %v\n", p.Source, p.Records, p.Bytes, p.Done)
+	}
+	// Output:
+	// splits.json: 2 records, 204 bytes, done false
+	// splits.json: 3 records, 308 bytes, done true
+}
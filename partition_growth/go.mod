@@ -0,0 +1,3 @@
+module partition_growth
+
+go 1.25.7
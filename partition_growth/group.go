@@ -0,0 +1,207 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"partition_growth/topk"
+)
+
+// ── -group-by ─────────────────────────────────────────────────────────────────
+
+var groupDimensions = []string{"parent", "leader"}
+
+// validGroupDimension reports whether d is a built-in dimension. -group-by
+// also accepts enrich.<name>; see enrichField.
+func validGroupDimension(d string) bool {
+	for _, g := range groupDimensions {
+		if g == d {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey returns the value of dimension dim for evt.
+func groupKey(dim string, evt Event) string {
+	if f, ok := enrichField(dim); ok {
+		return evt.Enriched[f]
+	}
+	if dim == "leader" {
+		return evt.LeaderNodeInfo
+	}
+	return strconv.Itoa(evt.ParentID)
+}
+
+// groupCounter counts filtered events per group value. It is exact unless
+// approxThreshold is set and the number of distinct values exceeds it, at
+// which point it switches to a Space-Saving counter of that capacity.
+type groupCounter struct {
+	dim             string
+	approxThreshold int
+	exact           map[string]int
+	approx          *topk.SpaceSaving
+}
+
+func newGroupCounter(dim string, approxThreshold int) *groupCounter {
+	return &groupCounter{dim: dim, approxThreshold: approxThreshold, exact: make(map[string]int)}
+}
+
+func (g *groupCounter) add(evt Event) {
+	key := groupKey(g.dim, evt)
+	if g.approx != nil {
+		g.approx.Add(key)
+		return
+	}
+	g.exact[key]++
+	if g.approxThreshold > 0 && len(g.exact) > g.approxThreshold {
+		g.approx = topk.FromCounts(g.approxThreshold, g.exact)
+		g.exact = nil
+	}
+}
+
+// size reports the distinct values held and their approximate bytes.
+func (g *groupCounter) size() (int, int64) {
+	if g.approx != nil {
+		n, b := g.approx.Len(), int64(0)
+		for _, it := range g.approx.Top(n) {
+			b += int64(stringHeader+len(it.Key)) + 24 + mapEntryOverhead
+		}
+		return n, b
+	}
+	return len(g.exact), stringIntMapBytes(g.exact)
+}
+
+// top returns the n largest groups by count, ties broken by key ascending.
+func (g *groupCounter) top(n int) []topk.Item {
+	if g.approx != nil {
+		return g.approx.Top(n)
+	}
+	items := make([]topk.Item, 0, len(g.exact))
+	for k, v := range g.exact {
+		items = append(items, topk.Item{Key: k, Count: v})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return compareKeys(items[i].Key, items[j].Key) < 0
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+// ── -sort ─────────────────────────────────────────────────────────────────────
+
+// sortOrder is a -sort value: the order of the rows of a grouped listing.
+// Ties on count fall back to keys ascending; no two keys tie, so the order
+// is the same on every run. The zero value is count descending.
+type sortOrder struct {
+	byKey bool
+	asc   bool
+}
+
+// sortOrders lists the -sort values and the listings that honor them.
+var (
+	sortOrders   = []string{"key", "-key", "count", "-count"}
+	sortListings = []string{"-group-by", "-top-detail"}
+)
+
+func parseSortOrder(v string) (sortOrder, error) {
+	switch v {
+	case "", "-count":
+		return sortOrder{}, nil
+	case "count":
+		return sortOrder{asc: true}, nil
+	case "key":
+		return sortOrder{byKey: true, asc: true}, nil
+	case "-key":
+		return sortOrder{byKey: true}, nil
+	}
+	return sortOrder{}, fmt.Errorf("unknown -sort %q (want %s)", v, strings.Join(sortOrders, ", "))
+}
+
+// compareKeys orders group values numerically when both are numbers, as
+// ParentIDs are, numbers before other values, and as strings otherwise.
+// Only equal strings compare equal: "01" and "1" fall back to the strings.
+func compareKeys(a, b string) int {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if c := cmp.Compare(na, nb); c != 0 {
+			return c
+		}
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// sort orders items in place; listings select their rows by count first,
+// so the order only changes how the selected rows are shown.
+func (o sortOrder) sort(items []topk.Item) {
+	sort.Slice(items, func(i, j int) bool {
+		c := compareKeys(items[i].Key, items[j].Key)
+		if !o.byKey {
+			if n := items[i].Count - items[j].Count; n != 0 {
+				return n < 0 == o.asc
+			}
+			return c < 0
+		}
+		return c != 0 && c < 0 == o.asc
+	})
+}
+
+func (g *groupCounter) total() int {
+	if g.approx != nil {
+		return g.approx.Total()
+	}
+	t := 0
+	for _, v := range g.exact {
+		t += v
+	}
+	return t
+}
+
+func buildGroupBy(a *aggregates) []section {
+	g := a.groups
+	items := g.top(a.opts.groupTop)
+	a.opts.sort.sort(items)
+	total := g.total()
+	s := section{
+		Key:     "group_by",
+		Title:   fmt.Sprintf("Top %d by %s", a.opts.groupTop, g.dim),
+		Columns: []string{strings.ToUpper(g.dim[:1]) + g.dim[1:], "Events", "Share"},
+
+		Truncatable: true,
+	}
+	if g.approx != nil {
+		s.Columns = append(s.Columns, "± Error")
+	}
+	for _, it := range items {
+		row := []string{it.Key, strconv.Itoa(it.Count), pct(it.Count, total)}
+		if g.approx != nil {
+			row = append(row, strconv.Itoa(it.Err))
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	if g.approx != nil {
+		s.Notes = append(s.Notes,
+			fmt.Sprintf("Approximate: more than %d distinct %s values; counts are upper bounds, at most ± Error above the true count.", g.approxThreshold, g.dim),
+			fmt.Sprintf("Values not listed have at most %d events.", g.approx.MaxError()))
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Distinct %s values: %d", g.dim, len(g.exact)))
+	}
+	if len(items) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"9", "10", -1},
+		{"-1", strconv.Itoa(math.MaxInt64), -1},
+		{strconv.Itoa(math.MinInt64), strconv.Itoa(math.MaxInt64), -1},
+		{"10", "9a", -1}, // numbers before other values
+		{"9a", "a", -1},
+		{"01", "1", -1}, // equal numbers fall back to the strings
+		{"n1", "n1", 0},
+	}
+	for _, tt := range tests {
+		if got := compareKeys(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareKeys(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := compareKeys(tt.b, tt.a); got != -tt.want {
+			t.Errorf("compareKeys(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+// TestSortListings runs every -sort value on -group-by and on -top-detail
+// under -t -month and -t -week. Parents 1 and 20 tie at three events and 2, 9
+// and 30 at two, so the top three are 1, 20 and 2: ties are broken by key
+// ascending, numerically, both when rows are chosen and when they are shown.
+// The -t rows themselves tie at one event and stay in key order.
+func TestSortListings(t *testing.T) {
+	dir := t.TempDir()
+	var records []string
+	for _, p := range []struct{ parent, n int }{{30, 2}, {20, 3}, {9, 2}, {1, 3}, {2, 2}, {5, 1}} {
+		for i := 0; i < p.n; i++ {
+			records = append(records, event(p.parent, fmt.Sprintf("2025-03-%02dT10:00:00Z", 3+i), fmt.Sprintf("L%d", p.parent)))
+		}
+	}
+	records = append(records, event(40, "2025-03-18T10:00:00Z", "x"), event(50, "2025-04-01T10:00:00Z", "y"),
+		event(60, "2025-05-01T10:00:00Z", "z"))
+	writeFile(t, dir, "in.json", eventArray(records...))
+
+	orders := map[string][]string{
+		"":       {"1", "20", "2"},
+		"-count": {"1", "20", "2"},
+		"count":  {"2", "1", "20"},
+		"key":    {"1", "2", "20"},
+		"-key":   {"20", "2", "1"},
+	}
+	listings := []struct {
+		name  string
+		args  []string
+		key   string
+		other [][]string // the rows of a -t listing, whatever -sort says
+	}{
+		{"group-by parent", []string{"-y", "2025", "-group-by", "parent", "-group-top", "3"}, "group_by", nil},
+		{"group-by leader", []string{"-y", "2025", "-group-by", "leader", "-group-top", "3"}, "group_by", nil},
+		{"top-detail parent months", []string{"-y", "2025", "-t", "-month", "-top-detail", "parent"}, "top_months",
+			[][]string{{"2025-03", "14"}, {"2025-04", "1"}, {"2025-05", "1"}}},
+		{"top-detail leader weeks", []string{"-y", "2025", "-t", "-week", "-top-detail", "leader"}, "top_weeks",
+			[][]string{{"2025-W10", "13"}, {"2025-W12", "1"}, {"2025-W14", "1"}, {"2025-W18", "1"}}},
+	}
+	for _, l := range listings {
+		for order, want := range orders {
+			t.Run(l.name+" "+order, func(t *testing.T) {
+				if strings.Contains(l.name, "leader") {
+					want = prefixed("L", want)
+				}
+				args := append([]string{"-f", "in.json", "-output", "json"}, l.args...)
+				if order != "" {
+					args = append(args, "-sort", order)
+				}
+				out, errOut, status := runTool(t, dir, args...)
+				if status != 0 {
+					t.Fatalf("exit %d\n%s", status, errOut)
+				}
+				var doc struct {
+					Sections []struct {
+						Key     string
+						Rows    [][]string
+						SubRows [][][]string `json:"sub_rows"`
+					}
+				}
+				if err := json.Unmarshal([]byte(out), &doc); err != nil {
+					t.Fatal(err)
+				}
+				for _, s := range doc.Sections {
+					if s.Key != l.key {
+						continue
+					}
+					rows := s.Rows
+					if l.other != nil {
+						if !reflect.DeepEqual(rows, l.other) {
+							t.Errorf("rows %q, want %q", rows, l.other)
+						}
+						rows = s.SubRows[0]
+					}
+					var got []string
+					for _, r := range rows {
+						got = append(got, r[0])
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("order %q, want %q", got, want)
+					}
+					return
+				}
+				t.Fatalf("no %s section:\n%s", l.key, out)
+			})
+		}
+	}
+}
+
+func prefixed(prefix string, keys []string) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = prefix + k
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ── -input-format http ────────────────────────────────────────────────────────
+//
+// With -input-format http each -f value is a URL fetched with GET and decoded
+// as the body streams in, like standard input. A transport error, a timeout
+// waiting for the response or a 5xx response is retried with exponential
+// backoff, from 2s doubling up to -http-backoff-max; a 4xx response is fatal
+// at once. Once the body has started decoding its events are counted, so a
+// connection lost mid-body is not retried. Both failures exit with
+// E_NETWORK, apart from the data errors of E_DECODE.
+
+// inputFormats are the -input-format values.
+var inputFormats = []string{"file", "http"}
+
+// httpHeaderTimeout bounds the wait for a response's headers; the body may
+// take as long as it streams.
+const httpHeaderTimeout = 30 * time.Second
+
+// httpInput fetches -f URLs.
+type httpInput struct {
+	retries    int
+	backoff    time.Duration // first retry delay, doubled on each retry
+	backoffMax time.Duration
+	client     *http.Client
+}
+
+func newHTTPInput(retries int, backoffMax time.Duration) *httpInput {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ResponseHeaderTimeout = httpHeaderTimeout
+	return &httpInput{
+		retries:    retries,
+		backoff:    2 * time.Second,
+		backoffMax: backoffMax,
+		client:     &http.Client{Transport: t},
+	}
+}
+
+// resolveURLs checks that every -f value is an http or https URL and, unless
+// allowDup is set, keeps each URL once.
+func resolveURLs(specs []string, allowDup bool) (urls []string, dups []duplicateInput, err error) {
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		u, err := url.Parse(spec)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, nil, withCode(codeUsage, fmt.Errorf("-input-format http: %q is not an http or https URL", spec))
+		}
+		if !allowDup && seen[spec] {
+			dups = append(dups, duplicateInput{spec, spec})
+			continue
+		}
+		seen[spec] = true
+		urls = append(urls, spec)
+	}
+	return urls, dups, nil
+}
+
+// open fetches rawURL, retrying transient failures, and returns its body.
+func (h *httpInput) open(rawURL string) (*httpBody, error) {
+	for attempt := 0; ; attempt++ {
+		body, retry, err := h.get(rawURL)
+		if err == nil {
+			return &httpBody{ReadCloser: body}, nil
+		}
+		if !retry {
+			return nil, withCode(codeInputOpen, fmt.Errorf("%s: %v", rawURL, err))
+		}
+		if attempt == h.retries {
+			return nil, withCode(codeNetwork, fmt.Errorf("%s: %v (gave up after %d retries)", rawURL, err, h.retries))
+		}
+		wait := h.delay(attempt)
+		fmt.Fprintf(noteOut, "note: -input-format http: %s: %v; retrying in %s\n", rawURL, err, wait)
+		time.Sleep(wait)
+	}
+}
+
+// delay is the wait before retry attempt+1: the first delay doubled attempt
+// times, capped at backoffMax.
+func (h *httpInput) delay(attempt int) time.Duration {
+	d := h.backoff
+	for i := 0; i < attempt && d < h.backoffMax; i++ {
+		d *= 2
+	}
+	return min(d, h.backoffMax)
+}
+
+// get makes one request; retry reports whether a failure may be transient.
+func (h *httpInput) get(rawURL string) (body io.ReadCloser, retry bool, err error) {
+	resp, err := h.client.Get(rawURL)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode < 300 {
+		return resp.Body, false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return nil, true, fmt.Errorf("server error %s", resp.Status)
+	}
+	return nil, false, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// httpBody is a response body that keeps its first read error, so a
+// connection lost mid-body can be told apart from malformed JSON.
+type httpBody struct {
+	io.ReadCloser
+	err error
+}
+
+func (b *httpBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPDelay(t *testing.T) {
+	h := &httpInput{backoff: 2 * time.Second, backoffMax: 32 * time.Second}
+	want := []time.Duration{2, 4, 8, 16, 32, 32, 32}
+	for attempt, w := range want {
+		if got := h.delay(attempt); got != w*time.Second {
+			t.Errorf("delay(%d) = %s, want %s", attempt, got, w*time.Second)
+		}
+	}
+	h.backoffMax = 5 * time.Second
+	if got := h.delay(2); got != 5*time.Second {
+		t.Errorf("delay(2) under a 5s cap = %s", got)
+	}
+}
+
+func TestResolveURLs(t *testing.T) {
+	urls, dups, err := resolveURLs([]string{"http://h/a.json", "https://h/b.json", "http://h/a.json"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(urls, " ") != "http://h/a.json https://h/b.json" || len(dups) != 1 {
+		t.Errorf("urls %q, dups %+v", urls, dups)
+	}
+	for _, bad := range []string{"a.json", "-", "ftp://h/a.json", "http:///a.json"} {
+		if _, _, err := resolveURLs([]string{bad}, false); err == nil || codeOf(err, "") != codeUsage {
+			t.Errorf("%q: error %v, want %s", bad, err, codeUsage)
+		}
+	}
+}
+
+// TestHTTPHeaderTimeout retries a request whose response headers do not come
+// in time.
+func TestHTTPHeaderTimeout(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.ResponseHeaderTimeout = 50 * time.Millisecond
+	h := &httpInput{retries: 1, backoff: time.Millisecond, backoffMax: time.Millisecond, client: &http.Client{Transport: tr}}
+	body, err := h.open(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+// TestHTTPInput runs the command against a server that answers the first
+// fails requests with status, then serves body.
+func TestHTTPInput(t *testing.T) {
+	events := eventArray(event(1, "2025-03-14T01:00:00Z", "n"), event(10, "2025-03-15T01:00:00Z", "n"))
+	tests := []struct {
+		name     string
+		status   int
+		fails    int
+		body     string
+		args     []string
+		exit     int
+		requests int32
+		stderr   string
+	}{
+		{"ok", 0, 0, events, nil, 0, 1, ""},
+		{"recovers", http.StatusServiceUnavailable, 2, events, nil, 0, 3, "503 Service Unavailable; retrying in 10ms"},
+		{"gives up", http.StatusBadGateway, 100, events, []string{"-http-retries", "2"}, 75, 3,
+			"error [E_NETWORK]: http://"},
+		{"not found", http.StatusNotFound, 100, events, nil, 66, 1, "404 Not Found"},
+		{"truncated", 0, 0, "", nil, 75, 1, "connection lost while reading"},
+		{"malformed", 0, 0, "[{", nil, 65, 1, "error [E_DECODE]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.fails {
+					http.Error(w, "try later", tt.status)
+					return
+				}
+				if tt.name == "truncated" {
+					// promise more than is sent; the server then drops the connection
+					w.Header().Set("Content-Length", "1000")
+					w.Write([]byte(events[:40]))
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			args := append([]string{"-f", srv.URL + "/splits.json", "-input-format", "http", "-http-backoff-max", "10ms", "-a"}, tt.args...)
+			out, errOut, status := runTool(t, t.TempDir(), args...)
+			if status != tt.exit {
+				t.Errorf("exit %d, want %d\n%s", status, tt.exit, errOut)
+			}
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+			if !strings.Contains(errOut, tt.stderr) {
+				t.Errorf("stderr lacks %q:\n%s", tt.stderr, errOut)
+			}
+			if tt.exit == 0 && !strings.Contains(out, "2025: 2 splits") {
+				t.Errorf("events not counted:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestHTTPInputUsage(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"-f", "splits.json", "-input-format", "http"},
+		{"-f", "http://127.0.0.1:1/a.json", "-input-format", "http", "-resume", "j"},
+		{"-f", "http://127.0.0.1:1/a.json", "-input-format", "http", "-http-backoff-max", "0s"},
+		{"-f", "splits.json", "-input-format", "ftp"},
+	} {
+		_, errOut, status := runTool(t, dir, args...)
+		if status != codeUsage.exitStatus() {
+			t.Errorf("%q: exit %d, want %d\n%s", args, status, codeUsage.exitStatus(), errOut)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ── Inputs ────────────────────────────────────────────────────────────────────
+
+// inputList collects the repeated -f flag. Each value is a file, a directory
+// (its *.json, *.jsonl and *.ndjson files, not recursive), a glob pattern or
+// "-" for standard input.
+type inputList []string
+
+func (l *inputList) String() string { return strings.Join(*l, ",") }
+
+func (l *inputList) Set(v string) error {
+	if v == "" {
+		return fmt.Errorf("empty path")
+	}
+	*l = append(*l, v)
+	return nil
+}
+
+var inputExtensions = []string{".json", ".jsonl", ".ndjson"}
+
+// stdinInput is the -f value that reads standard input. It is read once, as
+// it arrives, so it cannot be pre-scanned or journaled.
+const stdinInput = "-"
+
+// stdinPiped reports whether standard input is a pipe or file rather than a
+// terminal, in which case a run without -f reads it.
+func stdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
+}
+
+// openInput opens a resolved input path.
+func openInput(path string) (*os.File, error) {
+	if path == stdinInput {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+// inputName is the name of an input in messages and record provenance.
+func inputName(path string) string {
+	if path == stdinInput {
+		return "<stdin>"
+	}
+	return path
+}
+
+// duplicateInput records a resolved path that named a file already read.
+type duplicateInput struct {
+	Path   string
+	SameAs string
+}
+
+// resolveInputs expands the -f values into the ordered list of files to
+// read. Unless allowDup is set, each physical file is kept once: paths are
+// compared by absolute path and, where the platform supports it, by device
+// and inode so symlinks and hard links collapse too.
+func resolveInputs(specs []string, allowDup bool) (files []string, dups []duplicateInput, err error) {
+	seen := make(map[string]string) // identity -> first path
+	add := func(path string) {
+		if !allowDup {
+			id := inputIdentity(path)
+			if first, ok := seen[id]; ok {
+				dups = append(dups, duplicateInput{path, first})
+				return
+			}
+			seen[id] = path
+		}
+		files = append(files, path)
+	}
+	stdin := false
+	for _, spec := range specs {
+		if spec == stdinInput {
+			if stdin {
+				return nil, nil, fmt.Errorf("-f - given more than once; standard input can only be read once")
+			}
+			stdin = true
+			files = append(files, spec)
+			continue
+		}
+		spec = inputPattern(spec)
+		if strings.ContainsAny(spec, "*?[") {
+			matches, err := filepath.Glob(spec)
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad pattern %s: %v", spec, err)
+			}
+			if len(matches) == 0 {
+				return nil, nil, withCode(codeEmpty, fmt.Errorf("no files match %s", spec))
+			}
+			for _, m := range matches {
+				add(m)
+			}
+			continue
+		}
+		fi, err := os.Stat(spec)
+		if err != nil || !fi.IsDir() {
+			// a missing file is reported when it is opened
+			add(spec)
+			continue
+		}
+		entries, err := os.ReadDir(spec)
+		if err != nil {
+			return nil, nil, withCode(codeInputOpen, fmt.Errorf("reading directory %s: %v", spec, err))
+		}
+		var names []string
+		for _, e := range entries {
+			if !e.IsDir() && hasInputExtension(e.Name()) {
+				names = append(names, e.Name())
+			}
+		}
+		if len(names) == 0 {
+			return nil, nil, withCode(codeEmpty, fmt.Errorf("no input files in directory %s", spec))
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			add(filepath.Join(spec, n))
+		}
+	}
+	return files, dups, nil
+}
+
+func hasInputExtension(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range inputExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// inputIdentity returns the key used to detect duplicate inputs: the file's
+// device and inode when available, else its cleaned absolute path.
+func inputIdentity(path string) string {
+	if id, ok := fileID(path); ok {
+		return id
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package main
+
+import "path/filepath"
+
+// fileID is unavailable here; duplicates are detected by absolute path only.
+func fileID(string) (string, bool) {
+	return "", false
+}
+
+// inputPattern converts / in -f values to the native separator.
+func inputPattern(spec string) string {
+	return filepath.FromSlash(spec)
+}
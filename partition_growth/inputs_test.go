@@ -0,0 +1,236 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// inputTree creates dir/a.json, dir/b.jsonl, dir/c.ndjson, dir/notes.txt and
+// dir/sub/d.json and returns dir.
+func inputTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.jsonl", "c.ndjson", "notes.txt"} {
+		writeFile(t, dir, name, "[]")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, filepath.Join("sub", "d.json"), "[]")
+	return dir
+}
+
+func TestResolveInputs(t *testing.T) {
+	dir := inputTree(t)
+	p := func(name string) string { return filepath.Join(dir, name) }
+	tests := []struct {
+		name     string
+		specs    []string
+		allowDup bool
+		files    []string
+		dups     []duplicateInput
+	}{
+		{
+			name:  "directory reads input extensions in name order, not recursive",
+			specs: []string{dir},
+			files: []string{p("a.json"), p("b.jsonl"), p("c.ndjson")},
+		},
+		{
+			name:  "glob",
+			specs: []string{p("*.json*")},
+			files: []string{p("a.json"), p("b.jsonl")},
+		},
+		{
+			name:  "same file through two globs",
+			specs: []string{p("a*"), p("*.json")},
+			files: []string{p("a.json")},
+			dups:  []duplicateInput{{p("a.json"), p("a.json")}},
+		},
+		{
+			name:  "file and its directory",
+			specs: []string{p("b.jsonl"), dir},
+			files: []string{p("b.jsonl"), p("a.json"), p("c.ndjson")},
+			dups:  []duplicateInput{{p("b.jsonl"), p("b.jsonl")}},
+		},
+		{
+			name:  "unclean path to the same file",
+			specs: []string{p("a.json"), filepath.Join(dir, "sub", "..", "a.json")},
+			files: []string{p("a.json")},
+			dups:  []duplicateInput{{filepath.Join(dir, "sub", "..", "a.json"), p("a.json")}},
+		},
+		{
+			name:     "-allow-duplicates reads every occurrence",
+			specs:    []string{p("a.json"), p("a*")},
+			allowDup: true,
+			files:    []string{p("a.json"), p("a.json")},
+		},
+		{
+			name:  "missing file is kept for the open error",
+			specs: []string{p("missing.json")},
+			files: []string{p("missing.json")},
+		},
+		{
+			name:  "standard input",
+			specs: []string{"-", p("a.json")},
+			files: []string{"-", p("a.json")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, dups, err := resolveInputs(tt.specs, tt.allowDup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("files = %q, want %q", files, tt.files)
+			}
+			if !reflect.DeepEqual(dups, tt.dups) {
+				t.Errorf("dups = %+v, want %+v", dups, tt.dups)
+			}
+		})
+	}
+}
+
+func TestResolveInputsErrors(t *testing.T) {
+	dir := inputTree(t)
+	empty := t.TempDir()
+	tests := []struct {
+		name  string
+		specs []string
+		code  errCode
+		msg   string
+	}{
+		{"no glob match", []string{filepath.Join(dir, "*.csv")}, codeEmpty, "no files match"},
+		{"directory without inputs", []string{empty}, codeEmpty, "no input files in directory"},
+		{"bad pattern", []string{filepath.Join(dir, "[")}, codeUsage, "bad pattern"},
+		{"standard input twice", []string{"-", "-"}, codeUsage, "more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := resolveInputs(tt.specs, false)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Fatalf("error = %v, want one mentioning %q", err, tt.msg)
+			}
+			if code := codeOf(err, codeUsage); code != tt.code {
+				t.Errorf("code = %s, want %s", code, tt.code)
+			}
+		})
+	}
+}
+
+// TestResolveInputsLinks collapses a symlink and a hard link to a file
+// already named, where the platform can create them.
+func TestResolveInputsLinks(t *testing.T) {
+	dir := inputTree(t)
+	a := filepath.Join(dir, "a.json")
+	links := t.TempDir()
+	sym, hard := filepath.Join(links, "sym.json"), filepath.Join(links, "hard.json")
+	if err := os.Symlink(a, sym); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	if err := os.Link(a, hard); err != nil {
+		t.Skipf("cannot create hard links: %v", err)
+	}
+	if _, ok := fileID(a); !ok {
+		t.Skip("no file identity on this platform; links are not detected")
+	}
+	files, dups, err := resolveInputs([]string{a, sym, links}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+	want := []duplicateInput{{sym, a}, {hard, a}, {sym, a}}
+	if !reflect.DeepEqual(dups, want) {
+		t.Errorf("dups = %+v, want %+v", dups, want)
+	}
+}
+
+// TestStdinMatchesFile pipes fixtures through -f - and standard input left
+// implicit, and checks the reports match those of the same files named with
+// -f; -f - given twice is rejected.
+func TestStdinMatchesFile(t *testing.T) {
+	dir := t.TempDir()
+	array := eventArray(event(1, "2025-03-01T10:00:00Z", "a"), event(10, "2025-03-02T00:00:00Z", "b"),
+		event(20, "garbage", "b"), event(30, "2024-12-31T23:00:00Z", "a"), event(40, "2025-03-02T00:00:00Z", "a"))
+	stream := event(50, "2025-04-01T10:00:00Z", "c") + "\n" + event(60, "2025-04-02T10:00:00Z", "c") + "\n"
+	writeFile(t, dir, "a.json", array)
+	writeFile(t, dir, "b.jsonl", stream)
+	runs := [][]string{
+		{"-a"},
+		{"-y", "2025", "-m", "3", "-d", "2"},
+		{"-y", "2025", "-t", "-month", "-top-detail", "leader"},
+		{"-a-filtered", "-y", "2025", "-group-by", "leader", "-output", "json"},
+	}
+	for _, args := range runs {
+		for _, in := range []struct{ file, content string }{{"a.json", array}, {"b.jsonl", stream}} {
+			t.Run(in.file+" "+strings.Join(args, " "), func(t *testing.T) {
+				want, _, wantStatus := runTool(t, dir, append([]string{"-f", in.file}, args...)...)
+				for _, stdinArgs := range [][]string{{"-f", "-"}, nil} {
+					out, errOut, status := runToolStdin(t, dir, strings.NewReader(in.content), append(stdinArgs, args...)...)
+					if status != wantStatus || out != want {
+						t.Errorf("%q: exit %d, want %d; output differs from -f %s:\n%s\nwant:\n%s\n%s",
+							stdinArgs, status, wantStatus, in.file, out, want, errOut)
+					}
+				}
+			})
+		}
+	}
+	want, _, _ := runTool(t, dir, "-f", "a.json", "-f", "b.jsonl", "-a")
+	out, errOut, status := runToolStdin(t, dir, strings.NewReader(array), "-f", "-", "-f", "b.jsonl", "-a")
+	if status != 0 || out != want {
+		t.Errorf("-f - -f b.jsonl: exit %d, output differs:\n%s\nwant:\n%s\n%s", status, out, want, errOut)
+	}
+	_, errOut, status = runToolStdin(t, dir, strings.NewReader(array), "-f", "-", "-f", "-", "-a")
+	if status != codeUsage.exitStatus() || !strings.Contains(errOut, "error [E_USAGE]") {
+		t.Errorf("-f - twice: exit %d, want %d\n%s", status, codeUsage.exitStatus(), errOut)
+	}
+}
+
+// TestDuplicateInputsCountedOnce runs the command on a file named twice and
+// checks the total and the note.
+func TestDuplicateInputsCountedOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.json", eventArray(event(1, "2025-01-01T00:00:00Z", "n"), event(5, "2025-01-02T00:00:00Z", "n")))
+	out, errOut, status := runTool(t, dir, "-f", "a.json", "-f", "*.json", "-a")
+	if status != 0 {
+		t.Fatalf("exit %d\n%s", status, errOut)
+	}
+	if !strings.Contains(out, "2025: 2 splits") {
+		t.Errorf("duplicate counted twice:\n%s", out)
+	}
+	if !strings.Contains(errOut, "note: skipping a.json, same file as a.json") {
+		t.Errorf("no duplicate note on stderr:\n%s", errOut)
+	}
+	out, _, _ = runTool(t, dir, "-f", "a.json", "-f", "*.json", "-a", "-allow-duplicates")
+	if !strings.Contains(out, "2025: 4 splits") {
+		t.Errorf("-allow-duplicates did not read the file twice:\n%s", out)
+	}
+}
+
+// TestConcatenatedArrays runs the command on three concatenated arrays
+// behind a byte order mark, as the rotator writes when merging hourly files:
+// every array is counted and -progress reports the three documents.
+func TestConcatenatedArrays(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "merged.json", "\xef\xbb\xbf\n"+
+		eventArray(event(1, "2025-01-01T00:00:00Z", "n"), event(4, "2025-01-01T01:00:00Z", "n"))+
+		eventArray(event(7, "2025-01-02T00:00:00Z", "n"))+
+		eventArray(event(10, "2025-02-01T00:00:00Z", "n"), event(13, "2025-02-02T00:00:00Z", "n"), event(16, "2025-02-03T00:00:00Z", "n")))
+	out, errOut, status := runTool(t, dir, "-f", "merged.json", "-a", "-progress")
+	if status != 0 {
+		t.Fatalf("exit %d\n%s", status, errOut)
+	}
+	for _, want := range []string{"2025: 6 splits", "2025-01: 3 splits", "2025-02: 3 splits"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(errOut, "done, 6 records") || !strings.Contains(errOut, "3 top-level documents") {
+		t.Errorf("progress does not report 6 records in 3 documents:\n%s", errOut)
+	}
+}
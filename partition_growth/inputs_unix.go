@@ -0,0 +1,27 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file by device and inode, following symlinks.
+func fileID(path string) (string, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("dev:%d:ino:%d", st.Dev, st.Ino), true
+}
+
+// inputPattern returns spec unchanged: \ escapes glob metacharacters here.
+func inputPattern(spec string) string {
+	return spec
+}
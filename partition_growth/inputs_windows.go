@@ -0,0 +1,36 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// fileID identifies a file by volume serial number and file index, which
+// hard links share, following symlinks.
+func fileID(path string) (string, bool) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return "", false
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS lets the handle open directories too.
+	h, err := syscall.CreateFile(p, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return "", false
+	}
+	defer syscall.CloseHandle(h)
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &info); err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("vol:%d:idx:%d", info.VolumeSerialNumber, uint64(info.FileIndexHigh)<<32|uint64(info.FileIndexLow)), true
+}
+
+// inputPattern lets -f values use / as well as \; there is no shell to
+// expand globs, and matches should come back with native separators.
+func inputPattern(spec string) string {
+	return filepath.FromSlash(spec)
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInputPatternSeparators(t *testing.T) {
+	dir := inputTree(t)
+	slashed := filepath.ToSlash(dir)
+	want := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.jsonl")}
+	for _, spec := range []string{
+		filepath.Join(dir, "*.json*"),
+		slashed + "/*.json*",
+		slashed + `\*.json*`,
+	} {
+		files, _, err := resolveInputs([]string{spec}, false)
+		if err != nil {
+			t.Fatalf("%s: %v", spec, err)
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("%s: files = %q, want %q with native separators", spec, files, want)
+		}
+	}
+}
+
+// TestFileIDCase collapses paths differing only in case, which name one
+// file on Windows.
+func TestFileIDCase(t *testing.T) {
+	dir := inputTree(t)
+	a := filepath.Join(dir, "a.json")
+	upper := filepath.Join(dir, "A.JSON")
+	files, dups, err := resolveInputs([]string{a, upper}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(files, []string{a}) || len(dups) != 1 {
+		t.Errorf("files = %q, dups = %+v; want a.json once and one duplicate", files, dups)
+	}
+}
+
+// TestCRLFInput runs the command on CRLF-terminated JSON lines, as Windows
+// tools write them.
+func TestCRLFInput(t *testing.T) {
+	dir := t.TempDir()
+	lines := []string{event(1, "2025-01-01T00:00:00Z", "n"), event(5, "2025-01-02T00:00:00Z", "n")}
+	writeFile(t, dir, "in.jsonl", strings.Join(lines, "\r\n")+"\r\n")
+	out, errOut, status := runTool(t, dir, "-f", "in.jsonl", "-a")
+	if status != 0 || errOut != "" {
+		t.Fatalf("exit %d\n%s", status, errOut)
+	}
+	if !strings.Contains(out, "2025: 2 splits") {
+		t.Errorf("CRLF lines not all counted:\n%s", out)
+	}
+}
+
+// TestReplaceLatestOverExisting replaces "latest" twice: os.Rename does not
+// replace a directory on Windows, so the copy must go through removal.
+func TestReplaceLatestOverExisting(t *testing.T) {
+	dir := t.TempDir()
+	for _, run := range []string{"run1", "run2"} {
+		if err := os.Mkdir(filepath.Join(dir, run), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, run), "report.txt", run)
+		if err := replaceLatest(dir, run); err != nil {
+			t.Fatalf("replacing latest with %s: %v", run, err)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, reportDirLatest, "report.txt"))
+		if err != nil || string(b) != run {
+			t.Errorf("latest holds %q, %v; want %q", b, err, run)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, reportDirNew)); !os.IsNotExist(err) {
+		t.Errorf("%s left behind: %v", reportDirNew, err)
+	}
+}
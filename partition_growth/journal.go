@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"partition_growth/events"
+)
+
+// ── -resume ───────────────────────────────────────────────────────────────────
+//
+// The resume journal lets a long multi-file run continue after a crash. After
+// each input file is read, one entry is appended and fsync'd: the file's path
+// and SHA-256 and the decoded records of that file in compact form (the
+// ordered replay encoding plus the date as written, value, size, year
+// inference, weight and type; records that -type excluded keep only their
+// type, for its tally, and records whose date was missing or unparseable keep
+// their ParentID and date, to be counted and reported again). On restart the
+// completed files are not decoded again; their records are fed straight to
+// the aggregation, which therefore ends in the same state as a run from
+// scratch. Display filters (-y, -m, -report, ...) may differ between runs
+// because records are journaled before filtering.
+//
+// Format: a sequence of frames, each a 4-byte big-endian payload length, the
+// payload and a CRC-32 of the payload. The first frame is the header: magic,
+// format version and a fingerprint of the input set and parse settings. A
+// short or corrupt frame ends the journal; it is truncated there, which is
+// the expected state after a crash mid-write. A header that does not match
+// the current version or fingerprint, or a completed file whose hash has
+// changed, starts the journal afresh. The journal is removed when the run
+// completes.
+
+const (
+	journalMagic   = "partition_growth journal"
+	journalVersion = 7
+)
+
+// journalRecord is one decoded event as stored in the journal.
+type journalRecord struct {
+	orderedRecord
+	Value         int
+	Size          int64
+	How           events.Inference
+	Weight        int
+	HasWeight     bool
+	Type          string // -type-field label
+	Skipped       bool   // excluded by -type; only Type is set
+	Rejected      bool   // date missing or unparseable; only Parent and Date are set
+	Date          string // Event.Date as written
+	Midnight      bool   // written at exactly 00:00:00
+	MidnightMoved bool   // and put on another day by -midnight-policy keep
+	Overflow      uint8  // Event.Overflow
+	Offset        int32  // UTC offset written in the date, seconds east
+	Zoned         bool   // the date had an offset
+}
+
+// journalEntry is one completed input file.
+type journalEntry struct {
+	Path    string
+	Hash    [sha256.Size]byte
+	Records []journalRecord
+}
+
+type journal struct {
+	f *os.File
+}
+
+// inputFingerprint identifies the input list and the settings that change how
+// records are decoded. File contents are checked per entry by hash, so a bad
+// file that stopped the run can be fixed without losing the journal.
+func inputFingerprint(files []string, settings string) string {
+	return settings + "\n" + strings.Join(files, "\n")
+}
+
+// openJournal opens or creates the journal at path and returns the completed
+// entries recorded under the same fingerprint, keyed by path.
+func openJournal(path, fingerprint string) (*journal, map[string]journalEntry, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	j := &journal{f: f}
+	done := make(map[string]journalEntry)
+	br := bufio.NewReader(f)
+	var good int64
+	header, n, err := readFrame(br)
+	if err == nil && bytes.Equal(header, journalHeader(fingerprint)) {
+		good = n
+		for {
+			payload, n, err := readFrame(br)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					logf("resume: ignoring damaged journal tail after %d bytes: %v", good, err)
+				}
+				break
+			}
+			e, err := decodeJournalEntry(payload)
+			if err != nil {
+				logf("resume: ignoring damaged journal tail after %d bytes: %v", good, err)
+				break
+			}
+			done[e.Path] = e
+			good += n
+		}
+	} else if err == nil || !errors.Is(err, io.EOF) {
+		fmt.Fprintf(noteOut, "note: -resume: journal %s is from another input set or version; starting fresh\n", path)
+	}
+	for p, e := range done {
+		if h, err := hashFile(p); err != nil || h != e.Hash {
+			fmt.Fprintf(noteOut, "note: -resume: %s changed since it was journaled; starting fresh\n", p)
+			done, good = make(map[string]journalEntry), 0
+			break
+		}
+	}
+	if err := j.reset(good, fingerprint); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return j, done, nil
+}
+
+// reset truncates the journal to its valid prefix, writing a new header when
+// nothing valid remains.
+func (j *journal) reset(good int64, fingerprint string) error {
+	if err := j.f.Truncate(good); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(good, io.SeekStart); err != nil {
+		return err
+	}
+	if good == 0 {
+		return j.writeFrame(journalHeader(fingerprint))
+	}
+	return nil
+}
+
+// append durably records a completed file.
+func (j *journal) append(e journalEntry) error {
+	return j.writeFrame(encodeJournalEntry(e))
+}
+
+// remove deletes the journal once the run has completed.
+func (j *journal) remove() error {
+	name := j.f.Name()
+	j.f.Close()
+	return os.Remove(name)
+}
+
+func (j *journal) writeFrame(payload []byte) error {
+	frame := make([]byte, 4, 8+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(payload))
+	if _, err := j.f.Write(frame); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// readFrame returns the next payload and the frame's size on disk.
+func readFrame(br *bufio.Reader) ([]byte, int64, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, errors.New("truncated frame")
+		}
+		return nil, 0, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	payload := make([]byte, int(n)+4)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return nil, 0, errors.New("truncated frame")
+	}
+	sum := binary.BigEndian.Uint32(payload[n:])
+	payload = payload[:n]
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	return payload, int64(n) + 8, nil
+}
+
+func journalHeader(fingerprint string) []byte {
+	b := []byte(journalMagic)
+	b = binary.AppendUvarint(b, journalVersion)
+	return append(b, fingerprint...)
+}
+
+func encodeJournalEntry(e journalEntry) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(e.Path)))
+	b = append(b, e.Path...)
+	b = append(b, e.Hash[:]...)
+	b = binary.AppendUvarint(b, uint64(len(e.Records)))
+	for _, r := range e.Records {
+		b = encodeRecord(b, r.orderedRecord)
+		b = binary.AppendVarint(b, int64(r.Value))
+		b = binary.AppendVarint(b, r.Size)
+		b = binary.AppendUvarint(b, uint64(r.How))
+		b = binary.AppendVarint(b, int64(r.Weight))
+		b = binary.AppendUvarint(b, boolBit(r.HasWeight)|boolBit(r.Skipped)<<1|boolBit(r.Midnight)<<2|boolBit(r.Zoned)<<3|
+			boolBit(r.Rejected)<<4|uint64(r.Overflow)<<5|boolBit(r.MidnightMoved)<<13)
+		b = binary.AppendVarint(b, int64(r.Offset))
+		b = appendString(b, r.Type)
+		b = appendString(b, r.Date)
+	}
+	return b
+}
+
+func decodeJournalEntry(payload []byte) (journalEntry, error) {
+	var e journalEntry
+	br := bufio.NewReader(bytes.NewReader(payload))
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return e, err
+	}
+	path := make([]byte, l)
+	if _, err := io.ReadFull(br, path); err != nil {
+		return e, err
+	}
+	e.Path = string(path)
+	if _, err := io.ReadFull(br, e.Hash[:]); err != nil {
+		return e, err
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return e, err
+	}
+	e.Records = make([]journalRecord, 0, n)
+	for i := uint64(0); i < n; i++ {
+		rec, err := decodeRecord(br)
+		if err != nil {
+			return e, err
+		}
+		v, err := binary.ReadVarint(br)
+		if err != nil {
+			return e, err
+		}
+		size, err := binary.ReadVarint(br)
+		if err != nil {
+			return e, err
+		}
+		how, err := binary.ReadUvarint(br)
+		if err != nil {
+			return e, err
+		}
+		w, err := binary.ReadVarint(br)
+		if err != nil {
+			return e, err
+		}
+		bits, err := binary.ReadUvarint(br)
+		if err != nil {
+			return e, err
+		}
+		off, err := binary.ReadVarint(br)
+		if err != nil {
+			return e, err
+		}
+		typ, err := readString(br)
+		if err != nil {
+			return e, err
+		}
+		date, err := readString(br)
+		if err != nil {
+			return e, err
+		}
+		e.Records = append(e.Records, journalRecord{rec, int(v), size, events.Inference(how), int(w), bits&1 != 0,
+			typ, bits&2 != 0, bits&16 != 0, date, bits&4 != 0, bits&(1<<13) != 0, uint8(bits >> 5), int32(off), bits&8 != 0})
+	}
+	return e, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func readString(br *bufio.Reader) (string, error) {
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return "", err
+	}
+	s := make([]byte, l)
+	if _, err := io.ReadFull(br, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// event rebuilds the fields of the original event that aggregation reads.
+func (r journalRecord) event() (Event, time.Time) {
+	return Event{
+		ParentID:       r.Parent,
+		FirstChildID:   r.First,
+		SecondChildID:  r.Second,
+		LeaderNodeInfo: r.Leader,
+		Date:           r.Date,
+		Value:          r.Value,
+		Size:           r.Size,
+		Weight:         r.Weight,
+		HasWeight:      r.HasWeight,
+		Type:           r.Type,
+		Overflow:       r.Overflow,
+	}, r.time()
+}
+
+func boolBit(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"partition_growth/events"
+)
+
+func TestJournalEntryRoundTrip(t *testing.T) {
+	e := journalEntry{Path: "/data/a.json", Records: []journalRecord{
+		{orderedRecord: orderedRecord{T: 1700000000e9, Parent: 1, First: 2, Second: 3, Leader: "n1"},
+			Value: -4, Size: 1 << 40, How: events.InferTwoDigitYear, Weight: 3, HasWeight: true, Type: "split",
+			Date: "23-11-14 22:13:20", Midnight: true, Overflow: events.OverflowSecondChildID, Offset: -18000, Zoned: true},
+		{Type: "merge", Skipped: true},
+		{orderedRecord: orderedRecord{Parent: 9}, Rejected: true, Date: "garbage"},
+		{orderedRecord: orderedRecord{Parent: 10}, Rejected: true},
+	}}
+	e.Hash[0], e.Hash[31] = 1, 2
+	got, err := decodeJournalEntry(encodeJournalEntry(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, e)
+	}
+	for n := 0; n < len(encodeJournalEntry(e)); n++ {
+		if _, err := decodeJournalEntry(encodeJournalEntry(e)[:n]); err == nil {
+			t.Errorf("payload cut to %d bytes decoded without error", n)
+		}
+	}
+}
+
+// writeJournal journals entries for files a, b and c in dir and returns the
+// journal path, its fingerprint and the size after the header and after each
+// entry.
+func writeJournal(t *testing.T, dir string) (path, fp string, sizes []int64) {
+	t.Helper()
+	var files []string
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		files = append(files, writeFile(t, dir, name, "["+name+"]"))
+	}
+	path, fp = filepath.Join(dir, "journal"), inputFingerprint(files, "settings")
+	j, done, err := openJournal(path, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Fatalf("new journal has entries: %v", done)
+	}
+	size := func() int64 {
+		fi, err := j.f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+	sizes = append(sizes, size())
+	for i, f := range files {
+		h, err := hashFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := journalRecord{orderedRecord: orderedRecord{T: int64(i), Parent: i}, Date: "d"}
+		if err := j.append(journalEntry{Path: f, Hash: h, Records: []journalRecord{rec}}); err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, size())
+	}
+	j.f.Close()
+	return path, fp, sizes
+}
+
+// TestJournalTruncation cuts the journal at every byte, as a kill mid-write
+// may, and reopens it: every complete entry is kept, the torn tail is
+// removed, and new entries can be appended after it.
+func TestJournalTruncation(t *testing.T) {
+	dir := t.TempDir()
+	path, fp, sizes := writeJournal(t, dir)
+	full, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cut := 0; cut <= len(full); cut++ {
+		if err := os.WriteFile(path, full[:cut], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		j, done, err := openJournal(path, fp)
+		if err != nil {
+			t.Fatalf("cut at %d: %v", cut, err)
+		}
+		want, good := 0, sizes[0]
+		for i, s := range sizes[1:] {
+			if int64(cut) >= s {
+				want, good = i+1, s
+			}
+		}
+		if len(done) != want {
+			t.Errorf("cut at %d: %d entries, want %d", cut, len(done), want)
+		}
+		fi, _ := j.f.Stat()
+		if fi.Size() != good {
+			t.Errorf("cut at %d: journal truncated to %d bytes, want %d", cut, fi.Size(), good)
+		}
+		j.f.Close()
+	}
+}
+
+func TestJournalCorruption(t *testing.T) {
+	dir := t.TempDir()
+	path, fp, sizes := writeJournal(t, dir)
+	full, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte inside the second entry's payload: its CRC no longer
+	// matches, so only the first entry survives.
+	bad := bytes.Clone(full)
+	bad[sizes[1]+6] ^= 0xff
+	if err := os.WriteFile(path, bad, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	j, done, err := openJournal(path, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.f.Close()
+	if len(done) != 1 {
+		t.Errorf("%d entries after a corrupt second entry, want 1", len(done))
+	}
+	if _, ok := done[filepath.Join(dir, "a.json")]; !ok {
+		t.Errorf("first entry lost: %v", done)
+	}
+}
+
+func TestJournalStartsFresh(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, dir, path string) (fp string)
+	}{
+		{"other fingerprint", func(t *testing.T, dir, path string) string {
+			return "other settings"
+		}},
+		{"other version", func(t *testing.T, dir, path string) string {
+			b, _ := os.ReadFile(path)
+			br := bufio.NewReader(bytes.NewReader(b))
+			header, n, err := readFrame(br)
+			if err != nil {
+				t.Fatal(err)
+			}
+			old := []byte(journalMagic)
+			old = binary.AppendUvarint(old, journalVersion-1)
+			old = append(old, header[len(journalHeader("")):]...)
+			frame := binary.BigEndian.AppendUint32(nil, uint32(len(old)))
+			frame = append(frame, old...)
+			frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(old))
+			os.WriteFile(path, append(frame, b[n:]...), 0o644)
+			return string(header[len(journalHeader("")):])
+		}},
+		{"journaled file changed", func(t *testing.T, dir, path string) string {
+			b, _ := os.ReadFile(path)
+			header, _, _ := readFrame(bufio.NewReader(bytes.NewReader(b)))
+			writeFile(t, dir, "b.json", "[changed]")
+			return string(header[len(journalHeader("")):])
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, _, sizes := writeJournal(t, dir)
+			fp := tt.change(t, dir, path)
+			j, done, err := openJournal(path, fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.f.Close()
+			if len(done) != 0 {
+				t.Errorf("kept %d entries, want a fresh journal", len(done))
+			}
+			fi, _ := j.f.Stat()
+			if want := int64(len(journalHeader(fp))) + 8; fi.Size() != want {
+				t.Errorf("journal is %d bytes, want a lone header of %d (old one %d)", fi.Size(), want, sizes[0])
+			}
+		})
+	}
+}
+
+// TestResumeMatchesFreshRun stops a run at a truncated file, as a crash
+// after the earlier files' entries would, then resumes it with the file
+// repaired and compares the output with a run from scratch. Each stopping
+// point is tried, and the journal also gets a torn frame appended, as a
+// kill mid-write leaves it.
+func TestResumeMatchesFreshRun(t *testing.T) {
+	inputs := []string{
+		eventArray(
+			event(1, "2025-01-01T10:00:00Z", "n1"),
+			event(4, "2025-01-02T10:00:00Z", "n2"),
+			event(7, "garbage", "n2"),
+			`{"parentId":10,"firstChildId":11,"secondChildId":12,"leaderNodeInfo":"n2"}`,
+			event(13, "2025-01-02T23:00:00-05:00", "n3"),
+		),
+		eventArray(event(20, "2025-01-03T10:00:00Z", "n3"), event(20, "2025-01-03T11:00:00Z", "n1")),
+		eventArray(event(30, "Jan 4, 2025, 1:00:00 AM", "n1"), `{"parentId":33,"date":"","leaderNodeInfo":"n1"}`),
+	}
+	args := []string{"-f", "f0.json", "-f", "f1.json", "-f", "f2.json", "-a",
+		"-report=event-metadata-summary", "-output", "json"}
+	for stop := 1; stop < len(inputs); stop++ {
+		t.Run(fmt.Sprintf("stopped at file %d", stop), func(t *testing.T) {
+			dir := t.TempDir()
+			for i, in := range inputs {
+				if i == stop {
+					in = in[:len(in)-3]
+				}
+				writeFile(t, dir, fmt.Sprintf("f%d.json", i), in)
+			}
+			if _, _, status := runTool(t, dir, append(args, "-resume", "j")...); status != codeDecode.exitStatus() {
+				t.Fatalf("run on a truncated file: exit %d, want %d", status, codeDecode.exitStatus())
+			}
+			f, err := os.OpenFile(filepath.Join(dir, "j"), os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte{0, 0, 1, 0, 'x'})
+			f.Close()
+			writeFile(t, dir, fmt.Sprintf("f%d.json", stop), inputs[stop])
+
+			resumedOut, resumedErr, status := runTool(t, dir, append(args, "-resume", "j", "-v")...)
+			if status != 0 {
+				t.Fatalf("resumed run: exit %d\n%s", status, resumedErr)
+			}
+			for i := 0; i < stop; i++ {
+				if want := fmt.Sprintf("resume: f%d.json: ", i); !bytes.Contains([]byte(resumedErr), []byte(want)) {
+					t.Errorf("f%d.json was not read from the journal:\n%s", i, resumedErr)
+				}
+			}
+			freshOut, freshErr, _ := runTool(t, dir, args...)
+			if resumedOut != freshOut {
+				t.Errorf("resumed output differs from a fresh run\nresumed:\n%s\nfresh:\n%s", resumedOut, freshOut)
+			}
+			if got, want := dateErrors(resumedErr), dateErrors(freshErr); !reflect.DeepEqual(got, want) {
+				t.Errorf("resumed date errors %q, fresh %q", got, want)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "j")); !os.IsNotExist(err) {
+				t.Errorf("journal left after a completed run: %v", err)
+			}
+		})
+	}
+}
+
+// dateErrors returns the E_DATE_PARSE lines of stderr.
+func dateErrors(stderr string) []string {
+	var out []string
+	for _, l := range bytes.Split([]byte(stderr), []byte("\n")) {
+		if bytes.Contains(l, []byte(codeDateParse)) {
+			out = append(out, string(l))
+		}
+	}
+	return out
+}
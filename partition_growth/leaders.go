@@ -0,0 +1,988 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -leader-churn ─────────────────────────────────────────────────────────────
+
+// leaderChurnData is the JSON payload of the leader churn section.
+type leaderChurnData struct {
+	Months           []string `json:"months"`
+	Distinct         []int    `json:"distinct"`
+	New              []int    `json:"new"`
+	Returning        []int    `json:"returning"`
+	LatestMonth      string   `json:"latest_month,omitempty"`
+	LatestNewLeaders []string `json:"latest_new_leaders"`
+}
+
+// leaderDebuts walks every month of the dataset in order, keeping the set of
+// leaders seen so far, and returns the months with the leaders first seen in
+// each, sorted.
+func leaderDebuts(a *aggregates) (months []string, debuts map[string][]string) {
+	months = make([]string, 0, len(a.monthLeaders))
+	for m := range a.monthLeaders {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	debuts = make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, m := range months {
+		var fresh []string
+		for l := range a.monthLeaders[m] {
+			if !seen[l] {
+				fresh = append(fresh, l)
+			}
+		}
+		for _, l := range fresh {
+			seen[l] = true
+		}
+		sort.Strings(fresh)
+		debuts[m] = fresh
+	}
+	return months, debuts
+}
+
+// buildLeaderChurn reports the distinct, new and returning leaders of each
+// month of the selected year.
+func buildLeaderChurn(a *aggregates) []section {
+	s := section{
+		Key:     "leader_churn",
+		Title:   fmt.Sprintf("Leader Churn %d", a.opts.year),
+		Columns: []string{"Month", "Distinct", "New", "Returning"},
+	}
+	data := leaderChurnData{LatestNewLeaders: []string{}}
+
+	months, debuts := leaderDebuts(a)
+	yprefix := fmt.Sprintf("%04d-", a.opts.year)
+	var latestNew []string
+	for _, m := range months {
+		if !strings.HasPrefix(m, yprefix) {
+			continue
+		}
+		leaders, fresh := a.monthLeaders[m], debuts[m]
+		data.Months = append(data.Months, m)
+		data.Distinct = append(data.Distinct, len(leaders))
+		data.New = append(data.New, len(fresh))
+		data.Returning = append(data.Returning, len(leaders)-len(fresh))
+		s.Rows = append(s.Rows, []string{m, strconv.Itoa(len(leaders)), strconv.Itoa(len(fresh)), strconv.Itoa(len(leaders) - len(fresh))})
+		data.LatestMonth = m
+		latestNew = fresh
+	}
+
+	if data.LatestMonth == "" {
+		s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", a.opts.year))
+	} else {
+		data.LatestNewLeaders = append(data.LatestNewLeaders, latestNew...)
+		if len(latestNew) == 0 {
+			s.Details = append(s.Details, fmt.Sprintf("New leaders in %s: none", data.LatestMonth))
+		} else {
+			s.Details = append(s.Details, fmt.Sprintf("New leaders in %s: %s", data.LatestMonth, strings.Join(latestNew, ", ")))
+		}
+	}
+	s.Data = data
+	return []section{s}
+}
+
+// ── monthly-leader-new-arrivals ───────────────────────────────────────────────
+
+func prepareMonthlyLeaders(a *aggregates, _ string) error {
+	if a.opts.year == 0 {
+		return fmt.Errorf("requires -y")
+	}
+	a.enableMonthLeaders()
+	return nil
+}
+
+// buildLeaderNewArrivals lists, for each month of -y with events, the leaders
+// with no event in any earlier month of the dataset.
+func buildLeaderNewArrivals(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "monthly_leader_new_arrivals",
+		Title:   fmt.Sprintf("New Leaders by Month %d", a.opts.year),
+		Columns: []string{"Month", "New", "Leaders"},
+	}
+	months, debuts := leaderDebuts(a)
+	yprefix := fmt.Sprintf("%04d-", a.opts.year)
+	data := make(map[string][]string)
+	total := 0
+	for _, m := range months {
+		if !strings.HasPrefix(m, yprefix) {
+			continue
+		}
+		fresh := debuts[m]
+		list := strings.Join(fresh, ", ")
+		if len(fresh) == 0 {
+			list = "none"
+		}
+		s.Rows = append(s.Rows, []string{m, strconv.Itoa(len(fresh)), list})
+		data[m] = append([]string{}, fresh...)
+		total += len(fresh)
+	}
+	s.Data = data
+	if len(s.Rows) == 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", a.opts.year))
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("New leaders in %d: %d", a.opts.year, total))
+	}
+	return []section{s}
+}
+
+// ── monthly-leader-departures ─────────────────────────────────────────────────
+
+// buildLeaderDepartures lists, for each month of -y with events, the leaders
+// whose last event of the year falls in that month. Leaders still active in
+// the last month with events are listed there too, since the year gives no
+// later month to tell them apart.
+func buildLeaderDepartures(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "monthly_leader_departures",
+		Title:   fmt.Sprintf("Departing Leaders by Month %d", a.opts.year),
+		Columns: []string{"Month", "Departed", "Leaders"},
+	}
+	yprefix := fmt.Sprintf("%04d-", a.opts.year)
+	var months []string
+	for m := range a.monthLeaders {
+		if strings.HasPrefix(m, yprefix) {
+			months = append(months, m)
+		}
+	}
+	sort.Strings(months)
+	last := make(map[string]string) // leader -> its last month of the year
+	for _, m := range months {
+		for l := range a.monthLeaders[m] {
+			last[l] = m
+		}
+	}
+	departures := make(map[string][]string)
+	for l, m := range last {
+		departures[m] = append(departures[m], l)
+	}
+	data := make(map[string][]string)
+	total := 0
+	for _, m := range months {
+		gone := departures[m]
+		sort.Strings(gone)
+		list := strings.Join(gone, ", ")
+		if len(gone) == 0 {
+			list = "none"
+		}
+		s.Rows = append(s.Rows, []string{m, strconv.Itoa(len(gone)), list})
+		data[m] = append([]string{}, gone...)
+		total += len(gone)
+	}
+	s.Data = data
+	if len(months) == 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", a.opts.year))
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Leaders departed before %s: %d", months[len(months)-1], total-len(departures[months[len(months)-1]])))
+	}
+	return []section{s}
+}
+
+// ── leader-tenure ─────────────────────────────────────────────────────────────
+
+// leaderTenure is one leader's streaks of consecutive active days.
+type leaderTenure struct {
+	Leader       string
+	ActiveDays   int
+	Current      int // streak ending on the last day of the data; 0 if idle that day
+	Longest      int
+	LongestStart string
+	LongestEnd   string
+}
+
+func prepareLeaderTenure(a *aggregates, _ string) error {
+	a.enableLeaderDays()
+	return nil
+}
+
+// leaderStreaks computes the streaks of one leader from its active days.
+// lastDay is the last day with any filtered event.
+func leaderStreaks(leader string, days map[string]bool, lastDay string) leaderTenure {
+	keys := make([]string, 0, len(days))
+	for d := range days {
+		keys = append(keys, d)
+	}
+	sort.Strings(keys)
+	t := leaderTenure{Leader: leader, ActiveDays: len(keys)}
+	run, start := 0, ""
+	var prev time.Time
+	for _, k := range keys {
+		d, _ := time.Parse("2006-01-02", k)
+		if run > 0 && d.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run, start = 1, k
+		}
+		if run > t.Longest {
+			t.Longest, t.LongestStart, t.LongestEnd = run, start, k
+		}
+		prev = d
+	}
+	if len(keys) > 0 && keys[len(keys)-1] == lastDay {
+		t.Current = run
+	}
+	return t
+}
+
+// buildLeaderTenure lists every leader with its current and longest run of
+// consecutive days with at least one event, longest-serving first.
+func buildLeaderTenure(a *aggregates, _ string) []section {
+	s := section{
+		Key:         "leader_tenure",
+		Title:       "Leader Tenure",
+		Columns:     []string{"Leader", "Current streak", "Longest streak", "Longest from", "Longest to", "Active days"},
+		Truncatable: true,
+	}
+	lastDay := ""
+	for _, days := range a.leaderDays {
+		for d := range days {
+			if d > lastDay {
+				lastDay = d
+			}
+		}
+	}
+	if lastDay == "" {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	tenures := make([]leaderTenure, 0, len(a.leaderDays))
+	for l, days := range a.leaderDays {
+		tenures = append(tenures, leaderStreaks(l, days, lastDay))
+	}
+	sort.Slice(tenures, func(i, j int) bool {
+		if tenures[i].Current != tenures[j].Current {
+			return tenures[i].Current > tenures[j].Current
+		}
+		if tenures[i].Longest != tenures[j].Longest {
+			return tenures[i].Longest > tenures[j].Longest
+		}
+		return tenures[i].Leader < tenures[j].Leader
+	})
+	for _, t := range tenures {
+		s.Rows = append(s.Rows, []string{t.Leader, fmt.Sprintf("%dd", t.Current), fmt.Sprintf("%dd", t.Longest),
+			t.LongestStart, t.LongestEnd, strconv.Itoa(t.ActiveDays)})
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Current streaks end on %s, the last day with events.", lastDay))
+	return []section{s}
+}
+
+// ── weekday-leader-affinity ───────────────────────────────────────────────────
+
+const affinityLeaders = 5
+
+func prepareWeekdayLeaderAffinity(a *aggregates, _ string) error {
+	if a.perWeekdayLeader == nil {
+		a.perWeekdayLeader = new([7]map[string]int)
+		for i := range a.perWeekdayLeader {
+			a.perWeekdayLeader[i] = make(map[string]int)
+		}
+	}
+	return nil
+}
+
+// buildWeekdayLeaderAffinity prints a Monday-first weekday by leader matrix
+// for the busiest leaders, with each cell's share of that leader's events.
+func buildWeekdayLeaderAffinity(a *aggregates, _ string) []section {
+	totals := make(map[string]int)
+	for _, m := range a.perWeekdayLeader {
+		for l, n := range m {
+			totals[l] += n
+		}
+	}
+	leaders := make([]string, 0, len(totals))
+	for l := range totals {
+		leaders = append(leaders, l)
+	}
+	sort.Slice(leaders, func(i, j int) bool {
+		if totals[leaders[i]] != totals[leaders[j]] {
+			return totals[leaders[i]] > totals[leaders[j]]
+		}
+		return leaders[i] < leaders[j]
+	})
+	if len(leaders) > affinityLeaders {
+		leaders = leaders[:affinityLeaders]
+	}
+	s := section{
+		Key:     "weekday_leader_affinity",
+		Title:   fmt.Sprintf("Weekday Leader Affinity (top %d leaders)", affinityLeaders),
+		Columns: append([]string{"Weekday"}, leaders...),
+	}
+	if len(leaders) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	for i := 1; i <= 7; i++ {
+		wd := time.Weekday(i % 7)
+		row := []string{wd.String()[:3]}
+		for _, l := range leaders {
+			n := a.perWeekdayLeader[wd][l]
+			row = append(row, fmt.Sprintf("%d (%s)", n, pct(n, totals[l])))
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	s.Notes = append(s.Notes, "Percentages are each leader's share of its own events.")
+	return []section{s}
+}
+
+// ── inverted-index ────────────────────────────────────────────────────────────
+
+func prepareInvertedIndex(a *aggregates, _ string) error {
+	if a.opts.maxDayLeaders < 1 {
+		return fmt.Errorf("-max-day-leaders must be at least 1")
+	}
+	if a.perDayLeaders == nil {
+		a.perDayLeaders = make(map[string][]string)
+		a.dayLeadersCapped = make(map[string]bool)
+	}
+	return nil
+}
+
+// addDayLeader records leader as active on day, keeping at most
+// -max-day-leaders distinct leaders per day.
+func (a *aggregates) addDayLeader(day, leader string) {
+	list := a.perDayLeaders[day]
+	for _, l := range list {
+		if l == leader {
+			return
+		}
+	}
+	if len(list) >= a.opts.maxDayLeaders {
+		a.dayLeadersCapped[day] = true
+		return
+	}
+	a.perDayLeaders[day] = append(list, leader)
+}
+
+// buildInvertedIndex lists, for every matching day, the leaders that had at
+// least one event.
+func buildInvertedIndex(a *aggregates, _ string) []section {
+	s := section{
+		Key:         "inverted_index",
+		Title:       "Active Leaders by Day",
+		Columns:     []string{"Date", "Count", "Leaders"},
+		Truncatable: true,
+	}
+	days := make([]string, 0, len(a.perDayLeaders))
+	for d := range a.perDayLeaders {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+	capped := 0
+	for _, d := range days {
+		leaders := append([]string(nil), a.perDayLeaders[d]...)
+		sort.Strings(leaders)
+		n := strconv.Itoa(len(leaders))
+		if a.dayLeadersCapped[d] {
+			n += "+"
+			capped++
+		}
+		s.Rows = append(s.Rows, []string{d, n, strings.Join(leaders, ", ")})
+	}
+	if len(days) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	if capped > 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d days had more than %d leaders; only the first %d seen are listed (raise -max-day-leaders).",
+			capped, a.opts.maxDayLeaders, a.opts.maxDayLeaders))
+	}
+	return []section{s}
+}
+
+// ── leader-event-share ────────────────────────────────────────────────────────
+
+const defaultShareLeaders = 5
+
+func parseShareLeaders(arg string) (int, error) {
+	if arg == "" {
+		return defaultShareLeaders, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of leaders %q", arg)
+	}
+	return n, nil
+}
+
+func prepareLeaderEventShare(a *aggregates, arg string) error {
+	if _, err := parseShareLeaders(arg); err != nil {
+		return err
+	}
+	if a.monthLeaderCounts == nil {
+		a.monthLeaderCounts = make(map[string]map[string]int)
+	}
+	return nil
+}
+
+// buildLeaderEventShare shows each month's events split between the busiest
+// leaders of the whole period and Other, as percentages of the month.
+func buildLeaderEventShare(a *aggregates, arg string) []section {
+	n, _ := parseShareLeaders(arg)
+	totals := make(map[string]int)
+	months := make([]string, 0, len(a.monthLeaderCounts))
+	for m, counts := range a.monthLeaderCounts {
+		months = append(months, m)
+		for l, c := range counts {
+			totals[l] += c
+		}
+	}
+	sort.Strings(months)
+	leaders := make([]string, 0, len(totals))
+	for l := range totals {
+		leaders = append(leaders, l)
+	}
+	sort.Slice(leaders, func(i, j int) bool {
+		if totals[leaders[i]] != totals[leaders[j]] {
+			return totals[leaders[i]] > totals[leaders[j]]
+		}
+		return leaders[i] < leaders[j]
+	})
+	other := len(leaders) > n
+	if other {
+		leaders = leaders[:n]
+	}
+	s := section{
+		Key:     "leader_event_share",
+		Title:   fmt.Sprintf("Leader Event Share by Month (top %d)", n),
+		Columns: append([]string{"Month", "Events"}, leaders...),
+	}
+	if other {
+		s.Columns = append(s.Columns, "Other")
+	}
+	for _, m := range months {
+		counts := a.monthLeaderCounts[m]
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		rest := total
+		row := []string{m, strconv.Itoa(total)}
+		for _, l := range leaders {
+			row = append(row, pct(counts[l], total))
+			rest -= counts[l]
+		}
+		if other {
+			row = append(row, pct(rest, total))
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	if len(months) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
+
+// ── leader-cooccurrence ───────────────────────────────────────────────────────
+
+const leaderCoActivityTop = 20
+
+// leaderCoActivity counts, for each pair of leaders, the clock hours in which
+// both handled an event. Like coActivity it consumes the ordered replay and
+// holds only the current hour's leaders besides the pair counts.
+type leaderCoActivity struct {
+	hour    time.Time
+	leaders map[string]bool
+	pairs   map[[2]string]int // lesser name first
+	hours   int
+}
+
+func (c *leaderCoActivity) add(t time.Time, leader string) {
+	h := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	if !h.Equal(c.hour) {
+		c.flush()
+		c.hour = h
+	}
+	c.leaders[leader] = true
+}
+
+// flush counts every pair in the finished hour; O(k²) in its k leaders.
+func (c *leaderCoActivity) flush() {
+	if len(c.leaders) == 0 {
+		return
+	}
+	names := make([]string, 0, len(c.leaders))
+	for l := range c.leaders {
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			c.pairs[[2]string{names[i], names[j]}]++
+		}
+	}
+	c.hours++
+	c.leaders = make(map[string]bool)
+}
+
+func prepareLeaderCooccurrence(a *aggregates, _ string) error {
+	c := &leaderCoActivity{leaders: make(map[string]bool), pairs: make(map[[2]string]int)}
+	a.leaderCoActivity = c
+	a.enableReplay().consume(func(r orderedRecord) { c.add(r.time().In(a.opts.loc), r.Leader) })
+	return nil
+}
+
+// buildLeaderCooccurrence lists the pairs of leaders active in the most
+// common clock hours.
+func buildLeaderCooccurrence(a *aggregates, _ string) []section {
+	c := a.leaderCoActivity
+	c.flush()
+	type pair struct {
+		names [2]string
+		hours int
+	}
+	pairs := make([]pair, 0, len(c.pairs))
+	for k, n := range c.pairs {
+		pairs = append(pairs, pair{k, n})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].hours != pairs[j].hours {
+			return pairs[i].hours > pairs[j].hours
+		}
+		if pairs[i].names[0] != pairs[j].names[0] {
+			return pairs[i].names[0] < pairs[j].names[0]
+		}
+		return pairs[i].names[1] < pairs[j].names[1]
+	})
+	if len(pairs) > leaderCoActivityTop {
+		pairs = pairs[:leaderCoActivityTop]
+	}
+	s := section{
+		Key:     "leader_cooccurrence",
+		Title:   fmt.Sprintf("Top %d Co-occurring Leader Pairs (same clock hour)", leaderCoActivityTop),
+		Columns: []string{"Leader", "Leader", "Shared hours", "Share of active hours"},
+	}
+	for _, p := range pairs {
+		s.Rows = append(s.Rows, []string{p.names[0], p.names[1], strconv.Itoa(p.hours), pct(p.hours, c.hours)})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Active hours: %d; distinct pairs: %d", c.hours, len(c.pairs)))
+	}
+	return []section{s}
+}
+
+// ── leader-health-score ───────────────────────────────────────────────────────
+
+// leaderHealth is one leader's score and its inputs.
+type leaderHealth struct {
+	Leader   string  `json:"leader"`
+	Score    float64 `json:"score"`    // percent
+	Coverage float64 `json:"coverage"` // share of days with events, 0..1
+	Mean     float64 `json:"mean"`     // events per day
+	CV       float64 `json:"cv"`       // coefficient of variation of daily counts
+}
+
+func prepareLeaderHealthScore(a *aggregates, _ string) error {
+	if a.leaderDayCounts == nil {
+		a.leaderDayCounts = make(map[string]map[string]int)
+	}
+	return nil
+}
+
+// buildLeaderHealthScore scores each leader over the calendar days from the
+// first to the last day with filtered events, days without events counting
+// as zero: health = coverage × 0.5 + 1/(1 + cv) × 0.5, where cv is the
+// population coefficient of variation of the daily counts.
+func buildLeaderHealthScore(a *aggregates, _ string) []section {
+	s := section{
+		Key:         "leader_health_score",
+		Title:       "Leader Health Score",
+		Columns:     []string{"Leader", "Score", "Coverage", "Events/day", "CV"},
+		Truncatable: true,
+	}
+	var first, last string
+	for k := range a.perDay {
+		if first == "" || k < first {
+			first = k
+		}
+		if k > last {
+			last = k
+		}
+	}
+	if first == "" {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	f, _ := time.Parse("2006-01-02", first)
+	l, _ := time.Parse("2006-01-02", last)
+	days := int(l.Sub(f).Hours()/24) + 1
+
+	scores := make([]leaderHealth, 0, len(a.leaderDayCounts))
+	for name, counts := range a.leaderDayCounts {
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		mean := float64(total) / float64(days)
+		var ss float64
+		for _, n := range counts {
+			ss += (float64(n) - mean) * (float64(n) - mean)
+		}
+		ss += float64(days-len(counts)) * mean * mean // the days without events
+		cv := 0.0
+		if mean > 0 {
+			cv = math.Sqrt(ss/float64(days)) / mean
+		}
+		coverage := float64(len(counts)) / float64(days)
+		scores = append(scores, leaderHealth{name, 100 * (coverage*0.5 + 0.5/(1+cv)), coverage, mean, cv})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Leader < scores[j].Leader
+	})
+	for _, h := range scores {
+		s.Rows = append(s.Rows, []string{h.Leader, strconv.FormatFloat(h.Score, 'f', 1, 64) + "%",
+			strconv.FormatFloat(100*h.Coverage, 'f', 1, 64) + "%", strconv.FormatFloat(h.Mean, 'f', 2, 64),
+			strconv.FormatFloat(h.CV, 'f', 2, 64)})
+	}
+	s.Data = scores
+	s.Notes = append(s.Notes, fmt.Sprintf("%d days, %s to %s; score = coverage × 0.5 + 1/(1 + CV) × 0.5", days, first, last))
+	return []section{s}
+}
+
+// ── parent-id-histogram-by-leader ─────────────────────────────────────────────
+
+// leaderParentBuckets are the per-parent event counts of the histogram.
+const leaderParentBuckets = "1,2-5,6-20,21+"
+
+func prepareParentHistogramByLeader(a *aggregates, _ string) error {
+	if a.leaderParents == nil {
+		a.leaderParents = make(map[string]map[int]int)
+	}
+	return nil
+}
+
+// buildParentHistogramByLeader buckets, for each leader, its ParentIDs by
+// the number of filtered events they had with that leader. A parent seen
+// with several leaders is counted under each, with its events there.
+func buildParentHistogramByLeader(a *aggregates, _ string) []section {
+	buckets, _ := parseCountBuckets(leaderParentBuckets)
+	s := section{
+		Key:     "parent_id_histogram_by_leader",
+		Title:   "ParentIDs by Events per Leader",
+		Columns: []string{"Leader", "Parents"},
+	}
+	for _, b := range buckets {
+		s.Columns = append(s.Columns, b.Label)
+	}
+	leaders := make([]string, 0, len(a.leaderParents))
+	for l := range a.leaderParents {
+		leaders = append(leaders, l)
+	}
+	sort.Strings(leaders)
+	for _, l := range leaders {
+		hist := make([]int, len(buckets))
+		for _, n := range a.leaderParents[l] {
+			for i, b := range buckets {
+				if b.contains(n) {
+					hist[i]++
+					break
+				}
+			}
+		}
+		row := []string{l, strconv.Itoa(len(a.leaderParents[l]))}
+		for _, h := range hist {
+			row = append(row, strconv.Itoa(h))
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	if len(leaders) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
+
+// ── weekly-leader-transition ──────────────────────────────────────────────────
+
+func prepareWeeklyLeaderTransition(a *aggregates, arg string) error {
+	if err := requireYear(a, arg); err != nil {
+		return err
+	}
+	if a.weekLeaders == nil {
+		a.weekLeaders = make(map[int]map[string]int)
+	}
+	return nil
+}
+
+// weekTransition is a change of the dominant leader between ISO weeks.
+type weekTransition struct {
+	Week string `json:"week"`
+	Old  string `json:"old_leader"`
+	New  string `json:"new_leader"`
+}
+
+// buildWeeklyLeaderTransition compares the dominant leader of each ISO week
+// of -y with events against the previous such week and lists the changes.
+func buildWeeklyLeaderTransition(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "weekly_leader_transition",
+		Title:   fmt.Sprintf("Weekly Dominant Leader Transitions in %d", a.opts.year),
+		Columns: []string{"ISO week", "Old leader", "New leader"},
+	}
+	weeks := make([]int, 0, len(a.weekLeaders))
+	for w := range a.weekLeaders {
+		weeks = append(weeks, w)
+	}
+	sort.Ints(weeks)
+	var ts []weekTransition
+	prev := ""
+	for i, w := range weeks {
+		cur := dominantLeader(a.weekLeaders[w])
+		if i > 0 && cur != prev {
+			ts = append(ts, weekTransition{fmt.Sprintf("%04d-W%02d", a.opts.year, w), prev, cur})
+		}
+		prev = cur
+	}
+	for _, t := range ts {
+		s.Rows = append(s.Rows, []string{t.Week, t.Old, t.New})
+	}
+	s.Data = append([]weekTransition{}, ts...)
+	if len(weeks) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	if len(ts) == 0 {
+		s.Notes = append(s.Notes, "None.")
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Transitions: %d across %d weeks with events; the dominant leader has most events in the week (lowest name on ties), and weeks without events are skipped",
+		len(ts), len(weeks)))
+	return []section{s}
+}
+
+// ── daily-top-leaders ─────────────────────────────────────────────────────────
+
+func prepareDailyTopLeaders(a *aggregates, _ string) error {
+	if a.opts.year == 0 || a.opts.month == 0 {
+		return fmt.Errorf("requires -y and -m")
+	}
+	if a.leaderDayCounts == nil {
+		a.leaderDayCounts = make(map[string]map[string]int)
+	}
+	return nil
+}
+
+// dayTopLeader is the busiest leader of one day; Leader is empty on a day
+// without events.
+type dayTopLeader struct {
+	Date   string `json:"date"`
+	Leader string `json:"leader"`
+	Events int    `json:"events"`
+}
+
+// buildDailyTopLeaders lists, for every day of the -m month, the leader with
+// the most filtered events that day.
+func buildDailyTopLeaders(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "daily_top_leaders",
+		Title:   fmt.Sprintf("Daily Top Leader in %s", a.opts.dates.month(a.opts.year, a.opts.month)),
+		Columns: []string{"Date", "Top leader", "Events"},
+	}
+	byDay := make(map[string]map[string]int)
+	for l, days := range a.leaderDayCounts {
+		for d, n := range days {
+			m := byDay[d]
+			if m == nil {
+				m = make(map[string]int)
+				byDay[d] = m
+			}
+			m[l] += n
+		}
+	}
+	var rows []dayTopLeader
+	active := 0
+	for _, d := range a.periodDays() {
+		k := d.Format("2006-01-02")
+		top := dayTopLeader{Date: k}
+		if counts := byDay[k]; len(counts) > 0 {
+			top.Leader = dominantLeader(counts)
+			top.Events = counts[top.Leader]
+			active++
+		}
+		rows = append(rows, top)
+		leader := top.Leader
+		if leader == "" {
+			leader = "-"
+		}
+		s.Rows = append(s.Rows, []string{a.opts.dates.day(d), leader, strconv.Itoa(top.Events)})
+	}
+	s.Data = rows
+	if active == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d of %d days with events; ties go to the lowest leader name", active, len(rows)))
+	}
+	return []section{s}
+}
+
+// ── event-count-parity ────────────────────────────────────────────────────────
+
+const defaultParityPercent = 10.0
+
+// parseParityPercent reads the allowed deviation, e.g. "10" or "12.5%".
+func parseParityPercent(arg string) (float64, error) {
+	if arg == "" {
+		return defaultParityPercent, nil
+	}
+	p, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("invalid percentage %q", arg)
+	}
+	return p, nil
+}
+
+func prepareEventCountParity(a *aggregates, arg string) error {
+	if _, err := parseParityPercent(arg); err != nil {
+		return err
+	}
+	if a.monthLeaderCounts == nil {
+		a.monthLeaderCounts = make(map[string]map[string]int)
+	}
+	return nil
+}
+
+// leaderParity is a leader whose count is off the mean by more than allowed.
+type leaderParity struct {
+	Leader    string  `json:"leader"`
+	Events    int     `json:"events"`
+	Mean      float64 `json:"mean"`
+	Deviation float64 `json:"deviation_percent"`
+}
+
+// buildEventCountParity compares each leader's filtered events with the mean
+// over the leaders that had any, and lists those further off than allowed,
+// largest deviation first.
+func buildEventCountParity(a *aggregates, arg string) []section {
+	allowed, _ := parseParityPercent(arg)
+	s := section{
+		Key:     "event_count_parity",
+		Title:   "Leader Event Count Parity",
+		Columns: []string{"Leader", "Events", "Mean", "Deviation"},
+	}
+	totals := make(map[string]int)
+	for _, m := range a.monthLeaderCounts {
+		for l, n := range m {
+			totals[l] += n
+		}
+	}
+	if len(totals) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	sum := 0
+	for _, n := range totals {
+		sum += n
+	}
+	mean := float64(sum) / float64(len(totals))
+	var off []leaderParity
+	for l, n := range totals {
+		dev := 0.0
+		if mean > 0 {
+			dev = 100 * (float64(n) - mean) / mean
+		}
+		if math.Abs(dev) > allowed {
+			off = append(off, leaderParity{l, n, mean, dev})
+		}
+	}
+	sort.Slice(off, func(i, j int) bool {
+		if di, dj := math.Abs(off[i].Deviation), math.Abs(off[j].Deviation); di != dj {
+			return di > dj
+		}
+		return off[i].Leader < off[j].Leader
+	})
+	for _, p := range off {
+		s.Rows = append(s.Rows, []string{p.Leader, strconv.Itoa(p.Events), strconv.FormatFloat(p.Mean, 'f', 1, 64),
+			fmt.Sprintf("%+.1f%%", p.Deviation)})
+	}
+	s.Data = append([]leaderParity{}, off...)
+	s.Notes = append(s.Notes, fmt.Sprintf("%d of %d leaders deviate more than %g%% from the mean of %.1f events; leaders without events are not counted",
+		len(off), len(totals), allowed, mean))
+	return []section{s}
+}
+
+// ── leader-event-gap ──────────────────────────────────────────────────────────
+
+// leaderGap is one leader's longest quiet period between consecutive events.
+type leaderGap struct {
+	last       time.Time
+	events     int
+	max        time.Duration
+	start, end time.Time // events bounding max
+}
+
+// leaderGaps tracks each leader's last event and longest gap while the
+// ordered replay runs, so memory is bounded by the number of leaders.
+type leaderGaps map[string]*leaderGap
+
+func (g leaderGaps) add(t time.Time, leader string) {
+	l := g[leader]
+	if l == nil {
+		g[leader] = &leaderGap{last: t, events: 1}
+		return
+	}
+	if d := t.Sub(l.last); d > l.max {
+		l.max, l.start, l.end = d, l.last, t
+	}
+	l.last = t
+	l.events++
+}
+
+func prepareLeaderEventGap(a *aggregates, _ string) error {
+	g := make(leaderGaps)
+	a.leaderGaps = g
+	a.enableReplay().consume(func(r orderedRecord) { g.add(r.time(), r.Leader) })
+	return nil
+}
+
+// buildLeaderEventGap lists leaders by their longest gap, longest first,
+// hiding those under -min-gap and those with a single event.
+func buildLeaderEventGap(a *aggregates, _ string) []section {
+	const ts = "2006-01-02 15:04:05"
+	leaders := make([]string, 0, len(a.leaderGaps))
+	single, below := 0, 0
+	for name, l := range a.leaderGaps {
+		switch {
+		case l.events < 2:
+			single++
+		case l.max < a.opts.minGap:
+			below++
+		default:
+			leaders = append(leaders, name)
+		}
+	}
+	sort.Slice(leaders, func(i, j int) bool {
+		gi, gj := a.leaderGaps[leaders[i]], a.leaderGaps[leaders[j]]
+		if gi.max != gj.max {
+			return gi.max > gj.max
+		}
+		return leaders[i] < leaders[j]
+	})
+	s := section{
+		Key:     "leader_event_gap",
+		Title:   "Longest Gap Between Events per Leader",
+		Columns: []string{"Leader", "Longest gap", "From", "To", "Events"},
+	}
+	for _, name := range leaders {
+		l := a.leaderGaps[name]
+		s.Rows = append(s.Rows, []string{name, formatSpan(l.max),
+			l.start.In(a.opts.loc).Format(ts), l.end.In(a.opts.loc).Format(ts), strconv.Itoa(l.events)})
+	}
+	if len(a.leaderGaps) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	if below > 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d leaders with a longest gap under %s not shown", below, shortSpan(a.opts.minGap)))
+	}
+	if single > 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d leaders with a single event not shown", single))
+	}
+	return []section{s}
+}
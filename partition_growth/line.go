@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -output line ──────────────────────────────────────────────────────────────
+//
+// The line format is one key=value line for log-based alerting, always with
+// these fields in this order:
+//
+//	partition_growth result total=<n> year=<y> month=<m> max_day=<date>:<n> errors=<n> duration=<d> status=<s>
+//
+// total is the filtered event count (or -weight-field sum); year and month are
+// -y and -m, "all" when unset; max_day is the busiest filtered day and its
+// count, "-" without events; errors counts the records skipped for a missing
+// or unparseable date; duration is the run time rounded to seconds in Go
+// syntax (41s, 2m5s); status is the check status, ok, warn or crit. A value
+// that is empty or holds a space, quote, '=' or control character is written
+// as a Go-quoted string. The report itself goes to stderr as text.
+
+// summaryLine holds the fields of the line format.
+type summaryLine struct {
+	total       int
+	year, month int
+	maxDay      string
+	maxDayCount int
+	errors      int
+	duration    time.Duration
+	status      checkStatus
+}
+
+func newSummaryLine(perDay map[string]int, total, year, month, errors int, duration time.Duration, status checkStatus) summaryLine {
+	l := summaryLine{total: total, year: year, month: month, errors: errors, duration: duration, status: status}
+	for d, n := range perDay {
+		if n > l.maxDayCount || n == l.maxDayCount && d < l.maxDay {
+			l.maxDay, l.maxDayCount = d, n
+		}
+	}
+	return l
+}
+
+func (l summaryLine) String() string {
+	filter := func(v int) string {
+		if v == 0 {
+			return "all"
+		}
+		return strconv.Itoa(v)
+	}
+	maxDay := "-"
+	if l.maxDay != "" {
+		maxDay = l.maxDay + ":" + strconv.Itoa(l.maxDayCount)
+	}
+	fields := [][2]string{
+		{"total", strconv.Itoa(l.total)},
+		{"year", filter(l.year)},
+		{"month", filter(l.month)},
+		{"max_day", maxDay},
+		{"errors", strconv.Itoa(l.errors)},
+		{"duration", l.duration.Round(time.Second).String()},
+		{"status", strings.ToLower(l.status.String())},
+	}
+	var b strings.Builder
+	b.WriteString("partition_growth result")
+	for _, f := range fields {
+		b.WriteString(" " + f[0] + "=" + lineValue(f[1]))
+	}
+	return b.String()
+}
+
+// lineValue quotes v when it could not be read back as one bare value.
+func lineValue(v string) string {
+	if v == "" || strings.ContainsFunc(v, func(r rune) bool {
+		return r <= ' ' || r == '"' || r == '=' || r == 0x7f
+	}) {
+		return strconv.Quote(v)
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The line format is parsed by log alerting; these tests pin it exactly.
+
+func TestSummaryLine(t *testing.T) {
+	tests := []struct {
+		name string
+		l    summaryLine
+		want string
+	}{
+		{"filtered run",
+			newSummaryLine(map[string]int{"2025-03-13": 900, "2025-03-14": 2317, "2025-03-15": 2317}, 48211, 2025, 3, 12, 41*time.Second+400*time.Millisecond, statusOK),
+			"partition_growth result total=48211 year=2025 month=3 max_day=2025-03-14:2317 errors=12 duration=41s status=ok"},
+		{"no filters",
+			newSummaryLine(map[string]int{"2024-01-01": 1}, 1, 0, 0, 0, 0, statusWarn),
+			"partition_growth result total=1 year=all month=all max_day=2024-01-01:1 errors=0 duration=0s status=warn"},
+		{"no events",
+			newSummaryLine(nil, 0, 2025, 0, 3, 125*time.Second+600*time.Millisecond, statusCrit),
+			"partition_growth result total=0 year=2025 month=all max_day=- errors=3 duration=2m6s status=crit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.String(); got != tt.want {
+				t.Errorf("\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineValue(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"41s", "41s"},
+		{"2025-03-14:2317", "2025-03-14:2317"},
+		{"", `""`},
+		{"a b", `"a b"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"k=v", `"k=v"`},
+		{"tab\there", `"tab\there"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"del\x7f", `"del\x7f"`},
+		{"naïve", "naïve"},
+	}
+	for _, tt := range tests {
+		if got := lineValue(tt.in); got != tt.want {
+			t.Errorf("lineValue(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// linePattern is the documented field order, as an alerting rule would match
+// it.
+var linePattern = regexp.MustCompile(`^partition_growth result total=\d+ year=(\d+|all) month=(\d+|all) max_day=(\d{4}-\d\d-\d\d:\d+|-) errors=\d+ duration=\S+ status=(ok|warn|crit)\n$`)
+
+// TestLineOutput runs the command with -output line: stdout holds the one
+// line, the report goes to stderr, and status follows the checks.
+func TestLineOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(
+		event(1, "2025-03-14T01:00:00Z", "a"),
+		event(10, "2025-03-14T02:00:00Z", "b"),
+		event(20, "2025-03-15T01:00:00Z", "a"),
+		event(30, "2025-04-01T01:00:00Z", "a"),
+		event(40, "garbage", "a"),
+	))
+	tests := []struct {
+		name string
+		args []string
+		exit int
+		want string
+	}{
+		{"month", []string{"-y", "2025", "-m", "3"}, 0,
+			"partition_growth result total=3 year=2025 month=3 max_day=2025-03-14:2 errors=1 duration=0s status=ok"},
+		{"everything", []string{"-a"}, 0,
+			"partition_growth result total=4 year=all month=all max_day=2025-03-14:2 errors=1 duration=0s status=ok"},
+		{"stale leader", []string{"-y", "2025", "-asof", "2025-04-03", "-leader-freshness", "1d"}, 1,
+			"partition_growth result total=4 year=2025 month=all max_day=2025-03-14:2 errors=1 duration=0s status=warn"},
+		{"critical", []string{"-y", "2025", "-asof", "2025-04-05", "-leader-freshness", "1d", "-leader-freshness-crit", "2d"}, 2,
+			"partition_growth result total=4 year=2025 month=all max_day=2025-03-14:2 errors=1 duration=0s status=crit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, errOut, status := runTool(t, dir, append([]string{"-f", "in.json", "-output", "line"}, tt.args...)...)
+			if status != tt.exit {
+				t.Errorf("exit %d, want %d\n%s", status, tt.exit, errOut)
+			}
+			if !linePattern.MatchString(out) {
+				t.Errorf("stdout is not one summary line: %q", out)
+			}
+			if got := strings.TrimSuffix(out, "\n"); got != tt.want {
+				t.Errorf("\n got %s\nwant %s", got, tt.want)
+			}
+			if !strings.Contains(errOut, "Unparseable date  1") {
+				t.Errorf("report not on stderr:\n%s", errOut)
+			}
+		})
+	}
+}
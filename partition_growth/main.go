@@ -1,23 +1,55 @@
 package main
 
 import (
-    "bufio"
-    "encoding/json"
+    "crypto/sha256"
+    "errors"
     "flag"
     "fmt"
+    "io"
+    "math"
     "os"
     "sort"
     "strconv"
+    "strings"
+    "sync"
     "time"
+
+    "partition_growth/events"
+)
+
+// Event is the decoded JSON record; see the events package.
+type Event = events.Event
+
+// verbose enables the -v diagnostics written by logf.
+var verbose bool
+
+// reportOut receives the report, and noteOut the note:, warning:, progress
+// and -v lines; -silent discards both. Errors always go to stderr.
+var (
+    reportOut io.Writer = os.Stdout
+    noteOut   io.Writer = os.Stderr
 )
 
-// Event defines the structure of each JSON record.
-type Event struct {
-    Date           string `json:"date"`
-    ParentID       int    `json:"parentId"`
-    FirstChildID   int    `json:"firstChildId"`
-    SecondChildID  int    `json:"secondChildId"`
-    LeaderNodeInfo string `json:"leaderNodeInfo"`
+func logf(format string, args ...interface{}) {
+    if verbose {
+        fmt.Fprintf(noteOut, format+"\n", args...)
+    }
+}
+
+// logProgress is the -progress callback.
+func logProgress(p events.Progress) {
+    state := "reading"
+    if p.Done {
+        state = "done"
+    }
+    fmt.Fprintf(noteOut, "progress: %s: %s, %d records, %d bytes", p.Source, state, p.Records, p.Bytes)
+    if p.Documents > 1 {
+        fmt.Fprintf(noteOut, ", %d top-level documents", p.Documents)
+    }
+    if p.Errors > 0 {
+        fmt.Fprintf(noteOut, ", %d errors", p.Errors)
+    }
+    fmt.Fprintln(noteOut)
 }
 
 func monthName(m int) string {
@@ -28,64 +60,850 @@ func daysInMonth(year int, month int) int {
     return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
 }
 
+// maxDaysInMonth is the most days month has in any year, so Feb counts 29.
+func maxDaysInMonth(month int) int {
+    return daysInMonth(2000, month)
+}
+
+// maxDaysSelected is the most days of any month that -y, -m (0 for any) and
+// the -from/-to range select, in loc. An open selection may include any year,
+// so Feb counts 29; an empty one, matching no event, counts 31.
+func maxDaysSelected(year, month int, rng timeRange, loc *time.Location) int {
+    lo, hi := rng.From, rng.To
+    if year != 0 {
+        if start := time.Date(year, 1, 1, 0, 0, 0, 0, loc); lo.IsZero() || lo.Before(start) {
+            lo = start
+        }
+        if end := time.Date(year+1, 1, 1, 0, 0, 0, 0, loc); hi.IsZero() || hi.After(end) {
+            hi = end
+        }
+    }
+    switch {
+    case lo.IsZero() || hi.IsZero():
+        if month != 0 {
+            return maxDaysInMonth(month)
+        }
+        return 31
+    case !hi.After(lo):
+        return 31
+    }
+    most := 0
+    first, last := lo.In(loc), hi.Add(-time.Nanosecond).In(loc)
+    for y, m := first.Year(), first.Month(); most < 31 && (y < last.Year() || (y == last.Year() && m <= last.Month())); y, m = y+int(m)/12, m%12+1 {
+        if month == 0 || int(m) == month {
+            most = max(most, daysInMonth(y, int(m)))
+        }
+    }
+    return most
+}
+
 func getQuarter(m time.Month) int {
     return (int(m)-1)/3 + 1
 }
 
+// filterDescription summarizes the -y/-m/-d filters for document titles.
+func filterDescription(year, month, day int) string {
+    var parts []string
+    if year != 0 {
+        parts = append(parts, fmt.Sprintf("year %d", year))
+    }
+    if month != 0 {
+        parts = append(parts, "month "+monthName(month))
+    }
+    if day != 0 {
+        parts = append(parts, fmt.Sprintf("day %d", day))
+    }
+    if len(parts) == 0 {
+        return "all events"
+    }
+    return strings.Join(parts, ", ")
+}
+
 func main() {
+    started := time.Now()
+
     // Command‑line flags
-    filePath := flag.String("f", "", "path to JSON input file (required)")
+    var inputs inputList
+    flag.Var(&inputs, "f", "JSON input file, directory or glob; repeatable (required)")
+    dropSelfRefs := flag.Bool("drop-self-refs", false, "exclude records whose parent equals a child or whose children are equal")
+    idMin := flag.Int64("id-min", 0, "lowest valid parentId/firstChildId/secondChildId")
+    idMax := flag.Int64("id-max", math.MaxInt64, "highest valid parentId/firstChildId/secondChildId")
+    dropBadIDs := flag.Bool("drop-bad-ids", false, "exclude records with an ID outside -id-min..-id-max or beyond int64")
+    allowDuplicates := flag.Bool("allow-duplicates", false, "read a file again when several -f values resolve to it")
+    inputFormat := flag.String("input-format", "file", "how -f values are read: file (paths, directories, globs) or http (URLs fetched with GET)")
+    httpRetries := flag.Int("http-retries", 3, "with -input-format http: retries of a request after a 5xx response, timeout or transport error")
+    httpBackoffMax := flag.Duration("http-backoff-max", 32*time.Second, "with -input-format http: longest delay between retries, which double from 2s")
     day := flag.Int("d", 0, "filter by day of month (1‑31)")
     month := flag.Int("m", 0, "filter by month (1‑12)")
     year := flag.Int("y", 0, "filter by year")
     allYears := flag.Bool("a", false, "print all data summarized by year, quarter, and last 30 days")
+    allYearsFiltered := flag.Bool("a-filtered", false, "like -a, over the events passing -y/-m/-d, -from/-to and -where")
     top := flag.Bool("t", false, "show top results; use with -y and one of -week or -month")
     topMonth := flag.Bool("month", false, "with -t and -y: show top 5 months in that year")
     topWeek := flag.Bool("week", false, "with -t and -y: show top 5 ISO weeks in that year")
+    topDetailFlag := flag.String("top-detail", "", "with -t: list the top 3 parents or leaders of each top month or week")
+    fromFlag := flag.String("from", "", "only events at or after this date or timestamp")
+    toFlag := flag.String("to", "", "only events up to this date (whole day) or timestamp, inclusive")
+    toExclusive := flag.Bool("to-exclusive", false, "treat -to as the exclusive end of a half-open range")
+    var where, dumpWhere whereValue
+    flag.Var(&where, "where", "only events matching this filter expression")
+    twoDigitPivot := flag.Int("two-digit-year-pivot", 0, "read two-digit years as the year from this pivot to pivot+99 (e.g. 1970)")
+    assumeYearFlag := flag.String("assume-year", "", "year for dates without one: a year, or from-filename:<regexp> capturing it from the file path")
+    tz := flag.String("tz", "UTC", "IANA time zone for reading zone-less dates and bucketing (e.g. America/New_York)")
+    truncOffset := flag.String("date-trunc-offset", "", "fixed UTC offset, e.g. +5:30, at which day/week/month buckets start")
+    dateStyleFlag := flag.String("date-style", "us", "date labels in text, jira and html output: iso, us or eu")
+    outputFormat := flag.String("output", "text", "output format: text, jira, json, html, html-full, csv, tsv, dot-timeline or line")
+    dotPeriod := flag.String("dot-period", "month", "with -output dot-timeline: period of each node, day, week or month")
+    flag.StringVar(outputFormat, "o", "text", "shorthand for -output")
+    flag.StringVar(outputFormat, "output-format", "text", "same as -output")
+    var reportFlags reportList
+    flag.Var(&reportFlags, "report", "additional report(s) to print, comma-separated or repeated")
+    segments := flag.Bool("segments", false, "report active segments separated by quiet gaps longer than -gap")
+    gap := flag.Duration("gap", 6*time.Hour, "with -segments: quiet period that ends a segment")
+    var minGap spanValue
+    flag.Var(&minGap, "min-gap", "with leader-event-gap: hide leaders whose longest gap is shorter (e.g. 1h)")
+    minOverlapParents := flag.Int("min-parents", 3, "with event-overlap-window: distinct ParentIDs a window needs")
+    spikeFlag := flag.String("spike", "2", "with event-spike-context: days above mean + K standard deviations are spikes")
+    maxSegments := flag.Int("max-segments", 50, "with -segments: maximum segments listed")
+    spillDir := flag.String("spill-dir", "", "directory for ordered-report spill files (default $TMPDIR)")
+    sortMem := flag.Int("sort-mem", 1000000, "events sorted in memory before ordered reports spill to disk")
+    spillLimit := flag.Int64("spill-limit", 0, "maximum bytes of spill files; 0 means unlimited")
+    enrichCmd := flag.String("enrich-cmd", "", "executable adding fields to filtered events over NDJSON stdin/stdout, read as enrich.<name>")
+    enrichBatch := flag.Int("enrich-batch", 1000, "with -enrich-cmd: events sent per batch")
+    enrichTimeout := flag.Duration("enrich-timeout", 30*time.Second, "with -enrich-cmd: time allowed to answer a batch or to exit")
+    groupBy := flag.String("group-by", "", "count filtered events per parent or leader")
+    groupTop := flag.Int("group-top", 20, "with -group-by: number of groups listed")
+    moversDim := flag.String("movers", "", "list the top increases and decreases per parent or leader between -periods")
+    periodsFlag := flag.String("periods", "", "with -movers: two months YYYY-MM,YYYY-MM (default the two complete months before the latest event)")
+    moversTop := flag.Int("movers-top", 10, "with -movers: values listed in each direction")
+    sortFlag := flag.String("sort", "", "row order of -group-by and -top-detail listings: key, -key, count or -count (default)")
+    approx := flag.Bool("approx", false, "with -group-by: switch to bounded-memory approximate counting above -approx-threshold distinct values")
+    statsExact := flag.Int("stats-exact", 100000, "with -report=stats: open days kept exactly before quantiles become approximate")
+    approxThreshold := flag.Int("approx-threshold", 100000, "with -approx: distinct values kept before counting becomes approximate")
+    aggregate := flag.String("aggregate", "", "aggregate the event value field per period: count,sum,max,min")
+    reportDirFlag := flag.String("report-dir", "", "also save this run's output in a new timestamped subdirectory of this directory")
+    keepReports := flag.Int("keep", 30, "with -report-dir: number of runs to keep")
+    maxOutputBytes := flag.Int("max-output-bytes", 0, "shrink the report until it fits in this many bytes; 0 means unlimited")
+    sectionsFlag := flag.String("sections", "", "comma-separated section keys to print, in this order (JSON keeps its own order)")
+    flag.BoolVar(&verbose, "v", false, "log diagnostics to stderr")
+    resume := flag.String("resume", "", "journal file for resuming an interrupted multi-file run")
+    progress := flag.Bool("progress", false, "log reading progress to stderr")
+    workers := flag.Int("workers", 1, "goroutines that parse event dates; aggregation stays under one lock")
+    streamingOutput := flag.Bool("streaming-output", false, "print each period's filtered count as soon as it closes; input must be sorted")
+    streamPeriod := flag.String("stream-period", "month", "with -streaming-output: day, week, month or year")
+    assertSorted := flag.Bool("assert-sorted", false, "fail if an event is earlier than the one before it")
+    weightField := flag.String("weight-field", "", "sum this numeric record field instead of counting events")
+    withWeight := flag.String("with-weight", "", "also sum this numeric record field beside the event counts")
+    rankBy := flag.String("rank-by", "count", "with -with-weight: order top lists by count or weight")
+    weightDefault := flag.Int("weight-default", 0, "with -weight-field: weight of records without the field")
+    isoOffsetsFlag := flag.String("iso-offsets", "normalize-tz", "dates written with a UTC offset: honor (bucket at that offset), normalize-utc or normalize-tz (convert to -tz)")
+    midnightFlag := flag.String("midnight-policy", "keep", "records written at exactly midnight: keep (convert to -tz) or date-only (bucket on the written date)")
+    typeField := flag.String("type-field", "type", "record field naming the event type, read with -type or -by-type")
+    typeWant := flag.String("type", "", "count only records of this type; others are tallied separately")
+    byType := flag.Bool("by-type", false, "add a per-type monthly breakdown")
+    typeMissing := flag.String("type-missing", "include", "with -type or -by-type: records without the type field are include, exclude or own-bucket")
+    humanize := flag.Bool("humanize", false, "abbreviate large counts in the summary (1.2K, 3.4M)")
+    silent := flag.Bool("silent", false, "print nothing; report only through the exit status")
+    telemetry := flag.Bool("telemetry", false, "report the size of each active aggregation")
+    var pluginPaths pluginList
+    flag.Var(&pluginPaths, "plugin", "Go plugin (.so) exporting an Aggregator; repeatable")
+    asofFlag := flag.String("asof", "", "reference time for checks (default now); any accepted event date format")
+    var leaderFreshness, leaderFreshnessCrit spanValue
+    freshnessLookback := spanValue(14 * 24 * time.Hour)
+    flag.Var(&leaderFreshness, "leader-freshness", "check: WARN for leaders silent longer than this (e.g. 24h)")
+    flag.Var(&leaderFreshnessCrit, "leader-freshness-crit", "with -leader-freshness: CRIT for leaders silent longer than this")
+    flag.Var(&freshnessLookback, "freshness-lookback", "with -leader-freshness: only leaders seen within this window before -asof")
+    var ignoreLeaders patternList
+    flag.Var(&ignoreLeaders, "ignore-leader", "with -leader-freshness: leader glob pattern to skip; repeatable")
+    retention := flag.Bool("retention", false, "with -y: monthly ParentID cohorts and the share active in later months")
+    minCohort := flag.Int("min-cohort", 5, "with -retention: hide cohorts with fewer parents")
+    retentionCSV := flag.String("retention-csv", "", "with -retention: also write the cohort table as CSV to this path")
+    leaderChurn := flag.Bool("leader-churn", false, "with -y: per-month distinct, new and returning leaders")
+    ageHist := flag.Bool("age-histogram", false, "bucket filtered events by age at -asof, with the p95 age")
+    ageBuckets := flag.String("age-buckets", defaultAgeBuckets, "with -age-histogram: ascending bucket boundaries")
+    weekPercentiles := flag.Bool("week-percentiles", false, "p50/p90/p99/max of events per ISO week over the whole dataset, empty weeks included")
+    parentDistBuckets := flag.String("parent-dist-buckets", "1,2,3-10,11-100,101+", "bucket boundaries for -report=parent-id-distribution")
+    dumpDaily := flag.String("dump-daily", "", "write date,count CSV for every day of the dataset to this path (- for stdout)")
+    postURL := flag.String("post", "", "POST the filtered counts per bucket as gzip'd NDJSON batches to this URL")
+    postGranularity := flag.String("post-granularity", "day", "with -post: bucket of each record, day, week or month")
+    postBatch := flag.Int("post-batch", 500, "with -post: records per request")
+    postTokenEnv := flag.String("post-token-env", "PARTITION_GROWTH_POST_TOKEN", "with -post: environment variable holding the bearer token")
+    postRetries := flag.Int("post-retries", 5, "with -post: retries of a batch after a 5xx response or transport error")
+    postDryRun := flag.Bool("post-dry-run", false, "with -post: print the first batch instead of sending")
+    dumpDailyFiltered := flag.Bool("dump-daily-filtered", false, "with -dump-daily: count only events passing -y/-m/-d")
+    dumpPath := flag.String("dump", "", "write the filtered events as canonical JSON lines to this path (- for stdout)")
+    flag.Var(&dumpWhere, "dump-where", "with -dump: only dump events matching this filter expression")
+    showSamples := flag.Int("show-samples", 0, "print a uniform random sample of this many counted events after the report")
+    seed := flag.Int64("seed", 0, "with -show-samples: random seed; 0 picks one and prints it")
+    samplesInJSON := flag.Bool("samples-in-json", false, "with -show-samples: include the samples in -output json")
+    var presets presetList
+    flag.Var(&presets, "preset", "named set of flags: weekly-ops, capacity, audit or one from -config; repeatable")
+    flag.String("config", "", "JSON file defining additional presets")
+    flag.String("print-preset", "", "print the flags a preset expands to and exit")
+    var traceRecord whereValue
+    flag.Var(&traceRecord, "trace-record", "explain how records matching this filter expression are counted")
+    traceLimit := flag.Int("trace-limit", 10, "with -trace-record: maximum records traced")
+    traceOut := flag.String("trace-out", "", "with -trace-record: write traces to this file instead of stderr")
+    maxDayLeaders := flag.Int("max-day-leaders", 50, "with -report=inverted-index: distinct leaders kept per day")
+    minParentEvents := flag.Int("min-parent-events", 0, "with -report=parent-lifecycle or parent-event-rate: list parents with at least this many events (default 1 and 10)")
 
     flag.Usage = func() {
         fmt.Fprintf(os.Stderr, "Usage:\n")
         fmt.Fprintf(os.Stderr, "  %s -f <file> [options]\n\n", os.Args[0])
         fmt.Fprintf(os.Stderr, "Options:\n")
-        fmt.Fprintf(os.Stderr, "  -f <path>          Path to JSON input file (required)\n")
+        fmt.Fprintf(os.Stderr, "  -f <path>          JSON input file (required). Repeatable; a directory reads its *.json,\n")
+        fmt.Fprintf(os.Stderr, "                     *.jsonl and *.ndjson files and a quoted glob reads every match.\n")
+        fmt.Fprintf(os.Stderr, "                     - reads standard input, which is also the default when it is piped.\n")
+        fmt.Fprintf(os.Stderr, "                     All inputs are merged into one report; each is detected as a JSON\n")
+        fmt.Fprintf(os.Stderr, "                     array or an object stream on its own, and decode errors name the\n")
+        fmt.Fprintf(os.Stderr, "                     file and record\n")
+        fmt.Fprintf(os.Stderr, "  -allow-duplicates  Read a file once per -f value that names it (default: each file once)\n")
+        fmt.Fprintf(os.Stderr, "  -input-format <f>  file (default) or http: each -f value is an http or https URL, fetched\n")
+        fmt.Fprintf(os.Stderr, "                     with GET and read as it streams in, like standard input\n")
+        fmt.Fprintf(os.Stderr, "  -http-retries <n>  With -input-format http: retries after a 5xx response, a timeout or a\n")
+        fmt.Fprintf(os.Stderr, "                     transport error (default 3); then the run fails with E_NETWORK\n")
+        fmt.Fprintf(os.Stderr, "  -http-backoff-max <d>\n")
+        fmt.Fprintf(os.Stderr, "                     With -input-format http: the retry delay doubles from 2s up to this\n")
+        fmt.Fprintf(os.Stderr, "                     (default 32s)\n")
+        fmt.Fprintf(os.Stderr, "  -drop-self-refs    Exclude self-referential records (parent equal to a child, or equal\n")
+        fmt.Fprintf(os.Stderr, "                     children); they are counted in the report either way\n")
+        fmt.Fprintf(os.Stderr, "  -id-min <n>, -id-max <n>\n")
+        fmt.Fprintf(os.Stderr, "                     Valid range of parentId, firstChildId and secondChildId (default 0 to\n")
+        fmt.Fprintf(os.Stderr, "                     the int64 maximum); IDs outside it or beyond int64 are reported\n")
+        fmt.Fprintf(os.Stderr, "  -drop-bad-ids      Exclude the records with an ID out of range; counted in the report either way\n")
         fmt.Fprintf(os.Stderr, "  -y <year>          Filter by year; prints year summary only when provided\n")
         fmt.Fprintf(os.Stderr, "  -m <month>         Filter by month (1-12); with -y prints in-month weekly summary and total\n")
-        fmt.Fprintf(os.Stderr, "  -d <day>           Filter by day; day count prints only when -d -m -y are all provided\n")
-        fmt.Fprintf(os.Stderr, "  -a                 Print all data summarized by year, quarter, and last 30 days\n")
+        fmt.Fprintf(os.Stderr, "  -d <day>           Filter by day; day count prints only when -d -m -y are all provided.\n")
+        fmt.Fprintf(os.Stderr, "                     Rejected when no month selected by -y, -m and -from/-to has the day\n")
+        fmt.Fprintf(os.Stderr, "  -a                 Print all data summarized by year, quarter, and last 30 days; the\n")
+        fmt.Fprintf(os.Stderr, "                     rollup always covers every event, whatever filters are set\n")
+        fmt.Fprintf(os.Stderr, "  -a-filtered        Like -a, but only over events passing -y/-m/-d, -from/-to and -where\n")
         fmt.Fprintf(os.Stderr, "  -t                 Show top results (requires -y and one of -week or -month)\n")
         fmt.Fprintf(os.Stderr, "  -week              With -t and -y: show top 5 ISO weeks in that year\n")
         fmt.Fprintf(os.Stderr, "  -month             With -t and -y: show top 5 months in that year\n")
+        fmt.Fprintf(os.Stderr, "  -top-detail <dim>  With -t: under each top month or week, its top 3 parents or leaders\n")
+        fmt.Fprintf(os.Stderr, "                     with their counts and share of the bucket (-approx bounds memory)\n")
+        fmt.Fprintf(os.Stderr, "  -from <date>       Only events at or after this date (start of day) or timestamp\n")
+        fmt.Fprintf(os.Stderr, "  -to <date>         Only events up to this date, including the whole day, or timestamp\n")
+        fmt.Fprintf(os.Stderr, "  -to-exclusive      Make -to the exclusive end of a half-open range [from, to)\n")
+        fmt.Fprintf(os.Stderr, "                     The report opens with the resolved bounds in the -tz zone\n")
+        fmt.Fprintf(os.Stderr, "  -where <expr>      Only events matching expr, e.g. 'leader ~ \"node-[12]*\" and parent < 100'.\n")
+        fmt.Fprintf(os.Stderr, "                     Fields: parent first second value year month day hour leader date\n")
+        fmt.Fprintf(os.Stderr, "                     weekday enrich.<name>; operators == != < <= > >= ~ !~ (glob), and/or/not, parentheses\n")
+        fmt.Fprintf(os.Stderr, "  -two-digit-year-pivot <year>\n")
+        fmt.Fprintf(os.Stderr, "                     Accept two-digit years (Mar 14, 25, ...) as years from pivot to pivot+99\n")
+        fmt.Fprintf(os.Stderr, "  -assume-year <year|from-filename:regexp>\n")
+        fmt.Fprintf(os.Stderr, "                     Accept dates without a year using this year, or the first group of\n")
+        fmt.Fprintf(os.Stderr, "                     regexp matched against each file path. Inferred records are counted\n")
+        fmt.Fprintf(os.Stderr, "                     in the report. Without these flags such dates are parse errors\n")
+        fmt.Fprintf(os.Stderr, "  -tz <zone>         Time zone of zone-less dates and of all day/week/month buckets (default UTC).\n")
+        fmt.Fprintf(os.Stderr, "                     Repeated fall-back times resolve to the first occurrence; times skipped by\n")
+        fmt.Fprintf(os.Stderr, "                     spring-forward move forward by the gap. Dates with an offset are converted.\n")
+        fmt.Fprintf(os.Stderr, "  -iso-offsets <p>   Dates written with a UTC offset (2025-03-14T23:45:00+02:00): normalize-tz\n")
+        fmt.Fprintf(os.Stderr, "                     (default) buckets them in -tz, normalize-utc in UTC, honor at the written\n")
+        fmt.Fprintf(os.Stderr, "                     offset. Records with a non-zero offset are counted in the report\n")
+        fmt.Fprintf(os.Stderr, "  -midnight-policy <p>\n")
+        fmt.Fprintf(os.Stderr, "                     Records written at exactly 00:00:00, often dates truncated upstream:\n")
+        fmt.Fprintf(os.Stderr, "                     keep (default) converts them to -tz like other times, date-only buckets\n")
+        fmt.Fprintf(os.Stderr, "                     them on the written date. Either way they are counted in the report\n")
+        fmt.Fprintf(os.Stderr, "  -date-trunc-offset <+hh:mm>\n")
+        fmt.Fprintf(os.Stderr, "                     Start days, weeks and months at midnight of this fixed UTC offset while\n")
+        fmt.Fprintf(os.Stderr, "                     zone-less dates are still read in -tz; -from, -to and -asof dates use it too\n")
+        fmt.Fprintf(os.Stderr, "  -output <format>   Output format: text (default), jira (Jira wiki markup tables), json,\n")
+        fmt.Fprintf(os.Stderr, "                     html (fragment with inline styles for Confluence or email) or\n")
+        fmt.Fprintf(os.Stderr, "                     html-full (complete document titled with the filters and run time),\n")
+        fmt.Fprintf(os.Stderr, "                     csv or tsv (per section a header of section and columns, then one\n")
+        fmt.Fprintf(os.Stderr, "                     record per row, blocks separated by blank lines; ISO dates),\n")
+        fmt.Fprintf(os.Stderr, "                     dot-timeline (only a Graphviz chain of filtered counts per period,\n")
+        fmt.Fprintf(os.Stderr, "                     shaded white to red; render with dot -Grankdir=LR) or line (one\n")
+        fmt.Fprintf(os.Stderr, "                     line on stdout: partition_growth result total=<n> year=<y|all>\n")
+        fmt.Fprintf(os.Stderr, "                     month=<m|all> max_day=<date:n|-> errors=<n> duration=<d> status=<ok|warn|crit>,\n")
+        fmt.Fprintf(os.Stderr, "                     in that order, values with spaces quoted; the report goes to stderr)\n")
+        fmt.Fprintf(os.Stderr, "  -dot-period <p>    With -output dot-timeline: day, week or month (default)\n")
+        fmt.Fprintf(os.Stderr, "  -o <format>, -output-format <format>\n")
+        fmt.Fprintf(os.Stderr, "                     Same as -output\n")
+        fmt.Fprintf(os.Stderr, "  -preset <name>     Prepend a named set of flags; explicit flags override it. Built in:\n")
+        fmt.Fprintf(os.Stderr, "                     weekly-ops, capacity, audit. Repeatable\n")
+        fmt.Fprintf(os.Stderr, "  -config <path>     JSON file with more presets: {\"presets\": {\"name\": [\"-y\", \"2025\"]}}\n")
+        fmt.Fprintf(os.Stderr, "  -print-preset <name>\n")
+        fmt.Fprintf(os.Stderr, "                     Print the flags a preset expands to and exit\n")
+        fmt.Fprintf(os.Stderr, "  -report-dir <path> Also save the output in a new subdirectory of path named after the UTC start\n")
+        fmt.Fprintf(os.Stderr, "                     time, point path/latest at it and remove the oldest beyond -keep. Nothing\n")
+        fmt.Fprintf(os.Stderr, "                     is removed if path holds entries other than runs and latest\n")
+        fmt.Fprintf(os.Stderr, "  -keep <n>          With -report-dir: runs to keep (default 30)\n")
+        fmt.Fprintf(os.Stderr, "  -max-output-bytes <n>\n")
+        fmt.Fprintf(os.Stderr, "                     Fit the output in n bytes: drop example lists, then shorten long tables\n")
+        fmt.Fprintf(os.Stderr, "                     (marked truncated), then drop optional report sections\n")
+        fmt.Fprintf(os.Stderr, "  -sections <keys>   Print only the sections with these keys (the JSON \"key\" values), in the\n")
+        fmt.Fprintf(os.Stderr, "                     order listed, e.g. top_weeks,monthly; -output json keeps the usual order\n")
+        fmt.Fprintf(os.Stderr, "  -resume <journal>  Journal each completed input file; after a crash, rerun with the same\n")
+        fmt.Fprintf(os.Stderr, "                     journal to skip files already read. Removed when the run completes\n")
+        fmt.Fprintf(os.Stderr, "  -progress          Log records and bytes read to stderr every 100000 records and per file\n")
+        fmt.Fprintf(os.Stderr, "  -workers <n>       Parse event dates on n goroutines (default 1); aggregation stays under one\n")
+        fmt.Fprintf(os.Stderr, "                     lock. Reports are unchanged except -approx top-K, whose estimates\n")
+        fmt.Fprintf(os.Stderr, "                     depend on event order\n")
+        fmt.Fprintf(os.Stderr, "  -streaming-output  Print \"period: count\" of filtered events as each period closes, before\n")
+        fmt.Fprintf(os.Stderr, "                     the report; needs time-ordered input (implies -assert-sorted)\n")
+        fmt.Fprintf(os.Stderr, "  -stream-period <p> With -streaming-output: day, week, month (default) or year\n")
+        fmt.Fprintf(os.Stderr, "  -assert-sorted     Fail on the first event earlier than the one before it\n")
+        fmt.Fprintf(os.Stderr, "  -weight-field <name>\n")
+        fmt.Fprintf(os.Stderr, "                     Sum this numeric field of each record instead of counting events; the\n")
+        fmt.Fprintf(os.Stderr, "                     summary is labelled with the field name and JSON has both totals\n")
+        fmt.Fprintf(os.Stderr, "  -with-weight <name> Keep counting events and also sum this field: monthly, weekly and top\n")
+        fmt.Fprintf(os.Stderr, "                     lists gain sum and average columns\n")
+        fmt.Fprintf(os.Stderr, "  -rank-by <key>     With -with-weight: order top lists by count (default) or weight\n")
+        fmt.Fprintf(os.Stderr, "  -weight-default <n>\n")
+        fmt.Fprintf(os.Stderr, "                     With -weight-field or -with-weight: value for records without the field (default 0)\n")
+        fmt.Fprintf(os.Stderr, "  -type <name>       Count only records whose -type-field is name; others are tallied in\n")
+        fmt.Fprintf(os.Stderr, "                     their own section instead of being treated as errors\n")
+        fmt.Fprintf(os.Stderr, "  -type-field <name> Field holding the event type (default \"type\")\n")
+        fmt.Fprintf(os.Stderr, "  -by-type           Count every type and add a month by type breakdown of filtered events\n")
+        fmt.Fprintf(os.Stderr, "  -type-missing <p>  Records without the type field: include (default; they match -type),\n")
+        fmt.Fprintf(os.Stderr, "                     exclude, or own-bucket (a \"(none)\" type of their own)\n")
+        fmt.Fprintf(os.Stderr, "  -humanize          Abbreviate large summary counts with unit suffixes (1.2K, 3.4M)\n")
+        fmt.Fprintf(os.Stderr, "  -silent            Print no report, notes or warnings, only errors on stderr; the exit\n")
+        fmt.Fprintf(os.Stderr, "                     status is 0 OK, 1 WARN, 2 CRIT, or an error's status (see Errors)\n")
+        fmt.Fprintf(os.Stderr, "  -telemetry         Report each active aggregation's buckets, approximate bytes and records\n")
+        fmt.Fprintf(os.Stderr, "  -plugin <path.so>  Load a custom aggregator built with -buildmode=plugin; it sees every\n")
+        fmt.Fprintf(os.Stderr, "                     filtered event and adds a section. Repeatable; needs a cgo build on\n")
+        fmt.Fprintf(os.Stderr, "                     Linux, macOS or FreeBSD\n")
+        fmt.Fprintf(os.Stderr, "  -v                 Log diagnostics, such as -max-output-bytes decisions, to stderr\n")
+        fmt.Fprintf(os.Stderr, "  -date-style <s>    Date labels: us (Mar 14, 2025; default), iso (2025-03-14) or eu (14 Mar 2025).\n")
+        fmt.Fprintf(os.Stderr, "                     JSON, CSV and TSV output always use ISO\n")
+        fmt.Fprintf(os.Stderr, "  -report <name>     Additional report; repeat or comma-separate. Available:\n")
+        fmt.Fprintf(os.Stderr, "                       parent-id-distribution  histogram of events per ParentID\n")
+        fmt.Fprintf(os.Stderr, "                       event-rate-trend        with -y: least-squares trend of monthly counts\n")
+        fmt.Fprintf(os.Stderr, "                       forecast=<N>m           project the next N months from the monthly trend\n")
+        fmt.Fprintf(os.Stderr, "                       coverage                with -y [-m]: share of days and ISO weeks with events\n")
+        fmt.Fprintf(os.Stderr, "                       rolling-7d-percentile=<P>  daily count with the P-th percentile of the prior 7 days\n")
+        fmt.Fprintf(os.Stderr, "                       event-density           with -y [-m]: events per active day against a uniform spread\n")
+        fmt.Fprintf(os.Stderr, "                       leader-tenure           current and longest run of active days per leader\n")
+        fmt.Fprintf(os.Stderr, "                       leader-health-score     per leader: day coverage x 0.5 + 1/(1 + CV of daily counts) x 0.5\n")
+        fmt.Fprintf(os.Stderr, "                       parent-id-histogram-by-leader  per leader: ParentIDs with 1, 2-5, 6-20 and 21+ events\n")
+        fmt.Fprintf(os.Stderr, "                       weekly-leader-transition  with -y: ISO weeks whose busiest leader differs from the week before\n")
+        fmt.Fprintf(os.Stderr, "                       daily-top-leaders       with -y -m: each day's busiest leader and its count\n")
+        fmt.Fprintf(os.Stderr, "                       event-count-parity=<N>  leaders whose filtered count is more than N%% from the leaders' mean (default 10)\n")
+        fmt.Fprintf(os.Stderr, "                       monthly-leader-new-arrivals  with -y: leaders with their first event in each month\n")
+        fmt.Fprintf(os.Stderr, "                       monthly-leader-departures    with -y: leaders with their last event of the year in each month\n")
+        fmt.Fprintf(os.Stderr, "                       parent-lifecycle        first and last event and lifespan of each ParentID\n")
+        fmt.Fprintf(os.Stderr, "                       parent-event-rate       events per day over each ParentID's first-to-last span\n")
+        fmt.Fprintf(os.Stderr, "                       event-overlap-window=<W>  windows of length W with events of -min-parents\n")
+        fmt.Fprintf(os.Stderr, "                                               distinct ParentIDs, merged when they overlap (default 60s)\n")
+        fmt.Fprintf(os.Stderr, "                       event-size-stats        total, average, P50/P95/P99 and max of the \"size\" field (bytes) per month\n")
+        fmt.Fprintf(os.Stderr, "                       month-rank-history      with -m: rank of that month in each year among all years, by events\n")
+        fmt.Fprintf(os.Stderr, "                       percentile-ladder       P10-P99 of filtered events per day (-m), week (-y) or month, one line\n")
+        fmt.Fprintf(os.Stderr, "                       full-year-weekly-grid   with -y: every ISO week's count in a 4-column grid (2 if $COLUMNS is narrow)\n")
+        fmt.Fprintf(os.Stderr, "                       weekday-leader-affinity  events per weekday for the 5 busiest leaders\n")
+        fmt.Fprintf(os.Stderr, "                       monthly-summary-json    only a one-line JSON object of counts per month [-y]\n")
+        fmt.Fprintf(os.Stderr, "                       monthly-percentile-calendar  with -y [-m]: total, P50 and P95 of daily counts\n")
+        fmt.Fprintf(os.Stderr, "                       inverted-index          leaders active on each matching day\n")
+        fmt.Fprintf(os.Stderr, "                       parent-child-matrix     top 20 ParentID pairs active in the same clock hour\n")
+        fmt.Fprintf(os.Stderr, "                       daily-change-rate=<N>   day-over-day delta and change, flagging |delta| > N (default 10)\n")
+        fmt.Fprintf(os.Stderr, "                       leader-event-share=<N>  per-month share of events for the N busiest leaders (default 5)\n")
+        fmt.Fprintf(os.Stderr, "                       event-frequency-table=<day|week|month>  how many periods had exactly K events (default month)\n")
+        fmt.Fprintf(os.Stderr, "                       inactive-parents=<N>    ParentIDs silent for more than N days before the latest event (default 7)\n")
+        fmt.Fprintf(os.Stderr, "                       event-burst-sequence=<K>  runs of consecutive days above mean + K standard deviations (default 2)\n")
+        fmt.Fprintf(os.Stderr, "                       event-spike-context=<N>  the N days either side of each -spike day, with the delta to it (default 5)\n")
+        fmt.Fprintf(os.Stderr, "                       top-n-parents-by-month=<N>  with -y [-m]: the N busiest ParentIDs of each month (default 3)\n")
+        fmt.Fprintf(os.Stderr, "                       parent-transitions      months in which a ParentID's most frequent leader changed\n")
+        fmt.Fprintf(os.Stderr, "                       event-metadata-summary  distinct values and min/max or shortest/longest of each field\n")
+        fmt.Fprintf(os.Stderr, "                       stats=<day|parent>      P50/P90/P99 of events per day, or per ParentID and day\n")
+        fmt.Fprintf(os.Stderr, "                       leader-cooccurrence     top 20 leader pairs active in the same clock hour\n")
+        fmt.Fprintf(os.Stderr, "                       leader-event-gap        each leader's longest gap between consecutive events\n")
+        fmt.Fprintf(os.Stderr, "                       day-of-month-heatmap    with -y [-m]: average events on each day 1-31 across the months\n")
+        fmt.Fprintf(os.Stderr, "                       week-completeness       with -y: days with events in each ISO week, complete if all 7\n")
+        fmt.Fprintf(os.Stderr, "                       event-hour-of-week      24 x 7 heatmap of events by hour and weekday\n")
+        fmt.Fprintf(os.Stderr, "                       top-hours=<N>           the N busiest hours of day, with share and daily average (default 10)\n")
+        fmt.Fprintf(os.Stderr, "  -parent-dist-buckets <spec>\n")
+        fmt.Fprintf(os.Stderr, "                     Buckets for parent-id-distribution (default \"1,2,3-10,11-100,101+\")\n")
+        fmt.Fprintf(os.Stderr, "  -post <url>        POST the filtered counts per bucket to a collector as NDJSON records\n")
+        fmt.Fprintf(os.Stderr, "                     {\"bucket\", \"granularity\", \"count\"} in gzip'd batches; 5xx responses\n")
+        fmt.Fprintf(os.Stderr, "                     are retried with exponential backoff, 4xx responses are fatal\n")
+        fmt.Fprintf(os.Stderr, "  -post-granularity <g>\n")
+        fmt.Fprintf(os.Stderr, "                     With -post: day (default), week or month\n")
+        fmt.Fprintf(os.Stderr, "  -post-batch <n>    With -post: records per request (default 500)\n")
+        fmt.Fprintf(os.Stderr, "  -post-token-env <name>\n")
+        fmt.Fprintf(os.Stderr, "                     With -post: environment variable holding the bearer token\n")
+        fmt.Fprintf(os.Stderr, "                     (default PARTITION_GROWTH_POST_TOKEN; unset sends no token)\n")
+        fmt.Fprintf(os.Stderr, "  -post-retries <n>  With -post: retries per batch, doubling the delay from 1s (default 5)\n")
+        fmt.Fprintf(os.Stderr, "  -post-dry-run      With -post: print the first batch after the report instead of sending\n")
+        fmt.Fprintf(os.Stderr, "  -enrich-cmd <path>  Start path once and send it each event passing the date filters as an\n")
+        fmt.Fprintf(os.Stderr, "                     NDJSON line (the -dump form) on stdin, in batches; it must answer one\n")
+        fmt.Fprintf(os.Stderr, "                     JSON object line per event, in order, whose other members become\n")
+        fmt.Fprintf(os.Stderr, "                     fields enrich.<name> for -where and -group-by. Any failure stops the run\n")
+        fmt.Fprintf(os.Stderr, "  -enrich-batch <n>  With -enrich-cmd: events per batch (default 1000)\n")
+        fmt.Fprintf(os.Stderr, "  -enrich-timeout <d>\n")
+        fmt.Fprintf(os.Stderr, "                     With -enrich-cmd: time to answer a batch or exit (default 30s)\n")
+        fmt.Fprintf(os.Stderr, "  -dump-daily <path>  Write \"date,count\" CSV rows for every day from the first to the last event,\n")
+        fmt.Fprintf(os.Stderr, "                     zero-filled, to path (- for stdout); counts ignore -y/-m/-d\n")
+        fmt.Fprintf(os.Stderr, "  -dump-daily-filtered\n")
+        fmt.Fprintf(os.Stderr, "                     With -dump-daily: count only events passing -y/-m/-d\n")
+        fmt.Fprintf(os.Stderr, "  -dump <path>       Write the events passing the report filters as canonical JSON lines\n")
+        fmt.Fprintf(os.Stderr, "                     (- for stdout); <path>.meta.json records the filters used\n")
+        fmt.Fprintf(os.Stderr, "  -dump-where <expr> With -dump: dump only events matching expr (same syntax as -where);\n")
+        fmt.Fprintf(os.Stderr, "                     the reports are unaffected\n")
+        fmt.Fprintf(os.Stderr, "  -show-samples <n>  After the report, print n counted events picked uniformly at random with\n")
+        fmt.Fprintf(os.Stderr, "                     their timestamp and day, ISO week and month buckets (O(n) memory)\n")
+        fmt.Fprintf(os.Stderr, "  -seed <n>          With -show-samples: seed of the sample; the default 0 picks one and\n")
+        fmt.Fprintf(os.Stderr, "                     prints it. The same seed, input and -workers 1 give the same sample\n")
+        fmt.Fprintf(os.Stderr, "  -samples-in-json   With -show-samples: include them in -output json, which leaves them out\n")
+        fmt.Fprintf(os.Stderr, "                     by default as they hold raw records\n")
+        fmt.Fprintf(os.Stderr, "  -trace-record <expr>\n")
+        fmt.Fprintf(os.Stderr, "                     For records matching expr (same syntax as -where), print the raw JSON,\n")
+        fmt.Fprintf(os.Stderr, "                     parsed and -tz timestamps, each filter's outcome and the buckets counted\n")
+        fmt.Fprintf(os.Stderr, "  -trace-limit <n>   With -trace-record: maximum records traced (default 10)\n")
+        fmt.Fprintf(os.Stderr, "  -trace-out <path>  With -trace-record: write traces to path instead of stderr\n")
+        fmt.Fprintf(os.Stderr, "  -max-day-leaders <n>\n")
+        fmt.Fprintf(os.Stderr, "                     With inverted-index: distinct leaders kept per day (default 50)\n")
+        fmt.Fprintf(os.Stderr, "  -min-gap <dur>     With leader-event-gap: hide leaders whose longest gap is shorter (e.g. 1h, 2d)\n")
+        fmt.Fprintf(os.Stderr, "  -min-parents <n>   With event-overlap-window: distinct ParentIDs a window needs (default 3)\n")
+        fmt.Fprintf(os.Stderr, "  -spike <K>         With event-spike-context: days above mean + K standard deviations of the\n")
+        fmt.Fprintf(os.Stderr, "                     filtered daily counts are spikes (default 2)\n")
+        fmt.Fprintf(os.Stderr, "  -min-parent-events <n>\n")
+        fmt.Fprintf(os.Stderr, "                     With parent-lifecycle or parent-event-rate: only parents with at least\n")
+        fmt.Fprintf(os.Stderr, "                     n events (default 1 and 10)\n")
+        fmt.Fprintf(os.Stderr, "  -segments          List active segments of filtered events separated by quiet gaps\n")
+        fmt.Fprintf(os.Stderr, "  -gap <duration>    With -segments: gap that ends a segment (default 6h)\n")
+        fmt.Fprintf(os.Stderr, "  -max-segments <n>  With -segments: maximum segments listed (default 50)\n")
+        fmt.Fprintf(os.Stderr, "  -sort-mem <n>      Events ordered reports sort in memory before spilling (default 1000000)\n")
+        fmt.Fprintf(os.Stderr, "  -spill-dir <dir>   Directory for spill files (default $TMPDIR)\n")
+        fmt.Fprintf(os.Stderr, "  -spill-limit <n>   Abort when spill files would exceed n bytes (default unlimited)\n")
+        fmt.Fprintf(os.Stderr, "  -group-by <dim>    Top groups of filtered events by parent, leader or enrich.<name>\n")
+        fmt.Fprintf(os.Stderr, "  -group-top <n>     With -group-by: number of groups listed (default 20)\n")
+        fmt.Fprintf(os.Stderr, "  -movers <dim>      parent or leader: top increases and decreases in filtered events between\n")
+        fmt.Fprintf(os.Stderr, "                     the -periods months; a value absent from one month moves from or to zero\n")
+        fmt.Fprintf(os.Stderr, "  -periods <a,b>     With -movers: two months, e.g. 2025-02,2025-03 (default: the two complete\n")
+        fmt.Fprintf(os.Stderr, "                     months before the latest event's month, found by reading the inputs twice)\n")
+        fmt.Fprintf(os.Stderr, "  -movers-top <n>    With -movers: values listed in each direction (default 10)\n")
+        fmt.Fprintf(os.Stderr, "  -sort <order>      Row order of the -group-by and -top-detail listings: key, count, or\n")
+        fmt.Fprintf(os.Stderr, "                     -key, -count (descending; default -count). Rows are still the top ones\n")
+        fmt.Fprintf(os.Stderr, "                     by count; ties on count fall back to keys ascending\n")
+        fmt.Fprintf(os.Stderr, "  -approx            With -group-by: approximate top-K in bounded memory above -approx-threshold\n")
+        fmt.Fprintf(os.Stderr, "  -stats-exact <n>   With stats: days (or ParentID-days) counted exactly before older ones\n")
+        fmt.Fprintf(os.Stderr, "                     are summarized in a t-digest and quantiles become approximate (default 100000)\n")
+        fmt.Fprintf(os.Stderr, "  -approx-threshold <n>\n")
+        fmt.Fprintf(os.Stderr, "                     Distinct values counted exactly before -approx takes over (default 100000)\n")
+        fmt.Fprintf(os.Stderr, "  -aggregate <fns>   Aggregate the event \"value\" field per day (-y -m), month (-y) or year:\n")
+        fmt.Fprintf(os.Stderr, "                     comma-separated count,sum,max,min\n")
+        fmt.Fprintf(os.Stderr, "  -leader-churn      With -y: per-month distinct, new and returning leaders\n")
+        fmt.Fprintf(os.Stderr, "  -age-histogram     Filtered events by age at -asof (now by default) with the p95 age, to\n")
+        fmt.Fprintf(os.Stderr, "                     see whether exports keep up; events dated after -asof are counted apart\n")
+        fmt.Fprintf(os.Stderr, "  -age-buckets <list>\n")
+        fmt.Fprintf(os.Stderr, "                     With -age-histogram: bucket boundaries (default \"1h,6h,24h,7d\")\n")
+        fmt.Fprintf(os.Stderr, "  -week-percentiles  p50/p90/p99/max of events per ISO week across all years, counting the\n")
+        fmt.Fprintf(os.Stderr, "                     empty weeks between the first and last event; names the p99 and max weeks\n")
+        fmt.Fprintf(os.Stderr, "  -retention         With -y: cohorts of ParentIDs by first active month and the share active\n")
+        fmt.Fprintf(os.Stderr, "                     1, 2, 3... months later\n")
+        fmt.Fprintf(os.Stderr, "  -min-cohort <n>    With -retention: hide cohorts smaller than n parents (default 5)\n")
+        fmt.Fprintf(os.Stderr, "  -retention-csv <path>\n")
+        fmt.Fprintf(os.Stderr, "                     With -retention: also write the cohort table as CSV\n")
+        fmt.Fprintf(os.Stderr, "\nChecks (exit status 0 OK, 1 WARN, 2 CRIT; durations accept a day unit, e.g. 14d):\n")
+        fmt.Fprintf(os.Stderr, "  -asof <date>       Reference time for checks (default now)\n")
+        fmt.Fprintf(os.Stderr, "  -leader-freshness <dur>\n")
+        fmt.Fprintf(os.Stderr, "                     WARN for each leader with no event in this window before -asof\n")
+        fmt.Fprintf(os.Stderr, "  -leader-freshness-crit <dur>\n")
+        fmt.Fprintf(os.Stderr, "                     CRIT once a leader has been silent this long (default off)\n")
+        fmt.Fprintf(os.Stderr, "  -freshness-lookback <dur>\n")
+        fmt.Fprintf(os.Stderr, "                     Only leaders seen within this window before -asof are checked (default 14d)\n")
+        fmt.Fprintf(os.Stderr, "  -ignore-leader <glob>\n")
+        fmt.Fprintf(os.Stderr, "                     Skip matching leaders, e.g. decommissioned nodes; repeatable\n")
+        fmt.Fprintf(os.Stderr, "\nErrors (printed as \"error [CODE]: ...\", or JSON lines on stderr with -output json;\n")
+        fmt.Fprintf(os.Stderr, "a fatal error exits with its code's status):\n")
+        for _, e := range errorCodes {
+            fmt.Fprintf(os.Stderr, "  %-14s %3d  %s\n", e.code, e.exit, e.desc)
+        }
     }
 
-    flag.Parse()
+    args, err := expandPresets(flag.CommandLine, os.Args[1:])
+    if err != nil {
+        fail(codeUsage, "%v", err)
+    }
+    // Parse errors exit with E_USAGE's status rather than flag's 2, which is
+    // CRIT for the checks.
+    flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+    if err := flag.CommandLine.Parse(args); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            os.Exit(0)
+        }
+        os.Exit(codeUsage.exitStatus())
+    }
+    errorsJSON = *outputFormat == "json"
 
-    if *filePath == "" {
-        fmt.Fprintln(os.Stderr, "error: -f is required")
+    if len(inputs) == 0 && stdinPiped() {
+        inputs = append(inputs, stdinInput)
+    }
+    if len(inputs) == 0 {
+        logError(codeUsage, "-f is required")
         flag.Usage()
-        os.Exit(1)
+        os.Exit(codeUsage.exitStatus())
     }
 
-    file, err := os.Open(*filePath)
+    // -silent: from here on the report and notes are discarded; errors
+    // still reach stderr beside the exit status.
+    if *silent {
+        reportOut, noteOut = io.Discard, io.Discard
+    }
+
+    if !validOutputFormat(*outputFormat) {
+        fail(codeUsage, "unknown -output format %q (available: %s)", *outputFormat, strings.Join(outputFormats, ", "))
+    }
+    if *weightField != "" && *withWeight != "" {
+        fail(codeUsage, "use either -weight-field or -with-weight")
+    }
+    if *rankBy != "count" && *rankBy != "weight" {
+        fail(codeUsage, "unknown -rank-by %q (want count or weight)", *rankBy)
+    }
+    if *rankBy == "weight" && *withWeight == "" {
+        fail(codeUsage, "-rank-by weight requires -with-weight")
+    }
+    midnight, err := newMidnightPolicy(*midnightFlag)
+    if err != nil {
+        fail(codeUsage, "%v", err)
+    }
+    offsets, err := newISOOffsets(*isoOffsetsFlag)
+    if err != nil {
+        fail(codeUsage, "%v", err)
+    }
+    types, err := newTypeFilter(*typeField, *typeWant, *typeMissing, *byType)
     if err != nil {
-        fmt.Fprintf(os.Stderr, "error opening file %s: %v\n", *filePath, err)
-        os.Exit(1)
+        fail(codeUsage, "%v", err)
+    }
+    plugins, err := loadPlugins(pluginPaths)
+    if err != nil {
+        fail(codeExternal, "-plugin: %v", err)
+    }
+    var order *orderCheck
+    var streamer *periodStreamer
+    if *streamingOutput {
+        if *outputFormat != "text" {
+            fail(codeUsage, "-streaming-output writes text; it cannot be combined with -output %s", *outputFormat)
+        }
+        if _, ok := streamPeriods[*streamPeriod]; !ok {
+            fail(codeUsage, "unknown -stream-period %q (want day, week, month or year)", *streamPeriod)
+        }
+        streamer = newPeriodStreamer(reportOut, *streamPeriod)
+        *assertSorted = true
+    }
+    if *assertSorted {
+        if *workers > 1 {
+            fail(codeUsage, "-assert-sorted and -streaming-output need -workers 1 to see events in input order")
+        }
+        order = &orderCheck{}
     }
-    defer file.Close()
 
-    decoder := json.NewDecoder(bufio.NewReader(file))
-    token, err := decoder.Token()
+    var saved *reportDir
+    if *reportDirFlag != "" {
+        var err error
+        if saved, err = newReportDir(*reportDirFlag, *keepReports, started); err != nil {
+            fail(codeOutput, "-report-dir: %v", err)
+        }
+    }
+    if *idMin > *idMax {
+        fail(codeUsage, "-id-min is above -id-max")
+    }
+    if reportFlags.has("event-overlap-window") && *minOverlapParents < 1 {
+        fail(codeUsage, "-min-parents must be at least 1")
+    }
+    spikeSigma, err := parseBurstSigma(*spikeFlag)
     if err != nil {
-        fmt.Fprintf(os.Stderr, "error reading JSON: %v\n", err)
-        os.Exit(1)
+        fail(codeUsage, "-spike: %v", err)
+    }
+    if *outputFormat == "line" && (*dumpPath == "-" || *dumpDaily == "-" || *postDryRun || reportFlags.has("monthly-summary-json")) {
+        fail(codeUsage, "-output line keeps stdout for its one line; -dump -, -dump-daily -, -post-dry-run and monthly-summary-json write there too")
+    }
+    if *outputFormat == "dot-timeline" && !validPostGranularity(*dotPeriod) {
+        fail(codeUsage, "unknown -dot-period %q (want day, week or month)", *dotPeriod)
+    }
+
+    if !validDateStyle(*dateStyleFlag) {
+        fail(codeUsage, "unknown -date-style %q (available: %s)", *dateStyleFlag, strings.Join(dateStyles, ", "))
+    }
+    dates := dateStyle(*dateStyleFlag)
+    if *outputFormat == "json" || *outputFormat == "csv" || *outputFormat == "tsv" {
+        dates = dateISO
+    }
+
+    loc, err := time.LoadLocation(*tz)
+    if err != nil {
+        fail(codeUsage, "-tz: %v", err)
+    }
+    // readLoc interprets zone-less input dates; loc draws the bucket
+    // boundaries. They differ only under -date-trunc-offset.
+    readLoc := loc
+    if *truncOffset != "" {
+        if loc, err = parseTruncOffset(*truncOffset); err != nil {
+            fail(codeUsage, "-date-trunc-offset: %v", err)
+        }
+    }
+
+    assume, err := parseAssumeYear(*assumeYearFlag)
+    if err != nil {
+        fail(codeUsage, "-assume-year: %v", err)
+    }
+    if *twoDigitPivot < 0 {
+        fail(codeUsage, "-two-digit-year-pivot must be a year")
+    }
+    rng, err := resolveRange(*fromFlag, *toFlag, *toExclusive, loc)
+    if err != nil {
+        fail(codeUsage, "%v", err)
+    }
+    // A day none of the selected months has (-y 2025 -m 2 -d 29, or -d 31
+    // over -from 2025-04-01 -to 2025-04-30) can only be a typo.
+    if *day != 0 && *month >= 0 && *month <= 12 {
+        if most := maxDaysSelected(*year, *month, rng, loc); *day < 1 || *day > most {
+            fail(codeUsage, "-d %d: the selected months have days 1 to %d", *day, most)
+        }
+    }
+
+    asof := time.Now().In(loc)
+    if *asofFlag != "" {
+        if asof, err = events.ParseDateIn(*asofFlag, loc); err != nil {
+            fail(codeUsage, "-asof: %v", err)
+        }
+    }
+    var ages *ageHistogram
+    if *ageHist {
+        bounds, err := parseAgeBuckets(*ageBuckets)
+        if err != nil {
+            fail(codeUsage, "-age-buckets: %v", err)
+        }
+        ages = newAgeHistogram(asof, bounds)
+    }
+
+    rowOrder, err := parseSortOrder(*sortFlag)
+    if err != nil {
+        fail(codeUsage, "%v", err)
+    }
+    if *sortFlag != "" {
+        if len(reportFlags) > 0 {
+            fail(codeUsage, "-sort does not apply to -report=%s; it orders the %s listings",
+                reportFlags[0].Name, strings.Join(sortListings, " and "))
+        }
+        if *groupBy == "" && *topDetailFlag == "" {
+            fail(codeUsage, "-sort orders the %s listings; set one of them", strings.Join(sortListings, " and "))
+        }
+    }
+    agg := newAggregates(reportOptions{
+        loc:               loc,
+        dates:             dates,
+        year:              *year,
+        month:             *month,
+        day:               *day,
+        parentDistBuckets: *parentDistBuckets,
+        minParentEvents:   *minParentEvents,
+        minCohort:         *minCohort,
+        maxDayLeaders:     *maxDayLeaders,
+        asof:              asof,
+        freshness:         time.Duration(leaderFreshness),
+        freshnessCrit:     time.Duration(leaderFreshnessCrit),
+        freshnessLookback: time.Duration(freshnessLookback),
+        ignoreLeaders:     ignoreLeaders,
+        gap:               *gap,
+        minGap:            time.Duration(minGap),
+        overlapParents:    *minOverlapParents,
+        spikeSigma:        spikeSigma,
+        maxSegments:       *maxSegments,
+        spillDir:          *spillDir,
+        sortMem:           *sortMem,
+        spillLimit:        *spillLimit,
+        groupTop:          *groupTop,
+        sort:              rowOrder,
+        statsExact:        *statsExact,
+    })
+    if *aggregate != "" {
+        fns, err := parseAggregateFuncs(*aggregate)
+        if err != nil {
+            fail(codeUsage, "-aggregate: %v", err)
+        }
+        agg.opts.aggregates = fns
+        agg.values = make(map[string]valueStats)
+    }
+    if *segments {
+        if *gap <= 0 {
+            fail(codeUsage, "-gap must be positive")
+        }
+        prepareSegments(agg)
+    }
+    if *groupBy != "" {
+        _, enriched := enrichField(*groupBy)
+        if !validGroupDimension(*groupBy) && !enriched {
+            fail(codeUsage, "unknown -group-by %q (available: %s, enrich.<name>)", *groupBy, strings.Join(groupDimensions, ", "))
+        }
+        if enriched && *enrichCmd == "" {
+            fail(codeUsage, "-group-by enrich.<name> requires -enrich-cmd")
+        }
+        if *groupTop < 1 {
+            fail(codeUsage, "-group-top must be at least 1")
+        }
+        threshold := 0
+        if *approx {
+            if *approxThreshold < *groupTop {
+                fail(codeUsage, "-approx-threshold must be at least -group-top")
+            }
+            threshold = *approxThreshold
+        }
+        agg.groups = newGroupCounter(*groupBy, threshold)
+    }
+    var detail *topDetail
+    if *topDetailFlag != "" {
+        dim := strings.TrimSuffix(*topDetailFlag, "s")
+        if !validGroupDimension(dim) {
+            fail(codeUsage, "unknown -top-detail %q (want parents or leaders)", *topDetailFlag)
+        }
+        if !*top || *year == 0 {
+            fail(codeUsage, "-top-detail requires -t and -y")
+        }
+        threshold := 0
+        if *approx {
+            threshold = *approxThreshold
+        }
+        detail = newTopDetail(dim, *year, threshold, rowOrder)
+    }
+    if *allYearsFiltered {
+        *allYears = true
+    }
+    var post *poster
+    if *postURL != "" {
+        if !validPostGranularity(*postGranularity) {
+            fail(codeUsage, "unknown -post-granularity %q (want %s)", *postGranularity, strings.Join(postGranularities, ", "))
+        }
+        if *postBatch < 1 || *postRetries < 0 {
+            fail(codeUsage, "-post-batch must be at least 1 and -post-retries not negative")
+        }
+        post = newPoster(*postURL, os.Getenv(*postTokenEnv), *postBatch, *postRetries)
+    } else if *postDryRun {
+        fail(codeUsage, "-post-dry-run requires -post")
+    }
+    if dumpWhere.expr.String() != "" && *dumpPath == "" {
+        fail(codeUsage, "-dump-where requires -dump")
+    }
+    var dump *eventDump
+    if *dumpPath != "" {
+        var err error
+        if dump, err = openEventDump(*dumpPath, dumpWhere.expr); err != nil {
+            fail(codeOutput, "-dump: %v", err)
+        }
+    }
+    if *showSamples < 0 {
+        fail(codeUsage, "-show-samples must not be negative")
+    }
+    var samples *reservoir
+    if *showSamples > 0 {
+        samples = newReservoir(*showSamples, *seed)
+    }
+    var tr *tracer
+    if traceRecord.expr.String() != "" {
+        tr = &tracer{expr: traceRecord.expr, limit: *traceLimit, w: noteOut, read: readLoc, loc: loc, midnight: midnight, offsets: offsets, dump: dump}
+        if *traceOut != "" {
+            f, err := os.Create(*traceOut)
+            if err != nil {
+                fail(codeOutput, "-trace-out: %v", err)
+            }
+            defer f.Close()
+            tr.w = f
+        }
+        if rng.active() {
+            tr.filters = append(tr.filters, traceFilter{"-from/-to", func(t time.Time, _ Event) bool { return rng.contains(t) }})
+        }
+        if *year != 0 {
+            tr.filters = append(tr.filters, traceFilter{fmt.Sprintf("-y %d", *year), func(t time.Time, _ Event) bool { return t.Year() == *year }})
+        }
+        if *month != 0 {
+            tr.filters = append(tr.filters, traceFilter{fmt.Sprintf("-m %d", *month), func(t time.Time, _ Event) bool { return int(t.Month()) == *month }})
+        }
+        if *day != 0 {
+            tr.filters = append(tr.filters, traceFilter{fmt.Sprintf("-d %d", *day), func(t time.Time, _ Event) bool { return t.Day() == *day }})
+        }
+        if where.expr.String() != "" {
+            tr.filters = append(tr.filters, traceFilter{"-where", where.expr.matches})
+        }
+    }
+    if *leaderChurn {
+        if *year == 0 {
+            fail(codeUsage, "-leader-churn requires -y")
+        }
+        agg.enableMonthLeaders()
+    }
+    if *retention {
+        if *year == 0 {
+            fail(codeUsage, "-retention requires -y")
+        }
+        if *minCohort < 1 {
+            fail(codeUsage, "-min-cohort must be at least 1")
+        }
+        agg.enableParentMonths()
+    }
+    if leaderFreshness > 0 {
+        if leaderFreshnessCrit > 0 && leaderFreshnessCrit < leaderFreshness {
+            fail(codeUsage, "-leader-freshness-crit must not be shorter than -leader-freshness")
+        }
+        if freshnessLookback <= 0 {
+            fail(codeUsage, "-freshness-lookback must be positive")
+        }
+        agg.enableLeaderLastSeen()
+    }
+    for _, r := range reportFlags {
+        if err := reports[r.Name].prepare(agg, r.Arg); err != nil {
+            fail(codeUsage, "-report=%s: %v", r.Name, err)
+        }
+    }
+
+    var fetch *httpInput
+    resolve := resolveInputs
+    switch *inputFormat {
+    case "file":
+    case "http":
+        if *httpRetries < 0 || *httpBackoffMax <= 0 {
+            fail(codeUsage, "-http-retries must not be negative and -http-backoff-max positive")
+        }
+        fetch = newHTTPInput(*httpRetries, *httpBackoffMax)
+        resolve = resolveURLs
+    default:
+        fail(codeUsage, "unknown -input-format %q (want %s)", *inputFormat, strings.Join(inputFormats, " or "))
+    }
+    files, dups, err := resolve(inputs, *allowDuplicates)
+    if err != nil {
+        fail(codeOf(err, codeUsage), "%v", err)
+    }
+    for _, d := range dups {
+        fmt.Fprintf(noteOut, "note: skipping %s, same file as %s (use -allow-duplicates to read it again)\n", d.Path, d.SameAs)
+    }
+    if *moversDim != "" {
+        if *moversDim != "parent" && *moversDim != "leader" {
+            fail(codeUsage, "unknown -movers %q (want parent or leader)", *moversDim)
+        }
+        if *moversTop < 1 {
+            fail(codeUsage, "-movers-top must be at least 1")
+        }
+        var periods [2]string
+        if *periodsFlag != "" {
+            periods, err = parsePeriods(*periodsFlag)
+        } else if fetch != nil {
+            fail(codeUsage, "-movers: http inputs cannot be pre-scanned; give -periods")
+        } else {
+            periods, err = latestMonths(files, readLoc, loc, func(path string) events.YearOptions {
+                opts := events.YearOptions{TwoDigitPivot: *twoDigitPivot}
+                opts.AssumeYear, _ = assume.yearFor(path)
+                return opts
+            })
+        }
+        if err != nil {
+            fail(codeOf(err, codeUsage), "-periods: %v", err)
+        }
+        logf("movers: periods %s and %s", periods[0], periods[1])
+        agg.movers = newMovers(*moversDim, *moversTop, periods)
+    } else if *periodsFlag != "" {
+        fail(codeUsage, "-periods requires -movers")
     }
 
     // Aggregation maps
-    perDay := make(map[string]int)
-    perWeek := make(map[string]int)
-    perMonth := make(map[string]int)
-    perYear := make(map[int]int)
-    perQuarter := make(map[string]int) // "YYYY-QN"
+    perDay := agg.perDay
+    perWeek := agg.perWeek
+    perMonth := agg.perMonth
+    perYear := agg.perYear
+    perQuarter := agg.perQuarter
     var allDates []time.Time
+    var allWeights []int // with -weight-field, parallel to allDates
     totalEvents := 0
 
     // Additional aggregations for conditional reporting
@@ -94,9 +912,17 @@ func main() {
     selectedMonth := *month
     selectedYear := *year
 
-    perISOWeekAll := make(map[string]int) // key: "YYYY-Www" using ISO week-year, always collected
+    perISOWeekAll := agg.perISOWeekAll
+
+    var filteredRollup *rollup // -a-filtered
+    if *allYearsFiltered {
+        filteredRollup = newRollup()
+    }
 
     shouldInclude := func(t time.Time) bool {
+        if !rng.contains(t) {
+            return false
+        }
         if *year != 0 && t.Year() != *year {
             return false
         }
@@ -109,134 +935,507 @@ func main() {
         return true
     }
 
-    layout := "Jan 2, 2006, 3:04:05 PM"
+    // years counts all events per year without locking; every other
+    // aggregation is updated under mu so that -workers may run processEvent
+    // concurrently.
+    var years yearCounter
+    var mu sync.Mutex
+
+    var inferred yearInference
+    weighting := newWeightTotals(*weightField, *withWeight, *weightDefault)
+    rejected := recordErrors{dropSelfRefs: *dropSelfRefs, dropBadIDs: *dropBadIDs, idMin: *idMin, idMax: *idMax}
 
-    processEvent := func(evt Event) {
-        dt, err := time.Parse(layout, evt.Date)
+    var enrich *enricher
+    if *enrichCmd != "" {
+        if *enrichBatch < 1 || *enrichTimeout <= 0 {
+            fail(codeUsage, "-enrich-batch must be at least 1 and -enrich-timeout positive")
+        }
+        enrich, err = startEnricher(*enrichCmd, *enrichBatch, *enrichTimeout)
         if err != nil {
-            fmt.Fprintf(os.Stderr, "error parsing date %q: %v\n", evt.Date, err)
-            return
+            fail(codeExternal, "-enrich-cmd: %v", err)
         }
+    } else if where.expr.enriched || dumpWhere.expr.enriched {
+        fail(codeUsage, "enrich.<name> fields in -where or -dump-where require -enrich-cmd")
+    }
+
+    var recordFiltered func(dt time.Time, k eventKeys, w, sw int, evt Event)
+
+    // recordEvent adds one parsed event to every aggregation.
+    recordEvent := func(dt time.Time, how events.Inference, evt Event) {
+        k := keysFor(dt)
+        isoWeekKey, dayKey, monthKey, qKey := k.ISOWeek, k.Day, k.Month, k.Quarter
+        if why := rejected.badID(evt); why != "" {
+            mu.Lock()
+            keep := rejected.countBadID(dt, why)
+            mu.Unlock()
+            if !keep {
+                return
+            }
+        }
+        if isSelfRef(evt) {
+            mu.Lock()
+            keep := rejected.selfRef(dt, evt)
+            mu.Unlock()
+            if !keep {
+                return
+            }
+        }
+        w, sw := evt.Weight, evt.Weight // count weight and -with-weight sum
+        if weighting.dual {
+            w, evt.Weight = 1, 1
+        }
+        inYearCounter := years.add(dt.Year(), w)
+
+        mu.Lock()
+        defer mu.Unlock()
+        if order != nil {
+            if err := order.check(dt); err != nil {
+                fail(codeUnsorted, "%v", err)
+            }
+        }
+        inferred.count(how)
         allDates = append(allDates, dt)
-        isoYear, isoWeek := dt.ISOWeek()
-        isoWeekKey := fmt.Sprintf("%04d-W%02d", isoYear, isoWeek)
-        perISOWeekAll[isoWeekKey]++
+        if weighting.field != "" {
+            weighting.addAll(sw, evt.HasWeight, k)
+            if !weighting.dual {
+                allWeights = append(allWeights, w)
+            }
+        }
+        agg.addAll(dt, evt)
+        if detail != nil {
+            detail.add(dt, k, evt)
+        }
+        perISOWeekAll[isoWeekKey] += w
+        agg.perDayAll[dayKey] += w
 
-        monthKey := dt.Format("2006-01")
-        perMonth[monthKey]++
-        perYear[dt.Year()]++
+        perMonth[monthKey] += w
+        if !inYearCounter {
+            perYear[dt.Year()] += w
+        }
         
-        qKey := fmt.Sprintf("%d-Q%d", dt.Year(), getQuarter(dt.Month()))
-        perQuarter[qKey]++
+        perQuarter[qKey] += w
 
         if !shouldInclude(dt) {
             return
         }
+        if enrich != nil {
+            batch, err := enrich.add(pendingEvent{dt, k, w, sw, evt})
+            if err != nil {
+                fail(codeExternal, "-enrich-cmd: %v", err)
+            }
+            for _, p := range batch {
+                recordFiltered(p.dt, p.k, p.w, p.sw, p.evt)
+            }
+            return
+        }
+        recordFiltered(dt, k, w, sw, evt)
+    }
 
-        dayKey := dt.Format("2006-01-02")
-        perDay[dayKey]++
+    // recordFiltered adds an event that passed the date filters to the
+    // filtered aggregations if it matches -where. It runs under mu.
+    recordFiltered = func(dt time.Time, k eventKeys, w, sw int, evt Event) {
+        if !where.expr.matches(dt, evt) {
+            return
+        }
+        dayKey, monthKey, weekKey := k.Day, k.Month, k.Week
+        if dump != nil {
+            dump.add(dt, evt)
+        }
+        if samples != nil {
+            samples.add(dt, k, evt)
+        }
+        if ages != nil {
+            ages.add(dt, w)
+        }
+        if streamer != nil {
+            streamer.add(dt, w)
+        }
+        if weighting.field != "" {
+            weighting.add(sw)
+        }
+
+        perDay[dayKey] += w
+        if filteredRollup != nil {
+            filteredRollup.add(dt, k, w, sw, &weighting)
+        }
+        agg.add(dt, evt)
+        if types.perMonth != nil {
+            types.add(monthKey, evt.Type, w)
+        }
+        for _, p := range plugins {
+            p.agg.Process(evt, dt)
+        }
 
         if selectedMonth != 0 && selectedYear != 0 &&
             int(dt.Month()) == selectedMonth && dt.Year() == selectedYear {
-            w := (dt.Day()-1)/7 + 1
-            monthWeekBuckets[w]++
-            monthTotal++
+            wk := (dt.Day()-1)/7 + 1
+            monthWeekBuckets[wk] += w
+            if weighting.dual {
+                weighting.side.monthWeeks[wk] += sw
+            }
+            monthTotal += w
         }
 
-        _, iw := dt.ISOWeek()
-        weekKey := fmt.Sprintf("%d-W%02d", dt.Year(), iw)
-        perWeek[weekKey]++
+        perWeek[weekKey] += w
 
-        totalEvents++
+        totalEvents += w
     }
 
-    if delim, ok := token.(json.Delim); ok && delim == '[' {
-        for decoder.More() {
-            var evt Event
-            if err := decoder.Decode(&evt); err != nil {
-                fmt.Fprintf(os.Stderr, "error decoding JSON element: %v\n", err)
-                os.Exit(1)
+    // With -resume each file's records are also collected for the journal.
+    var jr *journal
+    var journaled map[string]journalEntry
+    var collected []journalRecord
+    var cmu sync.Mutex
+
+    // placeDate parses evt's date into the bucket zone. A missing or
+    // unparseable date is counted and reported, and ok is false.
+    placeDate := func(evt Event, yopts events.YearOptions) (dt time.Time, how events.Inference, off int, zoned, atMidnight, moved, ok bool) {
+        dt, how, err := events.ParseDateYears(evt.Date, readLoc, yopts)
+        off, zoned = events.DateOffset(evt.Date)
+        dt, atMidnight, moved = midnight.place(dt, off, zoned, offsets.zone(off, zoned, loc))
+        mu.Lock()
+        ok = rejected.classify(dt, err)
+        if ok {
+            midnight.observe(atMidnight, moved)
+            offsets.observe(off)
+        }
+        mu.Unlock()
+        if !ok {
+            if err == nil || errors.Is(err, events.ErrMissingDate) {
+                logError(codeDateParse, "record without a date (parentId %d)", evt.ParentID)
+            } else {
+                logError(codeDateParse, "parsing date %q: %v", evt.Date, err)
             }
-            processEvent(evt)
         }
-        if _, err := decoder.Token(); err != nil {
-            fmt.Fprintf(os.Stderr, "error closing array: %v\n", err)
-            os.Exit(1)
+        return dt, how, off, zoned, atMidnight, moved, ok
+    }
+
+    processEvent := func(evt Event, yopts events.YearOptions) {
+        if types.field != "" {
+            label, ok := types.keep(evt)
+            if !ok {
+                mu.Lock()
+                types.skip(label)
+                mu.Unlock()
+                if jr != nil {
+                    cmu.Lock()
+                    collected = append(collected, journalRecord{Type: label, Skipped: true})
+                    cmu.Unlock()
+                }
+                return
+            }
+            evt.Type = label
         }
-    } else {
-        file.Seek(0, 0)
-        decoder = json.NewDecoder(bufio.NewReader(file))
-        for {
-            var evt Event
-            if err := decoder.Decode(&evt); err != nil {
-                if err.Error() == "EOF" {
-                    break
+        dt, how, off, zoned, atMidnight, moved, ok := placeDate(evt, yopts)
+        if !ok {
+            if jr != nil {
+                cmu.Lock()
+                collected = append(collected, journalRecord{orderedRecord: orderedRecord{Parent: evt.ParentID},
+                    Rejected: true, Date: evt.Date})
+                cmu.Unlock()
+            }
+            return
+        }
+        if weighting.field == "" {
+            evt.Weight = 1
+        } else if !evt.HasWeight {
+            evt.Weight = weighting.missingAs
+        }
+        recordEvent(dt, how, evt)
+        if jr != nil {
+            cmu.Lock()
+            collected = append(collected, journalRecord{orderedRecord{dt.UnixNano(), evt.ParentID, evt.FirstChildID,
+                evt.SecondChildID, evt.LeaderNodeInfo}, evt.Value, evt.Size, how, evt.Weight, evt.HasWeight, evt.Type, false, false, evt.Date, atMidnight, moved, evt.Overflow, int32(off), zoned})
+            cmu.Unlock()
+        }
+    }
+
+    if *resume != "" {
+        for _, path := range files {
+            if path == stdinInput || fetch != nil {
+                fail(codeUsage, "-resume: standard input and http inputs cannot be journaled; read from files")
+            }
+        }
+        settings := fmt.Sprintf("tz=%s/%s pivot=%d assume=%s weight=%s/%d type=%s/%s/%s midnight=%s offsets=%s", readLoc, loc,
+            *twoDigitPivot, *assumeYearFlag, weighting.field, weighting.missingAs, types.field, types.want, types.missing,
+            midnight.name, offsets.name)
+        var err error
+        jr, journaled, err = openJournal(*resume, inputFingerprint(files, settings))
+        if err != nil {
+            fail(codeIO, "-resume: %v", err)
+        }
+    }
+
+    for _, path := range files {
+        yopts := events.YearOptions{TwoDigitPivot: *twoDigitPivot}
+        var err error
+        if yopts.AssumeYear, err = assume.yearFor(path); err != nil {
+            fmt.Fprintf(noteOut, "warning: -assume-year: %v; dates without a year in it are errors\n", err)
+        }
+        if e, ok := journaled[path]; ok {
+            logf("resume: %s: %d records from journal", path, len(e.Records))
+            for _, r := range e.Records {
+                if r.Skipped {
+                    types.skip(r.Type)
+                    continue
+                }
+                if r.Rejected {
+                    placeDate(Event{ParentID: r.Parent, Date: r.Date}, yopts)
+                    continue
                 }
-                fmt.Fprintf(os.Stderr, "error decoding JSON object: %v\n", err)
-                os.Exit(1)
+                midnight.observe(r.Midnight, r.MidnightMoved)
+                offsets.observe(int(r.Offset))
+                evt, t := r.event()
+                recordEvent(t.In(offsets.zone(int(r.Offset), r.Zoned, loc)), r.How, evt)
+            }
+            continue
+        }
+        var file io.ReadCloser
+        var body *httpBody
+        if fetch != nil {
+            body, err = fetch.open(path)
+            file = body
+        } else {
+            file, err = openInput(path)
+        }
+        if err != nil {
+            fail(codeOf(err, codeInputOpen), "%v", err)
+        }
+        var in io.Reader = file
+        hash := sha256.New()
+        if jr != nil {
+            in = io.TeeReader(file, hash)
+            collected = collected[:0]
+        }
+        reader := events.NewReader(in, inputName(path))
+        if *progress {
+            reader.SetProgress(events.ProgressOptions{EveryRecords: 100000}, logProgress)
+        }
+        if tr != nil {
+            reader.SetKeepRaw(true)
+        }
+        if weighting.field != "" {
+            reader.SetWeightField(weighting.field)
+        }
+        if types.field != "" {
+            reader.SetTypeField(types.field)
+        }
+        next := func() (Event, bool, error) {
+            evt, prov, err := reader.Next()
+            if tr != nil && err == nil {
+                tr.record(reader.Raw(), prov, evt, yopts)
+            }
+            if errors.Is(err, io.EOF) {
+                return evt, false, nil
             }
-            processEvent(evt)
+            return evt, err == nil, err
+        }
+        process := func(evt Event) { processEvent(evt, yopts) }
+        if err := runWorkers(*workers, next, process); err != nil {
+            if body != nil && body.err != nil {
+                fail(codeNetwork, "%s: connection lost while reading: %v", path, body.err)
+            }
+            fail(codeOf(err, codeDecode), "%v", err)
+        }
+        if jr != nil {
+            e := journalEntry{Path: path, Records: collected}
+            _, err := io.Copy(hash, file) // bytes the decoder did not need
+            if err == nil {
+                copy(e.Hash[:], hash.Sum(nil))
+                err = jr.append(e)
+            }
+            if err != nil {
+                fail(codeIO, "-resume: %v", err)
+            }
+        }
+        file.Close()
+    }
+    if jr != nil {
+        if err := jr.remove(); err != nil {
+            fmt.Fprintf(noteOut, "warning: -resume: %v\n", err)
+        }
+    }
+    if streamer != nil {
+        streamer.flush()
+        fmt.Fprintln(reportOut)
+    }
+    if enrich != nil {
+        batch, err := enrich.flush()
+        if err == nil {
+            err = enrich.close()
+        }
+        if err != nil {
+            fail(codeExternal, "-enrich-cmd: %v", err)
+        }
+        for _, p := range batch {
+            recordFiltered(p.dt, p.k, p.w, p.sw, p.evt)
+        }
+    }
+    years.mergeInto(perYear)
+    if err := agg.finish(); err != nil {
+        fail(codeIO, "%v", err)
+    }
+    filter := filterDescription(*year, *month, *day)
+    if !rng.From.IsZero() {
+        filter += ", from " + rng.From.In(loc).Format(time.RFC3339Nano)
+    }
+    if !rng.To.IsZero() {
+        filter += ", before " + rng.To.In(loc).Format(time.RFC3339Nano)
+    }
+    if dump != nil {
+        meta := dumpMeta{Inputs: files, ReportFilter: filter, Where: where.expr.String(), Midnight: midnight.name, ISOOffsets: offsets.name}
+        if err := dump.close(meta); err != nil {
+            fail(codeOutput, "-dump: %v", err)
+        }
+    }
+    if *dumpDaily != "" {
+        daily := agg.perDayAll
+        if *dumpDailyFiltered {
+            daily = agg.perDay
+        }
+        if err := dumpDailyCSV(*dumpDaily, daily); err != nil {
+            fail(codeOutput, "-dump-daily: %v", err)
         }
     }
 
     // ----- Output logic -----
 
+    if reportFlags.has("monthly-summary-json") {
+        if err := writeMonthlySummaryJSON(saved.tee(reportOut, "monthly-summary.json"), agg); err != nil {
+            fail(codeOutput, "%v", err)
+        }
+        publishReportDir(saved)
+        return
+    }
+    if *outputFormat == "dot-timeline" {
+        if err := writeDotTimeline(saved.tee(reportOut, "timeline.dot"), "Partition growth: "+filterDescription(*year, *month, *day), *dotPeriod, agg); err != nil {
+            fail(codeOutput, "%v", err)
+        }
+        publishReportDir(saved)
+        return
+    }
+
+    // Counts below are sums of -weight-field when it is set, labelled with
+    // the field name, and abbreviated under -humanize.
+    unit := "splits"
+    if weighting.field != "" && !weighting.dual {
+        unit = weighting.field
+    }
+    num := strconv.Itoa
+    if *humanize {
+        num = humanizeCount
+    }
+
+    var out []section
+    if rng.active() {
+        out = append(out, buildRangeHeader(rng, loc))
+    }
+
     if *top && *year != 0 {
         if *topMonth {
             type kv struct {
                 Key string
                 Val int
                 M   int
+                W   int
             }
             rows := make([]kv, 0, 12)
             yprefix := fmt.Sprintf("%04d-", *year)
             for k, v := range perMonth {
                 if len(k) >= 7 && k[:5] == yprefix {
                     mm, _ := strconv.Atoi(k[5:7])
-                    rows = append(rows, kv{Key: k, Val: v, M: mm})
+                    rows = append(rows, kv{Key: k, Val: v, M: mm, W: weighting.side.perMonth[k]})
                 }
             }
-            sort.Slice(rows, func(i, j int) bool { return rows[i].Val > rows[j].Val })
+            sort.Slice(rows, func(i, j int) bool { return byRank(*rankBy == "weight", rows[i].Val, rows[j].Val, rows[i].W, rows[j].W, rows[i].Key, rows[j].Key) })
             if len(rows) > 5 {
                 rows = rows[:5]
             }
-            fmt.Printf("Top 5 months in %d:\n", *year)
+            s := section{
+                Key:     "top_months",
+                Title:   fmt.Sprintf("Top 5 months in %d", *year),
+                Heading: fmt.Sprintf("Top 5 months in %d:", *year),
+                Columns: []string{"Month", "Count"},
+                LineFmt: "%s: %s",
+            }
+            var counts, sums []int
+            var keys []string
             for _, r := range rows {
-                fmt.Printf("%s %d: %d\n", monthName(r.M), *year, r.Val)
+                s.Rows = append(s.Rows, []string{dates.month(*year, r.M), num(r.Val)})
+                counts, sums = append(counts, r.Val), append(sums, r.W)
+                keys = append(keys, r.Key)
+            }
+            if weighting.dual {
+                weighting.extend(&s, counts, sums, num)
             }
-            fmt.Println()
+            if detail != nil {
+                detail.attach(&s, detail.months, keys)
+            }
+            if len(rows) == 0 {
+                s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", *year))
+            }
+            out = append(out, s)
         }
         if *topWeek {
             type wk struct {
                 Key string
                 Val int
                 W   int
+                Sum int
             }
             yprefix := fmt.Sprintf("%04d-", *year)
             weeks := make([]wk, 0, 60)
             for k, v := range perISOWeekAll {
                 if len(k) >= 7 && k[:5] == yprefix {
                     w, _ := strconv.Atoi(k[6:8])
-                    weeks = append(weeks, wk{Key: k, Val: v, W: w})
+                    weeks = append(weeks, wk{Key: k, Val: v, W: w, Sum: weighting.side.perISOWeekAll[k]})
                 }
             }
-            sort.Slice(weeks, func(i, j int) bool { return weeks[i].Val > weeks[j].Val })
+            sort.Slice(weeks, func(i, j int) bool {
+                return byRank(*rankBy == "weight", weeks[i].Val, weeks[j].Val, weeks[i].Sum, weeks[j].Sum, weeks[i].Key, weeks[j].Key)
+            })
             if len(weeks) > 5 {
                 weeks = weeks[:5]
             }
-            fmt.Printf("Top 5 ISO weeks in %d:\n", *year)
+            s := section{
+                Key:     "top_weeks",
+                Title:   fmt.Sprintf("Top 5 ISO weeks in %d", *year),
+                Heading: fmt.Sprintf("Top 5 ISO weeks in %d:", *year),
+                Columns: []string{"ISO week", "Count"},
+                LineFmt: "%s: %s",
+            }
+            var counts, sums []int
+            var keys []string
             for _, r := range weeks {
-                fmt.Printf("%s: %d\n", r.Key, r.Val)
+                s.Rows = append(s.Rows, []string{r.Key, num(r.Val)})
+                counts, sums = append(counts, r.Val), append(sums, r.Sum)
+                keys = append(keys, r.Key)
+            }
+            if weighting.dual {
+                weighting.extend(&s, counts, sums, num)
+            }
+            if detail != nil {
+                detail.attach(&s, detail.weeks, keys)
             }
-            fmt.Println()
+            if len(weeks) == 0 {
+                s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", *year))
+            }
+            out = append(out, s)
         }
     }
 
     if *month != 0 && *year != 0 {
-        fmt.Printf("%s %d weekly summary:\n", monthName(*month), *year)
+        s := section{
+            Key:     "month_weekly",
+            Title:   fmt.Sprintf("%s weekly summary", dates.month(*year, *month)),
+            Heading: fmt.Sprintf("%s weekly summary:", dates.month(*year, *month)),
+            Columns: []string{"Week", "Period", "Count"},
+            LineFmt: "%s: %s: %s",
+        }
         dim := daysInMonth(*year, *month)
         numWeeks := (dim + 6) / 7
         grand := 0
+        var counts, sums []int
         for w := 1; w <= numWeeks; w++ {
             start := (w-1)*7 + 1
             end := w * 7
@@ -244,64 +1443,118 @@ func main() {
                 end = dim
             }
             count := monthWeekBuckets[w]
-            fmt.Printf("Week %d: %s %d–%d, %d: %d\n", w, monthName(*month), start, end, *year, count)
+            s.Rows = append(s.Rows, []string{fmt.Sprintf("Week %d", w),
+                dates.span(time.Date(*year, time.Month(*month), start, 0, 0, 0, 0, time.UTC),
+                    time.Date(*year, time.Month(*month), end, 0, 0, 0, 0, time.UTC)), num(count)})
             grand += count
+            counts, sums = append(counts, count), append(sums, weighting.side.monthWeeks[w])
+        }
+        if weighting.dual {
+            weighting.extend(&s, counts, sums, num)
         }
-        fmt.Printf("Total for %s %d: %d\n", monthName(*month), *year, grand)
-        fmt.Println()
+        if grand == 0 {
+            s.Notes = append(s.Notes, "No events matched.")
+        }
+        s.Notes = append(s.Notes, fmt.Sprintf("Total for %s: %s", dates.month(*year, *month), num(grand)))
+        out = append(out, s)
     }
 
     if *day != 0 && *month != 0 && *year != 0 {
         key := fmt.Sprintf("%04d-%02d-%02d", *year, *month, *day)
-        fmt.Printf("Day %s %d, %04d: %d\n", monthName(*month), *day, *year, perDay[key])
-        fmt.Println()
+        label, count := dates.day(time.Date(*year, time.Month(*month), *day, 0, 0, 0, 0, time.UTC)), num(perDay[key])
+        out = append(out, section{
+            Key:     "day",
+            Title:   "Day count",
+            Bare:    true,
+            Columns: []string{"Day", "Count"},
+            Rows:    [][]string{{label, count}},
+            LineFmt: "Day %s: %s",
+        })
     }
 
     if *year != 0 && !*allYears {
-        fmt.Println("Counts for year:")
-        fmt.Printf("%d: %d\n", *year, perYear[*year])
-        fmt.Println()
+        out = append(out, section{
+            Key:     "year",
+            Title:   "Counts for year",
+            Heading: "Counts for year:",
+            Columns: []string{"Year", "Count"},
+            Rows:    [][]string{{strconv.Itoa(*year), num(perYear[*year])}},
+            LineFmt: "%s: %s",
+        })
     }
 
     if *allYears {
-        fmt.Println("--- Yearly Partition Growth ---")
+        ra := &rollup{perYear, perQuarter, perMonth, weighting.side.perMonth, allDates, allWeights}
+        if filteredRollup != nil {
+            ra = filteredRollup
+        }
+        filters := ""
+        if filter != "all events" {
+            filters = strings.TrimPrefix(filter, "all events, ")
+        }
+        if w := where.expr.String(); w != "" {
+            if filters != "" {
+                filters += ", "
+            }
+            filters += "where " + w
+        }
+        if filters != "" || filteredRollup != nil {
+            out = append(out, rollupScope(filteredRollup != nil, filters))
+        }
+        perYear, perQuarter, perMonth := ra.perYear, ra.perQuarter, ra.perMonth
+        allDates, allWeights := ra.dates, ra.weights
+
         years := make([]int, 0, len(perYear))
         for y := range perYear {
             years = append(years, y)
         }
         sort.Ints(years)
         sum := 0
+        yearly := section{Key: "yearly", Title: "Yearly Partition Growth", Columns: []string{"Year", "Splits"}, LineFmt: "%s: %s " + unit}
         for _, y := range years {
             v := perYear[y]
-            fmt.Printf("%d: %d splits\n", y, v)
+            yearly.Rows = append(yearly.Rows, []string{strconv.Itoa(y), num(v)})
             sum += v
         }
-        fmt.Println()
+        if len(years) == 0 {
+            yearly.Notes = append(yearly.Notes, "No events matched.")
+        }
+        out = append(out, yearly)
 
-        fmt.Println("--- Quarterly Partition Growth ---")
         qs := make([]string, 0, len(perQuarter))
         for q := range perQuarter {
             qs = append(qs, q)
         }
         sort.Strings(qs)
+        quarterly := section{Key: "quarterly", Title: "Quarterly Partition Growth", Columns: []string{"Quarter", "Splits"}, LineFmt: "%s: %s " + unit}
         for _, q := range qs {
-            fmt.Printf("%s: %d splits\n", q, perQuarter[q])
+            quarterly.Rows = append(quarterly.Rows, []string{q, num(perQuarter[q])})
+        }
+        if len(qs) == 0 {
+            quarterly.Notes = append(quarterly.Notes, "No events matched.")
         }
-        fmt.Println()
+        out = append(out, quarterly)
 
-        fmt.Println("--- Monthly Partition Growth ---")
         ms := make([]string, 0, len(perMonth))
         for m := range perMonth {
             ms = append(ms, m)
         }
         sort.Strings(ms)
+        monthly := section{Key: "monthly", Title: "Monthly Partition Growth", Columns: []string{"Month", "Splits"}, LineFmt: "%s: %s " + unit}
+        var counts, sums []int
         for _, m := range ms {
-            fmt.Printf("%s: %d splits\n", m, perMonth[m])
+            monthly.Rows = append(monthly.Rows, []string{m, num(perMonth[m])})
+            counts, sums = append(counts, perMonth[m]), append(sums, ra.sideMonth[m])
+        }
+        if weighting.dual {
+            weighting.extend(&monthly, counts, sums, num)
         }
-        fmt.Println()
+        if len(ms) == 0 {
+            monthly.Notes = append(monthly.Notes, "No events matched.")
+        }
+        out = append(out, monthly)
 
         // --- 6-Month Average Monthly Growth ---
-        fmt.Println("--- 6-Month Average Monthly Growth ---")
         allMonthKeys := make([]string, 0, len(perMonth))
         for mk := range perMonth {
             allMonthKeys = append(allMonthKeys, mk)
@@ -329,7 +1582,7 @@ func main() {
             mid = 1
         }
         firstSum, secondSum := 0, 0
-        for i := 0; i < mid; i++ {
+        for i := 0; i < mid && i < n6; i++ {
             firstSum += counts6[i]
         }
         for i := mid; i < n6; i++ {
@@ -353,19 +1606,33 @@ func main() {
             }
         }
 
+        avg6 := section{Key: "avg_6_month", Title: "6-Month Average Monthly Growth", Columns: []string{"Month", "Splits"}, LineFmt: "  %s: %s " + unit}
         for i, mk := range recent6 {
-            fmt.Printf("  %s: %d splits\n", mk, counts6[i])
+            avg6.Rows = append(avg6.Rows, []string{mk, num(counts6[i])})
         }
         trend := "increasing"
         if !increasing {
             trend = "decreasing"
         }
-        fmt.Printf("Trend (last %d months): %s\n", n6, trend)
-        fmt.Printf("avg_monthly_growth: %d splits/month\n", avgMonthlyGrowth)
-        fmt.Println()
+        switch n6 {
+        case 0:
+            avg6.Notes = append(avg6.Notes, "No events matched.")
+        case 1:
+            // One month has no halves to compare.
+            avg6.Notes = append(avg6.Notes, "Trend (last 1 month): n/a, needs 2 months",
+                fmt.Sprintf("avg_monthly_growth: %s %s/month", num(avgMonthlyGrowth), unit))
+        default:
+            avg6.Notes = append(avg6.Notes,
+                fmt.Sprintf("Trend (last %d months): %s", n6, trend),
+                fmt.Sprintf("avg_monthly_growth: %s %s/month", num(avgMonthlyGrowth), unit))
+        }
+        out = append(out, avg6)
 
-        fmt.Println("--- Last 30 Days Partition Growth ---")
-        if len(allDates) > 0 {
+        last30 := section{Key: "last_30_days", Title: "Last 30 Days Partition Growth", Columns: []string{"From", "To", "Splits"}, LineFmt: "From %s to %s: %s " + unit}
+        if allWeights != nil {
+            latest, thirtyDaysAgo, count30 := weightedLast30(allDates, allWeights)
+            last30.Rows = append(last30.Rows, []string{thirtyDaysAgo.Format("2006-01-02"), latest.Format("2006-01-02"), num(count30)})
+        } else if len(allDates) > 0 {
             sort.Slice(allDates, func(i, j int) bool { return allDates[i].After(allDates[j]) })
             latest := allDates[0]
             thirtyDaysAgo := latest.AddDate(0, 0, -30)
@@ -377,17 +1644,150 @@ func main() {
                     break
                 }
             }
-            fmt.Printf("From %s to %s: %d splits\n", thirtyDaysAgo.Format("2006-01-02"), latest.Format("2006-01-02"), count30)
+            last30.Rows = append(last30.Rows, []string{thirtyDaysAgo.Format("2006-01-02"), latest.Format("2006-01-02"), num(count30)})
         } else {
-            fmt.Println("No data available.")
+            last30.Notes = append(last30.Notes, "No events matched.")
         }
-        fmt.Println()
-        
-        fmt.Printf("Grand Total (All Years): %d splits\n", sum)
-        fmt.Println()
+        out = append(out, last30)
+
+        out = append(out, section{
+            Key:   "grand_total",
+            Title: "Grand Total",
+            Bare:  true,
+            Notes: []string{fmt.Sprintf("Grand Total (All Years): %s %s", num(sum), unit)},
+        })
+    }
+
+    core := len(out)
+    for _, r := range reportFlags {
+        out = append(out, reports[r.Name].build(agg, r.Arg)...)
+    }
+    if *segments {
+        out = append(out, buildSegments(agg)...)
+    }
+    if *leaderChurn {
+        out = append(out, buildLeaderChurn(agg)...)
+    }
+    if agg.movers != nil {
+        out = append(out, agg.movers.sections()...)
+    }
+    if *weekPercentiles {
+        out = append(out, buildWeekPercentiles(agg)...)
+    }
+    if ages != nil {
+        out = append(out, ages.section())
+    }
+    if *retention {
+        out = append(out, buildRetention(agg)...)
+        if *retentionCSV != "" {
+            if err := writeRetentionCSV(*retentionCSV, agg); err != nil {
+                fail(codeOutput, "-retention-csv: %v", err)
+            }
+        }
+    }
+    if agg.groups != nil {
+        out = append(out, buildGroupBy(agg)...)
+    }
+    if agg.values != nil {
+        out = append(out, buildValueAggregates(agg)...)
+    }
+    if types.perMonth != nil {
+        out = append(out, types.byTypeSection(num))
+    }
+    for _, p := range plugins {
+        out = append(out, p.section())
+    }
+    for i := core; i < len(out); i++ {
+        out[i].Optional = true
+    }
+    if *twoDigitPivot != 0 || *assumeYearFlag != "" {
+        out = append(out, inferred.section(len(allDates)))
+    }
+    if weighting.field != "" {
+        out = append(out, weighting.section(num))
+    }
+    if midnight.shown() {
+        out = append(out, midnight.section(loc))
+    }
+    if offsets.nonZero > 0 || offsets.name != "normalize-tz" {
+        out = append(out, offsets.section(loc))
+    }
+    if len(types.skipped) > 0 {
+        out = append(out, types.skippedSection())
+    }
+    if rejected.total() > 0 {
+        out = append(out, rejected.section())
+    }
+    if agg.leaderLastSeen != nil {
+        out = append(out, buildLeaderFreshness(agg)...)
+    }
+    if *telemetry {
+        out = append(out, buildTelemetry(agg, aggTelemetry{"all dates", len(allDates), int64(cap(allDates)) * timeSize, len(allDates)})...)
     }
 
     if !*allYears && *year == 0 && *month == 0 && *day == 0 {
-        fmt.Printf("Overall total (unfiltered): %d\n", len(allDates))
+        overallTotal := len(allDates)
+        if weighting.field != "" && !weighting.dual {
+            overallTotal = weighting.total
+        }
+        out = append(out, section{
+            Key:     "overall_total",
+            Title:   "Overall total",
+            Bare:    true,
+            NoBlank: true,
+            Notes:   []string{fmt.Sprintf("Overall total (unfiltered): %s", num(overallTotal))},
+        })
+    }
+    if samples != nil && (*outputFormat != "json" || *samplesInJSON) {
+        out = append(out, samples.section())
+    }
+
+    if *sectionsFlag != "" {
+        var missing []string
+        out, missing = selectSections(out, parseSectionKeys(*sectionsFlag), *outputFormat == "json")
+        for _, k := range missing {
+            fmt.Fprintf(noteOut, "note: -sections: no %s section in this run\n", k)
+        }
+    }
+    if n := rejected.missingDate + rejected.badDate; n > 0 {
+        runErrors = append(runErrors, reportError{codeDateParse, "records skipped for a missing or unreadable date", n})
+    }
+    for _, c := range agg.checks {
+        if c.Status > statusOK {
+            runErrors = append(runErrors, reportError{Code: codeThreshold, Message: c.line()})
+        }
+    }
+    title := "Partition growth: " + filterDescription(*year, *month, *day)
+    format, stdout := *outputFormat, reportOut
+    if format == "line" {
+        format, stdout = "text", noteOut
+    }
+    stdout = saved.tee(stdout, reportFileName(format))
+    if *maxOutputBytes > 0 {
+        buf, fits := fitBudget(format, title, out, *maxOutputBytes)
+        if !fits {
+            fmt.Fprintf(noteOut, "warning: output is %d bytes, over -max-output-bytes %d\n", len(buf), *maxOutputBytes)
+        }
+        stdout.Write(buf)
+    } else {
+        render(stdout, format, title, out)
+    }
+    if *outputFormat == "line" {
+        fmt.Fprintln(saved.tee(reportOut, "summary.line"), newSummaryLine(perDay, totalEvents, *year, *month, rejected.missingDate+rejected.badDate,
+            time.Since(started), agg.exitStatus()))
+    }
+    publishReportDir(saved)
+    if post != nil {
+        if err := postResults(post, postRecords(agg, *postGranularity), *postDryRun); err != nil {
+            fail(codeExternal, "-post: %v", err)
+        }
+    }
+    os.Exit(int(agg.exitStatus()))
+}
+
+// publishReportDir saves the run into -report-dir, if set, or exits.
+func publishReportDir(d *reportDir) {
+    if err := d.publish(); err != nil {
+        fail(codeOutput, "-report-dir: %v", err)
     }
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// toolPath is the partition_growth binary built by TestMain, for tests that
+// run the whole command.
+var toolPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "partition_growth_test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	toolPath = filepath.Join(dir, "partition_growth")
+	if runtime.GOOS == "windows" {
+		toolPath += ".exe"
+	}
+	if out, err := exec.Command("go", "build", "-o", toolPath, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building the command: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// runTool runs the command in dir and returns its stdout, stderr and exit
+// status.
+func runTool(t *testing.T, dir string, args ...string) (stdout, stderr string, status int) {
+	t.Helper()
+	return runToolStdin(t, dir, nil, args...)
+}
+
+// runToolStdin is runTool with stdin piped to the command; nil leaves it
+// unset.
+func runToolStdin(t *testing.T, dir string, stdin io.Reader, args ...string) (stdout, stderr string, status int) {
+	t.Helper()
+	cmd := exec.Command(toolPath, args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case errors.As(err, &exit):
+		status = exit.ExitCode()
+	case err != nil:
+		t.Fatalf("running %v: %v", args, err)
+	}
+	return out.String(), errOut.String(), status
+}
+
+// writeFile writes content to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// event renders one input record.
+func event(parent int, date, leader string) string {
+	return fmt.Sprintf(`{"parentId":%d,"firstChildId":%d,"secondChildId":%d,"date":%q,"leaderNodeInfo":%q}`,
+		parent, parent+1, parent+2, date, leader)
+}
+
+// eventArray renders records as a JSON array.
+func eventArray(records ...string) string {
+	return "[" + strings.Join(records, ",\n") + "]\n"
+}
+
+// TestTZFold counts events around the New York fall-back fold and local
+// midnight under -tz: every event is counted once, on its local day.
+func TestTZFold(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(
+		event(1, "2025-11-02T05:30:00Z", "a"),  // 01:30 EDT
+		event(10, "2025-11-02T06:30:00Z", "a"), // 01:30 EST
+		event(20, "2025-11-02 01:30:00", "a"),  // zone-less: the first 01:30
+		event(30, "2025-11-03T04:59:59Z", "a"), // 23:59:59 EST on Nov 2
+		event(40, "2025-11-03T05:00:00Z", "a"), // midnight Nov 3
+	))
+	out, errOut, status := runTool(t, dir, "-f", "in.json", "-tz", "America/New_York", "-y", "2025", "-m", "11", "-d", "2")
+	if status != 0 {
+		t.Fatalf("exit %d\n%s", status, errOut)
+	}
+	for _, want := range []string{"Day Nov 2, 2025: 4\n", "2025: 5\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestDayValidation checks -d against the months selected by -y, -m and
+// -from/-to: it is rejected only when none of them has the day.
+func TestDayValidation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(event(1, "2024-02-29T12:00:00Z", "a"), event(10, "2025-02-28T12:00:00Z", "a")))
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-y", "2024", "-m", "2", "-d", "29"}, true},
+		{[]string{"-y", "2025", "-m", "2", "-d", "29"}, false},
+		{[]string{"-y", "2025", "-m", "2", "-d", "28"}, true},
+		{[]string{"-m", "2", "-d", "29"}, true},
+		{[]string{"-m", "2", "-d", "30"}, false},
+		{[]string{"-m", "4", "-d", "31"}, false},
+		{[]string{"-d", "31"}, true},
+		{[]string{"-d", "32"}, false},
+		{[]string{"-d", "-1"}, false},
+		{[]string{"-d", "31", "-from", "2025-04-01", "-to", "2025-04-30"}, false},
+		{[]string{"-d", "31", "-from", "2025-04-01", "-to", "2025-05-01"}, true},
+		{[]string{"-d", "29", "-from", "2024-02-01", "-to", "2024-02-29"}, true},
+		{[]string{"-d", "29", "-from", "2025-02-01", "-to", "2025-02-28"}, false},
+		{[]string{"-d", "29", "-from", "2025-02-01", "-to", "2025-03-01", "-to-exclusive"}, false},
+		{[]string{"-m", "2", "-d", "29", "-from", "2025-01-01", "-to", "2026-12-31"}, false},
+		{[]string{"-m", "2", "-d", "29", "-from", "2024-01-01", "-to", "2025-12-31"}, true},
+		{[]string{"-y", "2025", "-d", "31", "-from", "2025-02-01", "-to", "2025-02-28"}, false},
+		{[]string{"-y", "2024", "-d", "31", "-from", "2025-02-01"}, true}, // selects nothing
+	}
+	for _, tt := range tests {
+		_, errOut, status := runTool(t, dir, append([]string{"-f", "in.json"}, tt.args...)...)
+		switch {
+		case tt.ok && status != 0:
+			t.Errorf("%q: exit %d\n%s", tt.args, status, errOut)
+		case !tt.ok && (status != codeUsage.exitStatus() || !strings.Contains(errOut, "error [E_USAGE]: -d ")):
+			t.Errorf("%q: exit %d, want %d\n%s", tt.args, status, codeUsage.exitStatus(), errOut)
+		}
+	}
+	out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2024", "-m", "2", "-d", "29")
+	if status != 0 || !strings.Contains(out, "Day Feb 29, 2024: 1\n") {
+		t.Errorf("leap day: exit %d\n%s%s", status, out, errOut)
+	}
+}
+
+// TestSilent runs every report, in every output format and with the summary
+// modes, under -silent: nothing may reach stdout, stderr may hold only the
+// error lines of the run without -silent, failing runs included, and the
+// exit status must match.
+func TestSilent(t *testing.T) {
+	dir := t.TempDir()
+	var records []string
+	for i := 0; i < 40; i++ {
+		records = append(records, event(10*i+1, fmt.Sprintf("2025-03-%02dT%02d:00:00Z", 1+i%28, i%24), fmt.Sprintf("n%d", i%3)))
+	}
+	records = append(records, event(900, "garbage", "n1"), event(910, "2025-03-04T00:00:00Z", "n1"))
+	writeFile(t, dir, "in.json", eventArray(records...))
+	base := []string{"-f", "in.json", "-y", "2025", "-m", "3"}
+	var runs [][]string
+	for name := range reports {
+		runs = append(runs, append(slices.Clone(base), "-report="+name))
+	}
+	all := strings.Join(slices.Sorted(maps.Keys(reports)), ",")
+	for _, format := range outputFormats {
+		runs = append(runs, append(slices.Clone(base), "-report="+all, "-output", format))
+	}
+	runs = append(runs,
+		append(slices.Clone(base), "-a", "-d", "4"),
+		append(slices.Clone(base), "-a-filtered", "-report=stats=parent", "-stats-exact", "1"),
+		append(slices.Clone(base), "-group-by", "leader", "-approx", "-approx-threshold", "5", "-group-top", "2"),
+		[]string{"-f", "in.json", "-y", "2025", "-t", "-week"},
+		[]string{"-f", "in.json", "-y", "2025", "-t", "-month"},
+		append(slices.Clone(base), "-leader-freshness", "1h"),
+		append(slices.Clone(base), "-progress", "-v", "-max-output-bytes", "10"),
+		[]string{"-f", "missing.json", "-a"},
+		[]string{"-f", "in.json", "-output", "yaml"},
+	)
+	for _, args := range runs {
+		t.Run(strings.Join(args[2:], " "), func(t *testing.T) {
+			_, loud, want := runTool(t, dir, args...)
+			var errLines []string
+			for _, line := range strings.SplitAfter(loud, "\n") {
+				if strings.HasPrefix(line, "error [") || strings.HasPrefix(line, `{"level":"error"`) {
+					errLines = append(errLines, line)
+				}
+			}
+			out, errOut, status := runTool(t, dir, append(args, "-silent")...)
+			if out != "" || errOut != strings.Join(errLines, "") {
+				t.Errorf("-silent printed %d bytes to stdout and to stderr:\n%s%s\nwant only the errors:\n%s",
+					len(out), out, errOut, strings.Join(errLines, ""))
+			}
+			if status != want {
+				t.Errorf("exit %d under -silent, %d without", status, want)
+			}
+		})
+	}
+}
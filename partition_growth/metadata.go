@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// ── event-metadata-summary ────────────────────────────────────────────────────
+
+// fieldSummary keeps the distinct values of each Event field over the
+// filtered events, for a quick look at the shape of an unfamiliar export.
+type fieldSummary struct {
+	dates, leaders           map[string]struct{}
+	parents, firsts, seconds map[int]struct{}
+}
+
+func (a *aggregates) enableFieldSummary() {
+	if a.fieldSummary == nil {
+		a.fieldSummary = &fieldSummary{
+			dates:   make(map[string]struct{}),
+			leaders: make(map[string]struct{}),
+			parents: make(map[int]struct{}),
+			firsts:  make(map[int]struct{}),
+			seconds: make(map[int]struct{}),
+		}
+	}
+}
+
+func (f *fieldSummary) add(evt Event) {
+	f.dates[evt.Date] = struct{}{}
+	f.leaders[evt.LeaderNodeInfo] = struct{}{}
+	f.parents[evt.ParentID] = struct{}{}
+	f.firsts[evt.FirstChildID] = struct{}{}
+	f.seconds[evt.SecondChildID] = struct{}{}
+}
+
+func (f *fieldSummary) size() (buckets int, b int64) {
+	for _, set := range []map[string]struct{}{f.dates, f.leaders} {
+		for s := range set {
+			b += int64(stringHeader+len(s)) + mapEntryOverhead
+		}
+		buckets += len(set)
+	}
+	for _, set := range []map[int]struct{}{f.parents, f.firsts, f.seconds} {
+		b += int64(len(set)) * (8 + mapEntryOverhead)
+		buckets += len(set)
+	}
+	return buckets, b
+}
+
+func prepareEventMetadataSummary(a *aggregates, _ string) error {
+	a.enableFieldSummary()
+	return nil
+}
+
+// buildEventMetadataSummary lists the distinct values of each field with the
+// range of the numeric fields and the extremes by length of the string
+// fields. Dates are the strings as written, before parsing.
+func buildEventMetadataSummary(a *aggregates, _ string) []section {
+	f := a.fieldSummary
+	s := section{
+		Key:     "event_metadata_summary",
+		Title:   "Event Field Summary",
+		Columns: []string{"Field", "Kind", "Unique", "Min / Shortest", "Max / Longest"},
+	}
+	for _, c := range []struct {
+		name string
+		set  map[string]struct{}
+	}{{"date", f.dates}, {"leaderNodeInfo", f.leaders}} {
+		short, long := stringExtremes(c.set)
+		s.Rows = append(s.Rows, []string{c.name, "string", strconv.Itoa(len(c.set)), short, long})
+	}
+	for _, c := range []struct {
+		name string
+		set  map[int]struct{}
+	}{{"parentId", f.parents}, {"firstChildId", f.firsts}, {"secondChildId", f.seconds}} {
+		min, max := intExtremes(c.set)
+		s.Rows = append(s.Rows, []string{c.name, "number", strconv.Itoa(len(c.set)), min, max})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
+
+// stringExtremes returns the shortest and longest value, quoted, breaking
+// ties by the lesser string so the output is stable.
+func stringExtremes(set map[string]struct{}) (string, string) {
+	var short, long string
+	first := true
+	for v := range set {
+		n := utf8.RuneCountInString(v)
+		if first {
+			short, long, first = v, v, false
+			continue
+		}
+		if s := utf8.RuneCountInString(short); n < s || n == s && v < short {
+			short = v
+		}
+		if l := utf8.RuneCountInString(long); n > l || n == l && v < long {
+			long = v
+		}
+	}
+	if first {
+		return "-", "-"
+	}
+	return strconv.Quote(short), strconv.Quote(long)
+}
+
+func intExtremes(set map[int]struct{}) (string, string) {
+	var min, max int
+	first := true
+	for v := range set {
+		if first || v < min {
+			min = v
+		}
+		if first || v > max {
+			max = v
+		}
+		first = false
+	}
+	if first {
+		return "-", "-"
+	}
+	return strconv.Itoa(min), strconv.Itoa(max)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ── -midnight-policy ──────────────────────────────────────────────────────────
+//
+// Some producers truncate timestamps to the date, writing 12:00:00 AM. Read in
+// one zone and bucketed in another, every such record moves to the previous
+// (or stays on the same) local day, which can shift whole days of events
+// across month boundaries. Records whose time of day is exactly midnight as
+// written are counted, and under date-only they are bucketed on the calendar
+// date written in the record, whatever -tz and -date-trunc-offset say.
+
+var midnightPolicies = []string{"keep", "date-only"}
+
+type midnightPolicy struct {
+	name               string
+	dateOnly           bool
+	count, moved, seen int // midnight records, those keep puts on another day, dated records
+}
+
+func newMidnightPolicy(name string) (*midnightPolicy, error) {
+	for _, p := range midnightPolicies {
+		if p == name {
+			return &midnightPolicy{name: name, dateOnly: name == "date-only"}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown -midnight-policy %q (want keep or date-only)", name)
+}
+
+// isMidnight reports whether the wall clock of t is exactly 00:00:00.
+// Callers pass t in the zone it was written in.
+func isMidnight(t time.Time) bool {
+	h, m, s := t.Clock()
+	return h == 0 && m == 0 && s == 0 && t.Nanosecond() == 0
+}
+
+// place returns the bucketing time in loc of a parsed record time t. The
+// date was written with UTC offset off when zoned, else as wall time in t's
+// zone. midnight reports a record written at exactly 00:00:00, and moved one
+// that keep buckets on another day than the one written.
+func (p *midnightPolicy) place(t time.Time, off int, zoned bool, loc *time.Location) (bt time.Time, midnight, moved bool) {
+	written := t
+	if zoned {
+		written = t.In(time.FixedZone("", off))
+	}
+	kept := t.In(loc)
+	if t.IsZero() || !isMidnight(written) {
+		return kept, false, false
+	}
+	y, m, d := written.Date()
+	ky, km, kd := kept.Date()
+	moved = y != ky || m != km || d != kd
+	if p.dateOnly {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), true, moved
+	}
+	return kept, true, moved
+}
+
+func (p *midnightPolicy) observe(midnight, moved bool) {
+	p.seen++
+	if midnight {
+		p.count++
+	}
+	if moved {
+		p.moved++
+	}
+}
+
+// shown reports whether the midnight section belongs in the report: under
+// date-only, or when keep moved a midnight record to another day.
+func (p *midnightPolicy) shown() bool {
+	return p.dateOnly || p.moved > 0
+}
+
+// section reports the midnight records and how they were bucketed.
+func (p *midnightPolicy) section(loc *time.Location) section {
+	how := fmt.Sprintf("converted to %s like any other time; a zone behind the written one moves them to the previous day", loc)
+	if p.dateOnly {
+		how = "bucketed on the date as written, ignoring -tz"
+	}
+	return section{
+		Key:     "midnight",
+		Title:   "Midnight Timestamps",
+		Columns: []string{"Measure", "Value"},
+		Rows: [][]string{
+			{"Records at exactly 00:00:00", strconv.Itoa(p.count)},
+			{"Share of dated records", pct(p.count, p.seen)},
+			{"Another day under keep", strconv.Itoa(p.moved)},
+			{"Policy", p.name},
+		},
+		Notes: []string{fmt.Sprintf("-midnight-policy %s: midnight records are %s.", p.name, how)},
+	}
+}
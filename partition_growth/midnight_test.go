@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMidnightPlace(t *testing.T) {
+	tests := []struct {
+		date            string
+		dateOnly        bool
+		day             string
+		midnight, moved bool
+	}{
+		{"2025-03-14T00:00:00+02:00", false, "2025-03-13", true, true},
+		{"2025-03-14T00:00:00+02:00", true, "2025-03-14", true, true},
+		{"2025-03-14T02:00:00+02:00", false, "2025-03-14", false, false}, // midnight only in UTC
+		{"2025-03-14T02:00:00+02:00", true, "2025-03-14", false, false},
+		{"2025-03-14T00:00:00Z", false, "2025-03-14", true, false},
+		{"2025-03-14T00:00:00-05:00", true, "2025-03-14", true, false},
+	}
+	for _, tt := range tests {
+		dt, err := time.Parse(time.RFC3339, tt.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, off := dt.Zone()
+		p := &midnightPolicy{dateOnly: tt.dateOnly}
+		bt, midnight, moved := p.place(dt, off, true, time.UTC)
+		if got := bt.Format(time.DateOnly); got != tt.day || midnight != tt.midnight || moved != tt.moved {
+			t.Errorf("%s, date-only %v: %s midnight %v moved %v, want %s %v %v",
+				tt.date, tt.dateOnly, got, midnight, moved, tt.day, tt.midnight, tt.moved)
+		}
+	}
+}
+
+// TestMidnightPolicies buckets offset-bearing records in UTC. Midnight
+// Mar 1 at +02:00 is Feb 28 under keep and Mar 1 under date-only; 02:00 at
+// +02:00 is midnight only in UTC and stays on its day either way.
+func TestMidnightPolicies(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(
+		event(1, "2025-03-01T00:00:00+02:00", "n"),
+		event(10, "2025-03-14T00:00:00+02:00", "n"),
+		event(20, "2025-03-14T02:00:00+02:00", "n"),
+		event(30, "2025-03-20T00:00:00Z", "n"),
+	))
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{"keep", []string{"Total for Feb 2025: 1\n", "Total for Mar 2025: 3\n", "Day Mar 13, 2025: 1\n", "Day Mar 14, 2025: 1\n",
+			"Records at exactly 00:00:00  3\n", "Another day under keep       2\n"}},
+		{"date-only", []string{"Total for Feb 2025: 0\n", "Total for Mar 2025: 4\n", "Day Mar 13, 2025: 0\n", "Day Mar 14, 2025: 2\n",
+			"Records at exactly 00:00:00  3\n", "Another day under keep       2\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var out string
+			for _, args := range [][]string{{"-m", "2"}, {"-m", "3"}, {"-m", "3", "-d", "13"}, {"-m", "3", "-d", "14"}} {
+				o, errOut, status := runTool(t, dir, append([]string{"-f", "in.json", "-y", "2025", "-midnight-policy", tt.policy}, args...)...)
+				if status != 0 {
+					t.Fatalf("%q: exit %d\n%s", args, status, errOut)
+				}
+				out += o
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+// TestMidnightSectionShown checks the section stays out of a default report
+// whose midnight records keep their day.
+func TestMidnightSectionShown(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(
+		event(1, "2025-03-14T00:00:00Z", "n"),
+		event(10, "2025-03-15 00:00:00", "n"),
+	))
+	tests := []struct {
+		args  []string
+		shown bool
+	}{
+		{nil, false},
+		{[]string{"-midnight-policy", "date-only"}, true},
+		{[]string{"-tz", "America/New_York", "-date-style", "iso"}, true},
+	}
+	for _, tt := range tests {
+		if len(tt.args) > 1 && tt.args[0] == "-tz" {
+			if _, err := time.LoadLocation(tt.args[1]); err != nil {
+				t.Skip(err)
+			}
+		}
+		out, errOut, status := runTool(t, dir, append([]string{"-f", "in.json", "-y", "2025"}, tt.args...)...)
+		if status != 0 {
+			t.Fatalf("%q: exit %d\n%s", tt.args, status, errOut)
+		}
+		if got := strings.Contains(out, "--- Midnight Timestamps ---"); got != tt.shown {
+			t.Errorf("%q: section shown %v, want %v:\n%s", tt.args, got, tt.shown, out)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"partition_growth/events"
+)
+
+// ── -movers ───────────────────────────────────────────────────────────────────
+//
+// Top movers compare the filtered events of two months per parent or leader.
+// Counts are kept for the two -periods months only, so the months must be
+// known before the main pass: given explicitly, or else found by a pre-scan
+// that reads the dates of every input once and picks the two months before
+// the month of the latest event, which is taken to be incomplete.
+
+// movers counts the events of each value of dim in the two periods.
+type movers struct {
+	dim     string
+	top     int
+	periods [2]string // "YYYY-MM", earlier first
+	counts  [2]map[string]int
+}
+
+func newMovers(dim string, top int, periods [2]string) *movers {
+	return &movers{dim: dim, top: top, periods: periods,
+		counts: [2]map[string]int{make(map[string]int), make(map[string]int)}}
+}
+
+// parsePeriods reads a -periods value: two months, earlier first.
+func parsePeriods(s string) ([2]string, error) {
+	var p [2]string
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return p, fmt.Errorf("want two months, e.g. 2025-02,2025-03")
+	}
+	for i, m := range parts {
+		m = strings.TrimSpace(m)
+		if _, err := time.Parse("2006-01", m); err != nil {
+			return p, fmt.Errorf("%q is not a YYYY-MM month", m)
+		}
+		p[i] = m
+	}
+	if p[0] >= p[1] {
+		return p, fmt.Errorf("%s is not before %s", p[0], p[1])
+	}
+	return p, nil
+}
+
+// latestMonths pre-scans files for the latest event date, reading zone-less
+// dates in read, and returns the two complete months of loc before it.
+// Records whose date does not parse are skipped here; the main pass reports
+// them.
+func latestMonths(files []string, read, loc *time.Location, yearOpts func(path string) events.YearOptions) ([2]string, error) {
+	var latest time.Time
+	for _, path := range files {
+		if path == stdinInput {
+			return [2]string{}, fmt.Errorf("standard input cannot be pre-scanned; give -periods")
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return [2]string{}, withCode(codeInputOpen, err)
+		}
+		r := events.NewReader(f, path)
+		opts := yearOpts(path)
+		for {
+			evt, _, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return [2]string{}, err
+			}
+			if dt, _, err := events.ParseDateYears(evt.Date, read, opts); err == nil && dt.After(latest) {
+				latest = dt
+			}
+		}
+		f.Close()
+	}
+	if latest.IsZero() {
+		return [2]string{}, withCode(codeEmpty, fmt.Errorf("no dated events to pick -periods from"))
+	}
+	latest = latest.In(loc)
+	first := time.Date(latest.Year(), latest.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return [2]string{first.AddDate(0, -2, 0).Format("2006-01"), first.AddDate(0, -1, 0).Format("2006-01")}, nil
+}
+
+func (m *movers) add(month string, evt Event) {
+	for i, p := range m.periods {
+		if month == p {
+			m.counts[i][groupKey(m.dim, evt)] += evt.Weight
+		}
+	}
+}
+
+// mover is one value's change between the periods.
+type mover struct {
+	Value  string `json:"value"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Change int    `json:"change"`
+}
+
+// sections lists the top increases and the top decreases. A value absent
+// from one period moves from or to zero; ties go to the lower value.
+func (m *movers) sections() []section {
+	seen := make(map[string]bool)
+	var all []mover
+	for _, c := range m.counts {
+		for v := range c {
+			if !seen[v] {
+				seen[v] = true
+				from, to := m.counts[0][v], m.counts[1][v]
+				all = append(all, mover{v, from, to, to - from})
+			}
+		}
+	}
+	label := "ParentID"
+	if m.dim == "leader" {
+		label = "Leader"
+	}
+	list := func(key, title string, keep func(mover) bool, less func(a, b mover) bool) section {
+		var sel []mover
+		for _, mv := range all {
+			if keep(mv) {
+				sel = append(sel, mv)
+			}
+		}
+		sort.Slice(sel, func(i, j int) bool {
+			if sel[i].Change != sel[j].Change {
+				return less(sel[i], sel[j])
+			}
+			return compareKeys(sel[i].Value, sel[j].Value) < 0
+		})
+		if len(sel) > m.top {
+			sel = sel[:m.top]
+		}
+		s := section{
+			Key:     key,
+			Title:   fmt.Sprintf("%s %s, %s to %s", title, label, m.periods[0], m.periods[1]),
+			Columns: []string{label, m.periods[0], m.periods[1], "Change"},
+		}
+		for _, mv := range sel {
+			s.Rows = append(s.Rows, []string{mv.Value, strconv.Itoa(mv.From), strconv.Itoa(mv.To), fmt.Sprintf("%+d", mv.Change)})
+		}
+		if len(sel) == 0 {
+			s.Notes = append(s.Notes, "None.")
+		}
+		s.Data = append([]mover{}, sel...)
+		return s
+	}
+	return []section{
+		list("movers_up", fmt.Sprintf("Top %d Increases by", m.top), func(mv mover) bool { return mv.Change > 0 },
+			func(a, b mover) bool { return a.Change > b.Change }),
+		list("movers_down", fmt.Sprintf("Top %d Decreases by", m.top), func(mv mover) bool { return mv.Change < 0 },
+			func(a, b mover) bool { return a.Change < b.Change }),
+	}
+}
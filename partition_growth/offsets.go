@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ── -iso-offsets ──────────────────────────────────────────────────────────────
+//
+// RFC 3339 dates carry their own UTC offset. Which day, week and month such an
+// event falls in depends on the clock it is read on: the producer's own
+// (honor), UTC (normalize-utc) or the bucket zone of -tz and
+// -date-trunc-offset (normalize-tz, the default and the behavior before the
+// flag existed). Zone-less dates have no offset to honor and are always
+// bucketed in the bucket zone.
+
+var isoOffsetPolicies = []string{"normalize-tz", "normalize-utc", "honor"}
+
+type isoOffsets struct {
+	name          string
+	nonZero, seen int // events written with a non-zero offset, dated events
+}
+
+func newISOOffsets(name string) (*isoOffsets, error) {
+	for _, p := range isoOffsetPolicies {
+		if p == name {
+			return &isoOffsets{name: name}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown -iso-offsets %q (want honor, normalize-utc or normalize-tz)", name)
+}
+
+// zone returns the zone to bucket a record in, given the offset written in
+// its date (see events.DateOffset) and the bucket zone loc.
+func (p *isoOffsets) zone(off int, zoned bool, loc *time.Location) *time.Location {
+	switch {
+	case p.name == "normalize-utc":
+		return time.UTC
+	case p.name == "honor" && zoned:
+		return time.FixedZone("", off)
+	}
+	return loc
+}
+
+func (p *isoOffsets) observe(off int) {
+	p.seen++
+	if off != 0 {
+		p.nonZero++
+	}
+}
+
+// section reports the events written with an offset and how they were
+// bucketed.
+func (p *isoOffsets) section(loc *time.Location) section {
+	how := fmt.Sprintf("converted to %s", loc)
+	switch p.name {
+	case "normalize-utc":
+		how = "converted to UTC, whatever -tz says"
+	case "honor":
+		how = fmt.Sprintf("bucketed at the offset they were written with; zone-less dates use %s", loc)
+	}
+	return section{
+		Key:     "iso_offsets",
+		Title:   "Written UTC Offsets",
+		Columns: []string{"Measure", "Value"},
+		Rows: [][]string{
+			{"Records with a non-zero offset", strconv.Itoa(p.nonZero)},
+			{"Share of dated records", pct(p.nonZero, p.seen)},
+			{"Policy", p.name},
+		},
+		Notes: []string{fmt.Sprintf("-iso-offsets %s: dates with an offset are %s.", p.name, how)},
+	}
+}
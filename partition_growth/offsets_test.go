@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestISOOffsetsZone(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	instant := time.Date(2025, 3, 14, 22, 30, 0, 0, time.UTC)
+	tests := []struct {
+		policy string
+		off    int
+		zoned  bool
+		day    string
+	}{
+		{"normalize-tz", 2 * 3600, true, "2025-03-15"},
+		{"normalize-tz", 0, false, "2025-03-15"},
+		{"normalize-utc", 2 * 3600, true, "2025-03-14"},
+		{"normalize-utc", 0, false, "2025-03-14"},
+		{"honor", 2 * 3600, true, "2025-03-15"},
+		{"honor", -5 * 3600, true, "2025-03-14"},
+		{"honor", 0, true, "2025-03-14"},
+		{"honor", 0, false, "2025-03-15"}, // zone-less: the bucket zone
+	}
+	for _, tt := range tests {
+		p, err := newISOOffsets(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := instant.In(p.zone(tt.off, tt.zoned, tokyo)).Format(time.DateOnly); got != tt.day {
+			t.Errorf("%s, offset %d, zoned %v: day %s, want %s", tt.policy, tt.off, tt.zoned, got, tt.day)
+		}
+	}
+	if _, err := newISOOffsets("local"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
+
+// TestISOOffsetsPolicies runs one instant, 2025-03-14 22:30 UTC, written
+// with three offsets under each policy and -tz Asia/Tokyo (UTC+9), and
+// checks the days it lands on, the offset count and the provenance. One
+// leader makes the daily top leader count the day total.
+func TestISOOffsetsPolicies(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(
+		event(1, "2025-03-14T22:30:00Z", "n"),
+		event(10, "2025-03-15T00:30:00+02:00", "n"),
+		event(20, "2025-03-14T17:30:00-05:00", "n"),
+	))
+	tests := []struct {
+		policy string // "" leaves the flag unset
+		days   map[string]string
+	}{
+		{"", map[string]string{"2025-03-15": "3"}},
+		{"normalize-tz", map[string]string{"2025-03-15": "3"}},
+		{"normalize-utc", map[string]string{"2025-03-14": "3"}},
+		{"honor", map[string]string{"2025-03-14": "2", "2025-03-15": "1"}},
+	}
+	for _, tt := range tests {
+		name := tt.policy
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			args := []string{"-f", "in.json", "-y", "2025", "-m", "3", "-tz", "Asia/Tokyo", "-date-style", "iso",
+				"-report=daily-top-leaders", "-output", "json", "-dump", "dump.jsonl"}
+			if tt.policy != "" {
+				args = append(args, "-iso-offsets", tt.policy)
+			}
+			out, errOut, status := runTool(t, dir, args...)
+			if status != 0 {
+				t.Fatalf("exit %d\n%s", status, errOut)
+			}
+			var doc struct{ Sections []section }
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			days := make(map[string]string)
+			offsets := make(map[string]string)
+			for _, s := range doc.Sections {
+				for _, r := range s.Rows {
+					switch {
+					case s.Key == "daily_top_leaders" && r[2] != "0":
+						days[r[0]] = r[2]
+					case s.Key == "iso_offsets":
+						offsets[r[0]] = r[1]
+					}
+				}
+			}
+			if !reflect.DeepEqual(days, tt.days) {
+				t.Errorf("days %v, want %v", days, tt.days)
+			}
+			policy := tt.policy
+			if policy == "" {
+				policy = "normalize-tz"
+			}
+			want := map[string]string{"Records with a non-zero offset": "2", "Share of dated records": "66.7%", "Policy": policy}
+			if !reflect.DeepEqual(offsets, want) {
+				t.Errorf("iso_offsets rows %v, want %v", offsets, want)
+			}
+			b, err := os.ReadFile(filepath.Join(dir, "dump.jsonl.meta.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var meta dumpMeta
+			if err := json.Unmarshal(b, &meta); err != nil {
+				t.Fatal(err)
+			}
+			if meta.ISOOffsets != policy {
+				t.Errorf("dump provenance records -iso-offsets %q, want %q", meta.ISOOffsets, policy)
+			}
+		})
+	}
+}
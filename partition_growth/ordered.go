@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Reports in this file need the filtered events in timestamp order. They
+// register a consumer with the shared replayer (see replay.go) when prepared
+// and read their results after aggregates.finish has run the replay.
+
+// formatSpan renders a duration as days, hours and minutes ("2d 3h 15m").
+func formatSpan(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	mins := d / time.Minute
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, mins)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
+
+// ── -segments ─────────────────────────────────────────────────────────────────
+
+// segment is a run of events with no gap longer than -gap between neighbours.
+type segment struct {
+	Start, End time.Time
+	Count      int
+	PeakHour   time.Time // start of the busiest clock hour
+	PeakCount  int
+}
+
+// segmenter groups ascending timestamps into segments separated by more than gap.
+type segmenter struct {
+	gap    time.Duration
+	cur    segment
+	hourly map[time.Time]int
+	out    []segment
+}
+
+func newSegmenter(gap time.Duration) *segmenter {
+	return &segmenter{gap: gap, hourly: make(map[time.Time]int)}
+}
+
+func (g *segmenter) add(t time.Time) {
+	if g.cur.Count > 0 && t.Sub(g.cur.End) > g.gap {
+		g.flush()
+	}
+	if g.cur.Count == 0 {
+		g.cur.Start = t
+	}
+	g.cur.End = t
+	g.cur.Count++
+	g.hourly[time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())]++
+}
+
+func (g *segmenter) flush() {
+	for h, n := range g.hourly {
+		if n > g.cur.PeakCount || (n == g.cur.PeakCount && h.Before(g.cur.PeakHour)) {
+			g.cur.PeakHour, g.cur.PeakCount = h, n
+		}
+	}
+	g.out = append(g.out, g.cur)
+	g.cur = segment{}
+	g.hourly = make(map[time.Time]int)
+}
+
+// size reports the closed segments and the open hourly buckets with their
+// approximate bytes.
+func (g *segmenter) size() (int, int64) {
+	return len(g.out) + len(g.hourly), int64(len(g.out))*(5*timeSize) + int64(len(g.hourly))*(timeSize+8+mapEntryOverhead)
+}
+
+// segments returns all segments, closing the one in progress.
+func (g *segmenter) segments() []segment {
+	if g.cur.Count > 0 {
+		g.flush()
+	}
+	return g.out
+}
+
+func prepareSegments(a *aggregates) {
+	a.segments = newSegmenter(a.opts.gap)
+	a.enableReplay().consume(func(r orderedRecord) { a.segments.add(r.time().In(a.opts.loc)) })
+}
+
+func buildSegments(a *aggregates) []section {
+	segs := a.segments.segments()
+	s := section{
+		Key:     "segments",
+		Title:   fmt.Sprintf("Active Segments (gap > %s)", formatSpan(a.opts.gap)),
+		Columns: []string{"#", "Start", "End", "Duration", "Events", "Peak hour"},
+
+		Truncatable: true,
+	}
+	shown := segs
+	if a.opts.maxSegments > 0 && len(shown) > a.opts.maxSegments {
+		shown = shown[:a.opts.maxSegments]
+	}
+	const ts = "2006-01-02 15:04:05"
+	for i, g := range shown {
+		s.Rows = append(s.Rows, []string{
+			strconv.Itoa(i + 1),
+			g.Start.Format(ts),
+			g.End.Format(ts),
+			formatSpan(g.End.Sub(g.Start)),
+			strconv.Itoa(g.Count),
+			fmt.Sprintf("%s (%d)", g.PeakHour.Format("2006-01-02 15:00"), g.PeakCount),
+		})
+	}
+	if len(shown) < len(segs) {
+		s.Notes = append(s.Notes, fmt.Sprintf("... %d more segments not shown (raise -max-segments)", len(segs)-len(shown)))
+	}
+	if len(segs) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	durs := make([]time.Duration, len(segs))
+	for i, g := range segs {
+		durs[i] = g.End.Sub(g.Start)
+	}
+	sort.Slice(durs, func(i, j int) bool { return durs[i] < durs[j] })
+	median := durs[len(durs)/2]
+	if len(durs)%2 == 0 {
+		median = (durs[len(durs)/2-1] + durs[len(durs)/2]) / 2
+	}
+	s.Notes = append(s.Notes,
+		fmt.Sprintf("Segments: %d", len(segs)),
+		fmt.Sprintf("Median duration: %s", formatSpan(median)))
+	return []section{s}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ── Output sections ───────────────────────────────────────────────────────────
+
+// section is one block of report output: a title, a table and trailing notes.
+// The Heading, LineFmt, Bare and NoBlank fields only affect the text format
+// and keep the original line layout that runchk.sh and the report scripts parse.
+type section struct {
+	Key     string // stable identifier used as the JSON "key"
+	Title   string
+	Columns []string
+	Rows    [][]string
+	Notes   []string
+	Data    interface{} // optional typed payload emitted as "data" in JSON
+
+	BarColumn int // 1-based column holding a bar() histogram; drawn as a bar in HTML
+
+	// Example lists and the -max-output-bytes degradation hints (budget.go).
+	Details     []string // example lists printed after Notes; dropped first
+	Truncatable bool     // long table whose trailing rows may be cut
+	Optional    bool     // whole section may be dropped
+
+	Heading string // text heading; "--- Title ---" when empty
+	LineFmt string // text row layout taking one %s per cell; aligned columns when empty
+	Bare    bool   // text: no heading line
+	NoBlank bool   // text: no blank line after the block
+
+	// SubRows nests rows under Rows[i], such as the top contributors of a
+	// bucket. Text prints them indented with SubFmt (one %s per cell), the
+	// other formats as a single-cell row, and JSON as nested arrays.
+	SubRows [][][]string
+	SubFmt  string
+}
+
+var outputFormats = []string{"text", "jira", "json", "html", "html-full", "csv", "tsv", "dot-timeline", "line"}
+
+func validOutputFormat(f string) bool {
+	for _, o := range outputFormats {
+		if o == f {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSectionKeys splits a -sections list.
+func parseSectionKeys(spec string) []string {
+	var keys []string
+	for _, k := range strings.Split(spec, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// selectSections keeps the sections whose Key is listed in keys. Unless
+// keepOrder is set they are arranged in the order of keys, sections sharing
+// a key staying in build order. It also returns the keys that matched no
+// section. Rendering reads nothing but the section itself, so any order
+// renders the same blocks.
+func selectSections(sections []section, keys []string, keepOrder bool) ([]section, []string) {
+	pos := make(map[string]int, len(keys))
+	for i, k := range keys {
+		if _, dup := pos[k]; !dup {
+			pos[k] = i
+		}
+	}
+	var out []section
+	found := make(map[string]bool)
+	for _, s := range sections {
+		if _, ok := pos[s.Key]; ok {
+			out = append(out, s)
+			found[s.Key] = true
+		}
+	}
+	if !keepOrder {
+		sort.SliceStable(out, func(i, j int) bool { return pos[out[i].Key] < pos[out[j].Key] })
+	}
+	var missing []string
+	for _, k := range keys {
+		if !found[k] {
+			missing = append(missing, k)
+		}
+	}
+	return out, missing
+}
+
+// render writes sections in the given -output format. title is only used by
+// formats that produce a complete document.
+func render(w io.Writer, format, title string, sections []section) {
+	switch format {
+	case "jira":
+		writeJira(w, sections)
+	case "json":
+		writeJSON(w, sections)
+	case "html":
+		writeHTML(w, sections)
+	case "html-full":
+		writeHTMLDocument(w, title, time.Now(), sections)
+	case "csv":
+		writeCSV(w, ',', sections)
+	case "tsv":
+		writeCSV(w, '\t', sections)
+	default:
+		writeText(w, sections)
+	}
+}
+
+// ── text ──────────────────────────────────────────────────────────────────────
+
+// writeText renders sections as "--- Title ---" blocks with aligned columns.
+func writeText(w io.Writer, sections []section) {
+	for _, s := range sections {
+		switch {
+		case s.Bare:
+		case s.Heading != "":
+			fmt.Fprintln(w, s.Heading)
+		default:
+			fmt.Fprintf(w, "--- %s ---\n", s.Title)
+		}
+		if s.LineFmt != "" {
+			for ri, row := range s.Rows {
+				args := make([]interface{}, len(row))
+				for i, c := range row {
+					args[i] = c
+				}
+				fmt.Fprintf(w, s.LineFmt+"\n", args...)
+				writeSubRows(w, s, ri)
+			}
+		} else if len(s.Rows) > 0 {
+			widths := make([]int, len(s.Columns))
+			for i, c := range s.Columns {
+				widths[i] = len([]rune(c))
+			}
+			for _, row := range s.Rows {
+				for i, cell := range row {
+					if n := len([]rune(cell)); i < len(widths) && n > widths[i] {
+						widths[i] = n
+					}
+				}
+			}
+			writeTextRow(w, s.Columns, widths)
+			for i, row := range s.Rows {
+				writeTextRow(w, row, widths)
+				writeSubRows(w, s, i)
+			}
+		}
+		for _, n := range s.Notes {
+			fmt.Fprintln(w, n)
+		}
+		for _, n := range s.Details {
+			fmt.Fprintln(w, n)
+		}
+		if !s.NoBlank {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+func writeSubRows(w io.Writer, s section, i int) {
+	for _, line := range s.subLines(i) {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// subLines formats the sub rows of row i with SubFmt.
+func (s section) subLines(i int) []string {
+	if i >= len(s.SubRows) {
+		return nil
+	}
+	var out []string
+	for _, sub := range s.SubRows[i] {
+		args := make([]interface{}, len(sub))
+		for j, c := range sub {
+			args[j] = c
+		}
+		out = append(out, fmt.Sprintf(s.SubFmt, args...))
+	}
+	return out
+}
+
+func writeTextRow(w io.Writer, cells []string, widths []int) {
+	var b strings.Builder
+	for i, c := range cells {
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		pad := 0
+		if i < len(widths) {
+			pad = widths[i] - len([]rune(c))
+		}
+		b.WriteString(c)
+		b.WriteString(strings.Repeat(" ", pad))
+	}
+	fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+}
+
+// ── jira ──────────────────────────────────────────────────────────────────────
+
+// writeJira renders sections as Jira wiki markup: an h3. heading, a
+// ||header|| row, |cell| rows and the notes as plain paragraphs.
+func writeJira(w io.Writer, sections []section) {
+	for _, s := range sections {
+		fmt.Fprintf(w, "h3. %s\n", jiraEscape(s.Title))
+		if len(s.Rows) > 0 {
+			fmt.Fprintln(w, jiraRow(s.Columns, "||"))
+			for i, row := range s.Rows {
+				fmt.Fprintln(w, jiraRow(row, "|"))
+				for _, line := range s.subLines(i) {
+					fmt.Fprintln(w, jiraRow([]string{strings.TrimSpace(line)}, "|"))
+				}
+			}
+		}
+		for _, n := range append(s.Notes, s.Details...) {
+			fmt.Fprintln(w, jiraEscape(n))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func jiraRow(cells []string, sep string) string {
+	var b strings.Builder
+	b.WriteString(sep)
+	for _, c := range cells {
+		if c == "" {
+			c = " " // an empty cell would collapse into the separator
+		}
+		b.WriteString(jiraEscape(c))
+		b.WriteString(sep)
+	}
+	return b.String()
+}
+
+// jiraEscape backslash-escapes the characters that Jira treats as markup
+// inside table cells and headings.
+var jiraEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "{", `\{`, "}", `\}`, "[", `\[`, "]", `\]`)
+
+func jiraEscape(s string) string {
+	return jiraEscaper.Replace(s)
+}
+
+// ── csv / tsv ─────────────────────────────────────────────────────────────────
+
+// writeCSV renders each section as a block of records separated by a blank
+// line: a header of "section" and the columns, then one record per row, all
+// led by the section key so rows stay identifiable when blocks are
+// concatenated or filtered. Nested rows follow their row as single-cell
+// records. Notes and details go in a second block with a "note" column.
+func writeCSV(w io.Writer, comma rune, sections []section) {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	first := true
+	block := func(header []string) {
+		if !first {
+			cw.Flush()
+			fmt.Fprintln(w)
+		}
+		first = false
+		cw.Write(header)
+	}
+	for _, s := range sections {
+		if len(s.Rows) > 0 {
+			block(append([]string{"section"}, s.Columns...))
+			for i, row := range s.Rows {
+				cw.Write(append([]string{s.Key}, row...))
+				for _, line := range s.subLines(i) {
+					cw.Write([]string{s.Key, strings.TrimSpace(line)})
+				}
+			}
+		}
+		if notes := append(append([]string(nil), s.Notes...), s.Details...); len(notes) > 0 {
+			block([]string{"section", "note"})
+			for _, n := range notes {
+				cw.Write([]string{s.Key, n})
+			}
+		}
+	}
+	cw.Flush()
+}
+
+// ── json ──────────────────────────────────────────────────────────────────────
+
+type jsonSection struct {
+	Key     string       `json:"key"`
+	Title   string       `json:"title"`
+	Columns []string     `json:"columns,omitempty"`
+	Rows    [][]string   `json:"rows,omitempty"`
+	Notes   []string     `json:"notes,omitempty"`
+	Details []string     `json:"details,omitempty"`
+	SubRows [][][]string `json:"sub_rows,omitempty"`
+	Data    interface{}  `json:"data,omitempty"`
+}
+
+// writeJSON renders all sections as one indented {"sections": [...]} document.
+func writeJSON(w io.Writer, sections []section) {
+	doc := struct {
+		Sections []jsonSection `json:"sections"`
+		Errors   []reportError `json:"errors,omitempty"`
+	}{Sections: make([]jsonSection, 0, len(sections)), Errors: runErrors}
+	for _, s := range sections {
+		doc.Sections = append(doc.Sections, jsonSection{s.Key, s.Title, s.Columns, s.Rows, s.Notes, s.Details, s.SubRows, s.Data})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(doc)
+}
+
+// ── html ──────────────────────────────────────────────────────────────────────
+
+// The HTML output uses inline style attributes only: Confluence and most mail
+// clients drop <style> blocks and external stylesheets.
+const (
+	htmlTableStyle = `border-collapse:collapse;font-family:sans-serif;font-size:13px;margin:0 0 12px 0`
+	htmlThStyle    = `border:1px solid #ccc;background:#f4f5f7;padding:3px 8px;text-align:left`
+	htmlTdStyle    = `border:1px solid #ccc;padding:3px 8px`
+	htmlBarStyle   = `background:#4c9aff;height:10px;padding:0`
+	htmlBarUnit    = 6 // pixels per bar() character
+)
+
+// writeHTML renders sections as a self-contained HTML fragment: an <h3>
+// and a table per section, notes as paragraphs.
+func writeHTML(w io.Writer, sections []section) {
+	fmt.Fprintln(w, `<div class="partition-growth-report">`)
+	for _, s := range sections {
+		if s.Title != "" {
+			fmt.Fprintf(w, "<h3 style=\"font-family:sans-serif\">%s</h3>\n", html.EscapeString(s.Title))
+		}
+		if len(s.Rows) > 0 {
+			fmt.Fprintf(w, "<table style=\"%s\">\n<tr>", htmlTableStyle)
+			for _, c := range s.Columns {
+				fmt.Fprintf(w, "<th style=\"%s\">%s</th>", htmlThStyle, html.EscapeString(c))
+			}
+			fmt.Fprintln(w, "</tr>")
+			for ri, row := range s.Rows {
+				fmt.Fprint(w, "<tr>")
+				for i, c := range row {
+					if i+1 == s.BarColumn {
+						fmt.Fprintf(w, "<td style=\"%s\">%s</td>", htmlTdStyle, htmlBar(len(c)))
+						continue
+					}
+					fmt.Fprintf(w, "<td style=\"%s\">%s</td>", htmlTdStyle, html.EscapeString(c))
+				}
+				fmt.Fprintln(w, "</tr>")
+				for _, line := range s.subLines(ri) {
+					fmt.Fprintf(w, "<tr><td colspan=\"%d\" style=\"%s;padding-left:24px\">%s</td></tr>\n",
+						len(s.Columns), htmlTdStyle, html.EscapeString(strings.TrimSpace(line)))
+				}
+			}
+			fmt.Fprintln(w, "</table>")
+		}
+		for _, n := range append(s.Notes, s.Details...) {
+			fmt.Fprintf(w, "<p style=\"font-family:sans-serif;font-size:13px;margin:2px 0\">%s</p>\n", html.EscapeString(n))
+		}
+	}
+	fmt.Fprintln(w, "</div>")
+}
+
+// htmlBar draws a bar n units long as a one-cell table, so it renders
+// without CSS classes or JavaScript.
+func htmlBar(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`<table style="border-collapse:collapse"><tr><td style="%s;width:%dpx"></td></tr></table>`,
+		htmlBarStyle, n*htmlBarUnit)
+}
+
+// writeHTMLDocument wraps the writeHTML fragment in a complete document.
+func writeHTMLDocument(w io.Writer, title string, generated time.Time, sections []section) {
+	t := html.EscapeString(title)
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, `<html><head><meta charset="utf-8">`)
+	fmt.Fprintf(w, "<title>%s</title>\n", t)
+	fmt.Fprintln(w, `</head><body style="margin:16px">`)
+	fmt.Fprintf(w, "<h2 style=\"font-family:sans-serif\">%s</h2>\n", t)
+	fmt.Fprintf(w, "<p style=\"font-family:sans-serif;font-size:12px;color:#666\">Generated %s</p>\n",
+		html.EscapeString(generated.UTC().Format(time.RFC3339)))
+	writeHTML(w, sections)
+	fmt.Fprintln(w, "</body></html>")
+}
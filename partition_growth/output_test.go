@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJiraRow(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []string
+		sep   string
+		want  string
+	}{
+		{"header", []string{"Month", "Events"}, "||", "||Month||Events||"},
+		{"cells", []string{"2025-01", "42"}, "|", "|2025-01|42|"},
+		{"empty cell keeps its column", []string{"a", "", "c"}, "|", "|a| |c|"},
+		{"pipe inside a cell", []string{"a|b"}, "|", `|a\|b|`},
+		{"markup characters", []string{`{x} [y] \z`}, "|", `|\{x\} \[y\] \\z|`},
+		{"no cells", nil, "||", "||"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jiraRow(tt.cells, tt.sep); got != tt.want {
+				t.Errorf("jiraRow(%q, %q) = %q, want %q", tt.cells, tt.sep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJira(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []section
+		want     string
+	}{
+		{
+			name: "table with notes",
+			sections: []section{{
+				Title:   "Monthly [all]",
+				Columns: []string{"Month", "Events"},
+				Rows:    [][]string{{"2025-01", "3"}, {"2025-02", ""}},
+				Notes:   []string{"Total: 3"},
+			}},
+			want: "h3. Monthly \\[all\\]\n||Month||Events||\n|2025-01|3|\n|2025-02| |\nTotal: 3\n\n",
+		},
+		{
+			name:     "no rows prints no header",
+			sections: []section{{Title: "Top Weeks", Columns: []string{"Week", "Events"}, Notes: []string{"No events matched."}}},
+			want:     "h3. Top Weeks\nNo events matched.\n\n",
+		},
+		{
+			name: "sub-rows as single cells",
+			sections: []section{{
+				Title:   "Top",
+				Columns: []string{"Month", "Events"},
+				Rows:    [][]string{{"Mar", "9"}},
+				SubRows: [][][]string{{{"n1", "5"}}},
+				SubFmt:  "  %s %s",
+			}},
+			want: "h3. Top\n||Month||Events||\n|Mar|9|\n|n1 5|\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			writeJira(&b, tt.sections)
+			if b.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectSections(t *testing.T) {
+	secs := []section{{Key: "a"}, {Key: "b", Title: "1"}, {Key: "c"}, {Key: "b", Title: "2"}}
+	keys := func(ss []section) string {
+		var out []string
+		for _, s := range ss {
+			out = append(out, s.Key+s.Title)
+		}
+		return strings.Join(out, ",")
+	}
+	tests := []struct {
+		spec      string
+		keepOrder bool
+		want      string
+		missing   []string
+	}{
+		{"c,a", false, "c,a", nil},
+		{"b,a", false, "b1,b2,a", nil},
+		{"c, b ,c", false, "c,b1,b2", nil},
+		{"c,a", true, "a,c", nil},
+		{"x,a,y", false, "a", []string{"x", "y"}},
+	}
+	for _, tt := range tests {
+		got, missing := selectSections(secs, parseSectionKeys(tt.spec), tt.keepOrder)
+		if keys(got) != tt.want || !reflect.DeepEqual(missing, tt.missing) {
+			t.Errorf("%q keepOrder %v: %s missing %q, want %s missing %q", tt.spec, tt.keepOrder, keys(got), missing, tt.want, tt.missing)
+		}
+	}
+}
+
+// TestSectionsOrder renders the same run with the sections in two orders.
+// Each order must be exactly the sections rendered one at a time, so no
+// section depends on another having been printed first; JSON keeps its
+// order either way.
+func TestSectionsOrder(t *testing.T) {
+	dir := t.TempDir()
+	var records []string
+	for i := 0; i < 30; i++ {
+		records = append(records, event(10*i+1, fmt.Sprintf("2025-%02d-%02dT10:00:00Z", 1+i%4, 1+i%28), "n"))
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+	keys := []string{"month_weekly", "top_weeks", "year"}
+	orders := [][]string{keys, {keys[2], keys[1], keys[0]}}
+	run := func(format string, keys ...string) string {
+		out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2025", "-m", "3", "-t", "-week",
+			"-output", format, "-sections", strings.Join(keys, ","))
+		if status != 0 {
+			t.Fatalf("exit %d\n%s", status, errOut)
+		}
+		return out
+	}
+	// body drops the wrapper the HTML fragment puts around the sections.
+	body := func(format, out string) string {
+		if format == "html" {
+			out = strings.TrimPrefix(out, "<div class=\"partition-growth-report\">\n")
+			out = strings.TrimSuffix(out, "</div>\n")
+		}
+		return out
+	}
+	for _, format := range []string{"text", "html", "jira"} {
+		t.Run(format, func(t *testing.T) {
+			alone := make(map[string]string)
+			for _, k := range keys {
+				alone[k] = body(format, run(format, k))
+				if alone[k] == "" {
+					t.Fatalf("section %s rendered nothing", k)
+				}
+			}
+			for _, order := range orders {
+				var want strings.Builder
+				for _, k := range order {
+					want.WriteString(alone[k])
+				}
+				if got := body(format, run(format, order...)); got != want.String() {
+					t.Errorf("-sections %s is not its sections rendered alone\n got:\n%s\nwant:\n%s", strings.Join(order, ","), got, want.String())
+				}
+			}
+		})
+	}
+	if a, b := run("json", orders[0]...), run("json", orders[1]...); a != b {
+		t.Errorf("JSON differs between -sections orders:\n%s\n%s", a, b)
+	}
+}
+
+// TestMaxOutputBytes renders a -group-by table, in json and text, under a
+// budget it fits once truncated and under one too small for any form: the
+// JSON stays valid, a fitting run keeps to the budget with the truncation
+// note, and a missed budget is warned about.
+func TestMaxOutputBytes(t *testing.T) {
+	dir := t.TempDir()
+	var records []string
+	for i := 0; i < 200; i++ {
+		records = append(records, event(10*i, fmt.Sprintf("2025-03-%02dT%02d:00:00Z", 1+i%28, i%24), fmt.Sprintf("n%d", i%30)))
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+	tests := []struct {
+		format string
+		max    int
+		fits   bool
+	}{
+		{"json", 2000, true},
+		{"json", 100, false},
+		{"text", 550, true},
+		{"text", 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.format, tt.max), func(t *testing.T) {
+			out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2025", "-m", "3", "-group-by", "leader",
+				"-output", tt.format, "-max-output-bytes", strconv.Itoa(tt.max))
+			if status != 0 {
+				t.Fatalf("exit %d\n%s", status, errOut)
+			}
+			if tt.format == "json" && !json.Valid([]byte(out)) {
+				t.Errorf("invalid JSON:\n%s", out)
+			}
+			missed := strings.Contains(errOut, "over -max-output-bytes")
+			if tt.fits {
+				if len(out) > tt.max {
+					t.Errorf("%d bytes, over the budget", len(out))
+				}
+				if !strings.Contains(out, "(truncated: showing 10 of 20 rows)") {
+					t.Errorf("no truncation note:\n%s", out)
+				}
+			}
+			if missed == tt.fits {
+				t.Errorf("budget missed %v, want %v:\n%s", missed, !tt.fits, errOut)
+			}
+		})
+	}
+}
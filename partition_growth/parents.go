@@ -0,0 +1,593 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── parent-lifecycle ──────────────────────────────────────────────────────────
+
+// parentSpan is the first and last filtered event of one ParentID.
+type parentSpan struct {
+	First, Last time.Time
+	Count       int
+}
+
+func (a *aggregates) enableParentSpans() {
+	if a.parentSpans == nil {
+		a.parentSpans = make(map[int]*parentSpan)
+	}
+}
+
+func (a *aggregates) addParentSpan(dt time.Time, parent int) {
+	p := a.parentSpans[parent]
+	if p == nil {
+		a.parentSpans[parent] = &parentSpan{First: dt, Last: dt, Count: 1}
+		return
+	}
+	if dt.Before(p.First) {
+		p.First = dt
+	}
+	if dt.After(p.Last) {
+		p.Last = dt
+	}
+	p.Count++
+}
+
+// minParents returns -min-parent-events, or def when it is not set.
+func (o reportOptions) minParents(def int) int {
+	if o.minParentEvents == 0 {
+		return def
+	}
+	return o.minParentEvents
+}
+
+func prepareParentLifecycle(a *aggregates, _ string) error {
+	if a.opts.minParentEvents < 0 {
+		return fmt.Errorf("-min-parent-events must be at least 1")
+	}
+	a.enableParentSpans()
+	return nil
+}
+
+// buildParentLifecycle lists each ParentID with at least -min-parent-events
+// events, from its first to its last event, earliest first.
+func buildParentLifecycle(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "parent_lifecycle",
+		Title:   "ParentID Lifecycle",
+		Columns: []string{"ParentID", "First event", "Last event", "Lifespan (days)", "Events"},
+
+		Truncatable: true,
+	}
+	least := a.opts.minParents(1)
+	ids := make([]int, 0, len(a.parentSpans))
+	for id, p := range a.parentSpans {
+		if p.Count >= least {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		pi, pj := a.parentSpans[ids[i]], a.parentSpans[ids[j]]
+		if !pi.First.Equal(pj.First) {
+			return pi.First.Before(pj.First)
+		}
+		return ids[i] < ids[j]
+	})
+	const ts = "2006-01-02 15:04:05"
+	for _, id := range ids {
+		p := a.parentSpans[id]
+		s.Rows = append(s.Rows, []string{
+			strconv.Itoa(id),
+			p.First.In(a.opts.loc).Format(ts),
+			p.Last.In(a.opts.loc).Format(ts),
+			strconv.FormatFloat(p.Last.Sub(p.First).Hours()/24, 'f', 1, 64),
+			strconv.Itoa(p.Count),
+		})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Parents listed: %d of %d (minimum %d events)",
+			len(ids), len(a.parentSpans), least))
+	}
+	return []section{s}
+}
+
+// ── parent-event-rate ─────────────────────────────────────────────────────────
+
+const defaultRateMinEvents = 10
+
+// buildParentEventRate lists each ParentID with at least -min-parent-events
+// events (default 10) by its average events per day over the span from its
+// first to its last filtered event, highest rate first. A span shorter than
+// a day counts as one day, so a burst within hours is not inflated.
+func buildParentEventRate(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "parent_event_rate",
+		Title:   "ParentID Event Rate",
+		Columns: []string{"ParentID", "Events", "Span (days)", "Events per day"},
+
+		Truncatable: true,
+	}
+	least := a.opts.minParents(defaultRateMinEvents)
+	type rate struct {
+		id         int
+		span, rate float64
+	}
+	var rates []rate
+	for id, p := range a.parentSpans {
+		if p.Count < least {
+			continue
+		}
+		span := max(p.Last.Sub(p.First).Hours()/24, 1)
+		rates = append(rates, rate{id, span, float64(p.Count) / span})
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].rate != rates[j].rate {
+			return rates[i].rate > rates[j].rate
+		}
+		return rates[i].id < rates[j].id
+	})
+	for _, r := range rates {
+		s.Rows = append(s.Rows, []string{
+			strconv.Itoa(r.id),
+			strconv.Itoa(a.parentSpans[r.id].Count),
+			strconv.FormatFloat(r.span, 'f', 1, 64),
+			strconv.FormatFloat(r.rate, 'f', 2, 64),
+		})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Parents listed: %d of %d (minimum %d events)",
+			len(rates), len(a.parentSpans), least))
+	}
+	return []section{s}
+}
+
+// ── inactive-parents ──────────────────────────────────────────────────────────
+
+const defaultInactiveDays = 7
+
+// parseInactiveDays reads the inactive-parents argument, the silence in days
+// after which a parent is listed.
+func parseInactiveDays(arg string) (int, error) {
+	if arg == "" {
+		return defaultInactiveDays, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(arg, "d"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid day count %q", arg)
+	}
+	return n, nil
+}
+
+func prepareInactiveParents(a *aggregates, arg string) error {
+	if _, err := parseInactiveDays(arg); err != nil {
+		return err
+	}
+	a.enableParentSpans()
+	return nil
+}
+
+// buildInactiveParents lists the ParentIDs whose last filtered event is more
+// than N days before the latest filtered event of any parent, longest silent
+// first. Measuring against the data rather than the clock keeps the report
+// meaningful for historical extracts.
+func buildInactiveParents(a *aggregates, arg string) []section {
+	days, _ := parseInactiveDays(arg)
+	s := section{
+		Key:     "inactive_parents",
+		Title:   fmt.Sprintf("Inactive ParentIDs (no events in the last %d days)", days),
+		Columns: []string{"ParentID", "Last seen", "Days since last event"},
+
+		Truncatable: true,
+	}
+	var latest time.Time
+	for _, p := range a.parentSpans {
+		if p.Last.After(latest) {
+			latest = p.Last
+		}
+	}
+	if latest.IsZero() {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	limit := time.Duration(days) * 24 * time.Hour
+	var ids []int
+	for id, p := range a.parentSpans {
+		if latest.Sub(p.Last) > limit {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		li, lj := a.parentSpans[ids[i]].Last, a.parentSpans[ids[j]].Last
+		if !li.Equal(lj) {
+			return li.Before(lj)
+		}
+		return ids[i] < ids[j]
+	})
+	const ts = "2006-01-02 15:04:05"
+	for _, id := range ids {
+		last := a.parentSpans[id].Last
+		s.Rows = append(s.Rows, []string{
+			strconv.Itoa(id),
+			last.In(a.opts.loc).Format(ts),
+			strconv.Itoa(int(latest.Sub(last) / (24 * time.Hour))),
+		})
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Inactive parents: %d of %d; latest event %s",
+		len(ids), len(a.parentSpans), latest.In(a.opts.loc).Format(ts)))
+	return []section{s}
+}
+
+// ── top-n-parents-by-month ────────────────────────────────────────────────────
+
+const defaultTopParents = 3
+
+// parseTopParents reads the top-n-parents-by-month argument, the number of
+// parents listed per month.
+func parseTopParents(arg string) (int, error) {
+	if arg == "" {
+		return defaultTopParents, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid count %q", arg)
+	}
+	return n, nil
+}
+
+func prepareTopParentsByMonth(a *aggregates, arg string) error {
+	if a.opts.year == 0 {
+		return fmt.Errorf("requires -y")
+	}
+	if _, err := parseTopParents(arg); err != nil {
+		return err
+	}
+	if a.monthParentCounts == nil {
+		a.monthParentCounts = make(map[string]map[int]int)
+	}
+	return nil
+}
+
+// buildTopParentsByMonth emits one section per month of -y (or the -m
+// month) with its N busiest ParentIDs and their share of the month's events.
+func buildTopParentsByMonth(a *aggregates, arg string) []section {
+	n, _ := parseTopParents(arg)
+	first, last := 1, 12
+	if a.opts.month != 0 {
+		first, last = a.opts.month, a.opts.month
+	}
+	var out []section
+	for m := first; m <= last; m++ {
+		counts := a.monthParentCounts[fmt.Sprintf("%04d-%02d", a.opts.year, m)]
+		s := section{
+			Key:     fmt.Sprintf("top_parents_%04d_%02d", a.opts.year, m),
+			Title:   fmt.Sprintf("Top %d ParentIDs, %s", n, a.opts.dates.month(a.opts.year, m)),
+			Columns: []string{"ParentID", "Events", "Share"},
+		}
+		total := 0
+		ids := make([]int, 0, len(counts))
+		for id, c := range counts {
+			ids = append(ids, id)
+			total += c
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			if counts[ids[i]] != counts[ids[j]] {
+				return counts[ids[i]] > counts[ids[j]]
+			}
+			return ids[i] < ids[j]
+		})
+		if len(ids) > n {
+			ids = ids[:n]
+		}
+		for _, id := range ids {
+			s.Rows = append(s.Rows, []string{strconv.Itoa(id), strconv.Itoa(counts[id]), pct(counts[id], total)})
+		}
+		if total == 0 {
+			s.Notes = append(s.Notes, "No events matched.")
+		} else {
+			s.Notes = append(s.Notes, fmt.Sprintf("Month total: %d events from %d parents", total, len(counts)))
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// ── parent-transitions ────────────────────────────────────────────────────────
+
+func (a *aggregates) addParentMonthLeader(dt time.Time, evt Event) {
+	months := a.parentMonthLeaders[evt.ParentID]
+	if months == nil {
+		months = make(map[string]map[string]int)
+		a.parentMonthLeaders[evt.ParentID] = months
+	}
+	mk := dt.Format("2006-01")
+	m := months[mk]
+	if m == nil {
+		m = make(map[string]int)
+		months[mk] = m
+	}
+	m[evt.LeaderNodeInfo] += evt.Weight
+}
+
+func prepareParentTransitions(a *aggregates, _ string) error {
+	if a.parentMonthLeaders == nil {
+		a.parentMonthLeaders = make(map[int]map[string]map[string]int)
+	}
+	return nil
+}
+
+// dominantLeader returns the leader with most events, the lowest name on ties.
+func dominantLeader(counts map[string]int) string {
+	best, bestN := "", 0
+	for l, n := range counts {
+		if n > bestN || (n == bestN && l < best) {
+			best, bestN = l, n
+		}
+	}
+	return best
+}
+
+// leaderTransition is a change of a parent's dominant leader.
+type leaderTransition struct {
+	Parent   int
+	Month    string // "YYYY-MM" of the new leader
+	Old, New string
+}
+
+// buildParentTransitions compares each ParentID's dominant leader in every
+// month with events against its previous month with events and lists the
+// changes, in month order.
+func buildParentTransitions(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "parent_transitions",
+		Title:   "ParentID Leader Transitions",
+		Columns: []string{"ParentID", "Month", "Old leader", "New leader"},
+
+		Truncatable: true,
+	}
+	var ts []leaderTransition
+	moved := 0
+	for id, months := range a.parentMonthLeaders {
+		keys := make([]string, 0, len(months))
+		for k := range months {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		prev := ""
+		n := len(ts)
+		for i, k := range keys {
+			cur := dominantLeader(months[k])
+			if i > 0 && cur != prev {
+				ts = append(ts, leaderTransition{id, k, prev, cur})
+			}
+			prev = cur
+		}
+		if len(ts) > n {
+			moved++
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Month != ts[j].Month {
+			return ts[i].Month < ts[j].Month
+		}
+		return ts[i].Parent < ts[j].Parent
+	})
+	for _, t := range ts {
+		y, _ := strconv.Atoi(t.Month[:4])
+		m, _ := strconv.Atoi(t.Month[5:])
+		s.Rows = append(s.Rows, []string{strconv.Itoa(t.Parent), a.opts.dates.month(y, m), t.Old, t.New})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Transitions: %d across %d of %d parents; the dominant leader is the one with most events in the month",
+			len(ts), moved, len(a.parentMonthLeaders)))
+	}
+	return []section{s}
+}
+
+// ── parent-child-matrix ───────────────────────────────────────────────────────
+
+const coActivityTop = 20
+
+// coActivity counts, for each pair of ParentIDs, the clock hours in which
+// both had an event. It consumes the ordered replay, so only the parents of
+// the current hour are held besides the pair counts.
+type coActivity struct {
+	hour    time.Time
+	parents map[int]bool
+	pairs   map[[2]int]int // lower ID first
+	hours   int
+}
+
+func (c *coActivity) add(t time.Time, parent int) {
+	h := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	if !h.Equal(c.hour) {
+		c.flush()
+		c.hour = h
+	}
+	c.parents[parent] = true
+}
+
+// flush counts every pair in the finished hour; O(k²) in its k parents.
+func (c *coActivity) flush() {
+	if len(c.parents) == 0 {
+		return
+	}
+	ids := make([]int, 0, len(c.parents))
+	for p := range c.parents {
+		ids = append(ids, p)
+	}
+	sort.Ints(ids)
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			c.pairs[[2]int{ids[i], ids[j]}]++
+		}
+	}
+	c.hours++
+	c.parents = make(map[int]bool)
+}
+
+func prepareParentChildMatrix(a *aggregates, _ string) error {
+	c := &coActivity{parents: make(map[int]bool), pairs: make(map[[2]int]int)}
+	a.coActivity = c
+	a.enableReplay().consume(func(r orderedRecord) { c.add(r.time().In(a.opts.loc), r.Parent) })
+	return nil
+}
+
+// buildParentChildMatrix lists the pairs of ParentIDs active in the most
+// common clock hours.
+func buildParentChildMatrix(a *aggregates, _ string) []section {
+	c := a.coActivity
+	c.flush()
+	type pair struct {
+		ids   [2]int
+		hours int
+	}
+	pairs := make([]pair, 0, len(c.pairs))
+	for k, n := range c.pairs {
+		pairs = append(pairs, pair{k, n})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].hours != pairs[j].hours {
+			return pairs[i].hours > pairs[j].hours
+		}
+		if pairs[i].ids[0] != pairs[j].ids[0] {
+			return pairs[i].ids[0] < pairs[j].ids[0]
+		}
+		return pairs[i].ids[1] < pairs[j].ids[1]
+	})
+	if len(pairs) > coActivityTop {
+		pairs = pairs[:coActivityTop]
+	}
+	s := section{
+		Key:     "parent_child_matrix",
+		Title:   fmt.Sprintf("Top %d Co-active ParentID Pairs (same clock hour)", coActivityTop),
+		Columns: []string{"ParentID", "ParentID", "Shared hours"},
+	}
+	for _, p := range pairs {
+		s.Rows = append(s.Rows, []string{strconv.Itoa(p.ids[0]), strconv.Itoa(p.ids[1]), strconv.Itoa(p.hours)})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Active hours: %d; distinct pairs: %d", c.hours, len(c.pairs)))
+	}
+	return []section{s}
+}
+
+// ── event-overlap-window ──────────────────────────────────────────────────────
+
+const (
+	defaultOverlapWindow = time.Minute
+	overlapMaxRows       = 100
+)
+
+// overlapStorm is a maximal run of windows with at least -min-parents
+// distinct ParentIDs: from the first event of the first such window to the
+// last event of the last one.
+type overlapStorm struct {
+	start, end time.Time
+	events     int
+	peak       int // most distinct parents in one window
+}
+
+// overlapScan slides a window of length width over the ordered replay,
+// keeping only the events inside it and a count per parent.
+type overlapScan struct {
+	width  time.Duration
+	least  int
+	window []orderedRecord
+	counts map[int]int
+	open   *overlapStorm
+	storms []overlapStorm
+}
+
+func (o *overlapScan) add(r orderedRecord) {
+	t := r.time()
+	o.window = append(o.window, r)
+	o.counts[r.Parent]++
+	drop := 0
+	for ; t.Sub(o.window[drop].time()) > o.width; drop++ {
+		old := o.window[drop].Parent
+		if o.counts[old]--; o.counts[old] == 0 {
+			delete(o.counts, old)
+		}
+	}
+	o.window = o.window[drop:]
+	if len(o.counts) < o.least {
+		o.close()
+		return
+	}
+	if o.open == nil {
+		o.open = &overlapStorm{start: o.window[0].time(), events: len(o.window) - 1}
+	}
+	o.open.end = t
+	o.open.events++
+	o.open.peak = max(o.open.peak, len(o.counts))
+}
+
+func (o *overlapScan) close() {
+	if o.open != nil {
+		o.storms = append(o.storms, *o.open)
+		o.open = nil
+	}
+}
+
+func parseOverlapWindow(arg string) (time.Duration, error) {
+	if arg == "" {
+		return defaultOverlapWindow, nil
+	}
+	d, err := parseSpan(arg)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", arg)
+	}
+	return d, nil
+}
+
+func prepareEventOverlapWindow(a *aggregates, arg string) error {
+	width, err := parseOverlapWindow(arg)
+	if err != nil {
+		return err
+	}
+	o := &overlapScan{width: width, least: a.opts.overlapParents, counts: make(map[int]int)}
+	a.overlap = o
+	a.enableReplay().consume(o.add)
+	return nil
+}
+
+// buildEventOverlapWindow lists, in time order, the storms where events of
+// at least -min-parents distinct ParentIDs fell within one window.
+func buildEventOverlapWindow(a *aggregates, _ string) []section {
+	const ts = "2006-01-02 15:04:05"
+	o := a.overlap
+	o.close()
+	s := section{
+		Key:     "event_overlap_window",
+		Title:   fmt.Sprintf("Windows of %s with %d+ ParentIDs", shortSpan(o.width), o.least),
+		Columns: []string{"From", "To", "Events", "Peak parents"},
+	}
+	for i, st := range o.storms {
+		if i == overlapMaxRows {
+			s.Notes = append(s.Notes, fmt.Sprintf("%d more windows not shown", len(o.storms)-i))
+			break
+		}
+		s.Rows = append(s.Rows, []string{st.start.In(a.opts.loc).Format(ts), st.end.In(a.opts.loc).Format(ts),
+			strconv.Itoa(st.events), strconv.Itoa(st.peak)})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else if len(o.storms) == 0 {
+		s.Notes = append(s.Notes, "No window reached -min-parents.")
+	} else {
+		s.Notes = append(s.Notes, "Overlapping windows are merged into one row from the first to the last event they cover.")
+	}
+	return []section{s}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ── -plugin ───────────────────────────────────────────────────────────────────
+//
+// A plugin is a Go plugin (.so) built with "go build -buildmode=plugin" against
+// this module. It exports a variable named Aggregator whose methods satisfy
+// the Aggregator interface; Event is partition_growth/events.Event:
+//
+//	package main
+//
+//	type score struct{ n int }
+//
+//	func (s *score) Process(evt events.Event, t time.Time) { s.n += evt.Value }
+//	func (s *score) Report(w io.Writer)                     { fmt.Fprintf(w, "score: %d\n", s.n) }
+//
+//	var Aggregator score
+//
+// Process is called once per filtered event, never concurrently; Report's
+// output becomes a section of its own. Loading is only available where the
+// plugin package is (see plugins_supported.go).
+
+// Aggregator is the interface a plugin's exported Aggregator must implement.
+type Aggregator interface {
+	Process(evt Event, t time.Time)
+	Report(w io.Writer)
+}
+
+// loadedPlugin is one -plugin with the name used for its section.
+type loadedPlugin struct {
+	name string
+	agg  Aggregator
+}
+
+// loadPlugins opens every path; any failure is fatal to the run.
+func loadPlugins(paths []string) ([]loadedPlugin, error) {
+	var out []loadedPlugin
+	for _, p := range paths {
+		agg, err := openPlugin(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		out = append(out, loadedPlugin{name: name, agg: agg})
+	}
+	return out, nil
+}
+
+// section captures the plugin's report, one note per line.
+func (p loadedPlugin) section() section {
+	var b bytes.Buffer
+	p.agg.Report(&b)
+	s := section{Key: "plugin_" + p.name, Title: "Plugin: " + p.name}
+	if text := strings.TrimRight(b.String(), "\n"); text != "" {
+		s.Notes = strings.Split(text, "\n")
+	}
+	return s
+}
+
+// pluginList collects repeated -plugin flags.
+type pluginList []string
+
+func (p *pluginList) String() string { return strings.Join(*p, ",") }
+
+func (p *pluginList) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package main
+
+import "errors"
+
+// openPlugin is unavailable: the plugin package needs cgo on Linux, macOS or
+// FreeBSD.
+func openPlugin(string) (Aggregator, error) {
+	return nil, errors.New("plugins are not supported in this build")
+}
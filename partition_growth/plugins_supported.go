@@ -0,0 +1,25 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package main
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// openPlugin loads path and returns its exported Aggregator.
+func openPlugin(path string) (Aggregator, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("Aggregator")
+	if err != nil {
+		return nil, err
+	}
+	agg, ok := sym.(Aggregator)
+	if !ok {
+		return nil, fmt.Errorf("Aggregator is %T, which does not implement Process(events.Event, time.Time) and Report(io.Writer)", sym)
+	}
+	return agg, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ── -post ─────────────────────────────────────────────────────────────────────
+//
+// The filtered counts per bucket are sent to a collector as NDJSON batches in
+// gzip-compressed POST bodies. A 5xx response or a transport error is retried
+// with exponential backoff; a 4xx response is fatal and its body is part of
+// the error, since resending the same batch cannot succeed.
+
+// postGranularities are the -post-granularity values.
+var postGranularities = []string{"day", "week", "month"}
+
+// postRecord is one NDJSON line of a batch.
+type postRecord struct {
+	Bucket      string `json:"bucket"`
+	Granularity string `json:"granularity"`
+	Count       int    `json:"count"`
+}
+
+// poster sends batches to one collector URL.
+type poster struct {
+	url        string
+	token      string // bearer token; empty sends no Authorization header
+	batchSize  int
+	maxRetries int
+	backoff    time.Duration // first retry delay, doubled on each retry
+	client     *http.Client
+
+	batches, records, retries int
+}
+
+func newPoster(url, token string, batchSize, maxRetries int) *poster {
+	return &poster{
+		url:        url,
+		token:      token,
+		batchSize:  batchSize,
+		maxRetries: maxRetries,
+		backoff:    time.Second,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func validPostGranularity(g string) bool {
+	return slices.Contains(postGranularities, g)
+}
+
+// postRecords turns the filtered counts into records of the given
+// granularity, in bucket order.
+func postRecords(a *aggregates, granularity string) []postRecord {
+	keys, counts := bucketCounts(a, granularity)
+	recs := make([]postRecord, len(keys))
+	for i, k := range keys {
+		recs[i] = postRecord{k, granularity, counts[k]}
+	}
+	return recs
+}
+
+func encodeBatch(recs []postRecord) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range recs {
+		enc.Encode(r)
+	}
+	return buf.Bytes()
+}
+
+// dryRun writes the first batch, uncompressed, instead of sending anything.
+func (p *poster) dryRun(w io.Writer, recs []postRecord) error {
+	if len(recs) > p.batchSize {
+		recs = recs[:p.batchSize]
+	}
+	_, err := w.Write(encodeBatch(recs))
+	return err
+}
+
+// send posts recs in batches and stops at the first batch that fails.
+func (p *poster) send(recs []postRecord) error {
+	for start := 0; start < len(recs); start += p.batchSize {
+		end := min(start+p.batchSize, len(recs))
+		if err := p.sendBatch(encodeBatch(recs[start:end])); err != nil {
+			return fmt.Errorf("batch %d (records %d-%d): %v", p.batches+1, start+1, end, err)
+		}
+		p.batches++
+		p.records += end - start
+	}
+	return nil
+}
+
+func (p *poster) sendBatch(ndjson []byte) error {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	zw.Write(ndjson)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	delay := p.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := p.post(body.Bytes())
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == p.maxRetries {
+			return err
+		}
+		fmt.Fprintf(noteOut, "note: -post: %v; retrying in %s\n", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		p.retries++
+	}
+}
+
+// post makes one request; retry reports whether a failure may be transient.
+func (p *poster) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("server error %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return false, fmt.Errorf("rejected with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return false, nil
+}
+
+// summary is the final line of a -post run.
+func (p *poster) summary() string {
+	return fmt.Sprintf("post: %d batches sent, %d records posted, %d retries", p.batches, p.records, p.retries)
+}
+
+// postResults sends or, with dryRun, prints the results and reports the
+// outcome on stderr.
+func postResults(p *poster, recs []postRecord, dryRun bool) error {
+	if dryRun {
+		return p.dryRun(reportOut, recs)
+	}
+	err := p.send(recs)
+	fmt.Fprintln(noteOut, p.summary())
+	return err
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ── -preset ───────────────────────────────────────────────────────────────────
+//
+// A preset is a named list of command-line arguments. The arguments of every
+// -preset are placed before the explicit ones, so an explicit flag overrides
+// a preset's value; repeatable flags (-f, -report) accumulate instead. More
+// presets, or replacements for the built-in ones, can be defined in a -config
+// file:
+//
+//	{"presets": {"nightly": ["-y", "2025", "-report", "coverage"]}}
+//
+// Configured presets are parsed against the real flag set when the file is
+// loaded, so a typo is reported even if that preset is not used.
+
+// builtinPresets are the standard invocations.
+var builtinPresets = map[string][]string{
+	// Day-to-day health: unusual days, silent parents and leaders.
+	"weekly-ops": {
+		"-report", "daily-change-rate,event-burst-sequence,inactive-parents",
+		"-leader-freshness", "24h", "-leader-freshness-crit", "72h",
+	},
+	// Growth and projection.
+	"capacity": {
+		"-a", "-report", "forecast=3m,rolling-7d-percentile,event-frequency-table",
+		"-aggregate", "count,sum,max",
+	},
+	// An extract of the filtered events with the run's own bookkeeping.
+	"audit": {
+		"-dump", "audit.jsonl", "-telemetry",
+	},
+}
+
+// presetFile is the -config file layout.
+type presetFile struct {
+	Presets map[string][]string `json:"presets"`
+}
+
+// expandPresets returns args with the arguments of each -preset inserted in
+// front. It handles -config and -print-preset itself, because both must take
+// effect before the flags are parsed; -print-preset exits after printing.
+func expandPresets(fs *flag.FlagSet, args []string) ([]string, error) {
+	presets := make(map[string][]string, len(builtinPresets))
+	for k, v := range builtinPresets {
+		presets[k] = v
+	}
+	if path, ok := scanFlag(args, "config"); ok {
+		if err := loadPresets(fs, path, presets); err != nil {
+			return nil, fmt.Errorf("-config: %v", err)
+		}
+	}
+	if name, ok := scanFlag(args, "print-preset"); ok {
+		p, ok := presets[name]
+		if !ok {
+			return nil, unknownPreset(name, presets)
+		}
+		printPreset(os.Stdout, name, p)
+		os.Exit(0)
+	}
+	var front []string
+	for _, name := range scanFlags(args, "preset") {
+		p, ok := presets[name]
+		if !ok {
+			return nil, unknownPreset(name, presets)
+		}
+		front = append(front, p...)
+	}
+	return append(front, args...), nil
+}
+
+func loadPresets(fs *flag.FlagSet, path string, presets map[string][]string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg presetFile
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return err
+	}
+	for name, args := range cfg.Presets {
+		if err := validatePreset(fs, args); err != nil {
+			return fmt.Errorf("preset %q: %v", name, err)
+		}
+		presets[name] = args
+	}
+	return nil
+}
+
+// validatePreset parses args against a copy of fs whose values are fresh, so
+// every flag's own validation runs without changing the real settings.
+func validatePreset(fs *flag.FlagSet, args []string) error {
+	check := flag.NewFlagSet("preset", flag.ContinueOnError)
+	check.SetOutput(io.Discard)
+	fs.VisitAll(func(f *flag.Flag) {
+		v := reflect.New(reflect.TypeOf(f.Value).Elem()).Interface().(flag.Value)
+		check.Var(v, f.Name, f.Usage)
+	})
+	if err := check.Parse(args); err != nil {
+		return err
+	}
+	if check.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", check.Arg(0))
+	}
+	for _, name := range []string{"preset", "config", "print-preset"} {
+		if _, ok := scanFlag(args, name); ok {
+			return fmt.Errorf("-%s cannot be used inside a preset", name)
+		}
+	}
+	return nil
+}
+
+func unknownPreset(name string, presets map[string][]string) error {
+	names := make([]string, 0, len(presets))
+	for n := range presets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+func printPreset(w io.Writer, name string, args []string) {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = a
+		if a == "" || strings.ContainsAny(a, " \t'\"*?[]$") {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	fmt.Fprintf(w, "-preset %s = %s\n", name, strings.Join(quoted, " "))
+}
+
+// scanFlags returns the values of every -name or --name flag in args, in
+// either the "-name value" or "-name=value" form. Scanning stops at "--".
+func scanFlags(args []string, name string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		a = strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		if a == args[i] {
+			continue
+		}
+		if v, ok := strings.CutPrefix(a, name+"="); ok {
+			out = append(out, v)
+		} else if a == name && i+1 < len(args) {
+			out = append(out, args[i+1])
+			i++
+		}
+	}
+	return out
+}
+
+// scanFlag returns the last value of -name in args.
+func scanFlag(args []string, name string) (string, bool) {
+	vals := scanFlags(args, name)
+	if len(vals) == 0 {
+		return "", false
+	}
+	return vals[len(vals)-1], true
+}
+
+// presetList registers -preset so the parser accepts it; expandPresets has
+// already applied the values.
+type presetList []string
+
+func (p *presetList) String() string { return strings.Join(*p, ",") }
+
+func (p *presetList) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"partition_growth/events"
+)
+
+// ── Record errors ─────────────────────────────────────────────────────────────
+
+// recordErrors counts decoded records that could not be placed in time. A
+// missing date (absent, null, empty or blank, or any date resolving to the
+// zero time) is kept apart from an unparseable one: the first usually means
+// an upstream schema change, the second a new date format.
+//
+// It also counts self-referential records, a parent equal to one of its
+// children or two equal children, which point to an upstream bug. They are
+// kept in the counts unless -drop-self-refs is set.
+//
+// Records with an ID outside -id-min..-id-max, or beyond int64 in the input,
+// usually come from a corrupted export. They are counted the same way and
+// kept unless -drop-bad-ids is set.
+type recordErrors struct {
+	missingDate, badDate int
+
+	dropSelfRefs             bool
+	selfParent, selfChildren int
+	selfExamples             []string
+
+	dropBadIDs   bool
+	idMin, idMax int64
+	badIDs       int
+	idExamples   []string
+}
+
+// maxRecordExamples is the number of self-referential and of bad-ID records
+// listed.
+const maxRecordExamples = 5
+
+// classify returns whether the parse result is usable, counting it otherwise.
+// Zero times are rejected even when parsing succeeded, so a more lenient
+// decoder can never put events into year 1 buckets.
+func (r *recordErrors) classify(dt time.Time, err error) bool {
+	switch {
+	case errors.Is(err, events.ErrMissingDate), err == nil && dt.IsZero():
+		r.missingDate++
+	case err != nil:
+		r.badDate++
+	default:
+		return true
+	}
+	return false
+}
+
+// isSelfRef reports whether evt's parent equals a child or its children are
+// equal.
+func isSelfRef(evt Event) bool {
+	return parentIsChild(evt) || sameID(evt.FirstChildID, evt.SecondChildID)
+}
+
+func parentIsChild(evt Event) bool {
+	return sameID(evt.ParentID, evt.FirstChildID) || sameID(evt.ParentID, evt.SecondChildID)
+}
+
+// sameID compares two IDs of a record. An absent or null ID decodes as 0, so
+// two zero IDs are not taken for the same one.
+func sameID(a, b int) bool {
+	return a == b && a != 0
+}
+
+// selfRef counts a self-referential record and returns whether it is kept.
+func (r *recordErrors) selfRef(dt time.Time, evt Event) bool {
+	if parentIsChild(evt) {
+		r.selfParent++
+	} else {
+		r.selfChildren++
+	}
+	if len(r.selfExamples) < maxRecordExamples {
+		r.selfExamples = append(r.selfExamples, fmt.Sprintf("%s parentId %d, firstChildId %d, secondChildId %d",
+			dt.Format(time.RFC3339), evt.ParentID, evt.FirstChildID, evt.SecondChildID))
+	}
+	return !r.dropSelfRefs
+}
+
+// badID describes the first ID of evt that overflowed int64 or lies outside
+// -id-min..-id-max, or returns "" when all are valid.
+func (r *recordErrors) badID(evt Event) string {
+	for _, f := range []struct {
+		name     string
+		id       int
+		overflow uint8
+	}{
+		{"parentId", evt.ParentID, events.OverflowParentID},
+		{"firstChildId", evt.FirstChildID, events.OverflowFirstChildID},
+		{"secondChildId", evt.SecondChildID, events.OverflowSecondChildID},
+	} {
+		switch {
+		case evt.Overflow&f.overflow != 0:
+			return fmt.Sprintf("%s beyond int%d", f.name, strconv.IntSize)
+		case int64(f.id) < r.idMin:
+			return fmt.Sprintf("%s %d below -id-min %d", f.name, f.id, r.idMin)
+		case int64(f.id) > r.idMax:
+			return fmt.Sprintf("%s %d above -id-max %d", f.name, f.id, r.idMax)
+		}
+	}
+	return ""
+}
+
+// countBadID counts a record with an invalid ID, described by why, and
+// returns whether it is kept.
+func (r *recordErrors) countBadID(dt time.Time, why string) bool {
+	r.badIDs++
+	if len(r.idExamples) < maxRecordExamples {
+		r.idExamples = append(r.idExamples, dt.Format(time.RFC3339)+" "+why)
+	}
+	return !r.dropBadIDs
+}
+
+func (r *recordErrors) total() int {
+	return r.missingDate + r.badDate + r.selfParent + r.selfChildren + r.badIDs
+}
+
+// section lists the record errors by cause. Only the date errors are always
+// left out of the counts; the notes say which of the others are.
+func (r *recordErrors) section() section {
+	s := section{
+		Key:     "record_errors",
+		Title:   "Record Errors",
+		Columns: []string{"Cause", "Records"},
+		Rows: [][]string{
+			{"Missing date", strconv.Itoa(r.missingDate)},
+			{"Unparseable date", strconv.Itoa(r.badDate)},
+		},
+	}
+	if n := r.missingDate + r.badDate; n > 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d records without a usable date are not counted", n))
+	}
+	if n := r.selfParent + r.selfChildren; n > 0 {
+		s.Rows = append(s.Rows,
+			[]string{"Parent equals a child", strconv.Itoa(r.selfParent)},
+			[]string{"Children equal", strconv.Itoa(r.selfChildren)})
+		if r.dropSelfRefs {
+			s.Notes = append(s.Notes, fmt.Sprintf("%d self-referential records excluded by -drop-self-refs", n))
+		} else {
+			s.Notes = append(s.Notes, fmt.Sprintf("%d self-referential records are counted; -drop-self-refs excludes them", n))
+		}
+		for _, e := range r.selfExamples {
+			s.Details = append(s.Details, "Self-referential: "+e)
+		}
+	}
+	if r.badIDs > 0 {
+		s.Rows = append(s.Rows, []string{"ID out of range", strconv.Itoa(r.badIDs)})
+		if r.dropBadIDs {
+			s.Notes = append(s.Notes, fmt.Sprintf("%d records with an ID out of range excluded by -drop-bad-ids", r.badIDs))
+		} else {
+			s.Notes = append(s.Notes, fmt.Sprintf("%d records with an ID out of range are counted; -drop-bad-ids excludes them", r.badIDs))
+		}
+		for _, e := range r.idExamples {
+			s.Details = append(s.Details, "Bad ID: "+e)
+		}
+	}
+	return s
+}
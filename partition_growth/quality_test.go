@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"partition_growth/events"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name             string
+		dt               time.Time
+		err              error
+		ok               bool
+		missing, unparse int
+	}{
+		{"parsed", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil, true, 0, 0},
+		{"missing date", time.Time{}, events.ErrMissingDate, false, 1, 0},
+		{"wrapped missing date", time.Time{}, errors.Join(errors.New("record 3"), events.ErrMissingDate), false, 1, 0},
+		{"zero time without an error", time.Time{}, nil, false, 1, 0},
+		{"unparseable", time.Time{}, errors.New("cannot parse"), false, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recordErrors
+			if ok := r.classify(tt.dt, tt.err); ok != tt.ok {
+				t.Errorf("classify = %v, want %v", ok, tt.ok)
+			}
+			if r.missingDate != tt.missing || r.badDate != tt.unparse {
+				t.Errorf("missing %d, unparseable %d; want %d, %d", r.missingDate, r.badDate, tt.missing, tt.unparse)
+			}
+		})
+	}
+}
+
+// TestRecordErrorsSection checks the notes that say which record errors are
+// left out of the counts: date errors always, the others only when dropped.
+func TestRecordErrorsSection(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     recordErrors
+		notes []string
+	}{
+		{"date errors", recordErrors{missingDate: 1, badDate: 2},
+			[]string{"3 records without a usable date are not counted"}},
+		{"self-references kept", recordErrors{selfParent: 1, selfChildren: 1},
+			[]string{"2 self-referential records are counted; -drop-self-refs excludes them"}},
+		{"self-references dropped", recordErrors{selfParent: 2, dropSelfRefs: true},
+			[]string{"2 self-referential records excluded by -drop-self-refs"}},
+		{"bad IDs kept, with a date error", recordErrors{badDate: 1, badIDs: 4},
+			[]string{"1 records without a usable date are not counted", "4 records with an ID out of range are counted; -drop-bad-ids excludes them"}},
+		{"bad IDs dropped", recordErrors{badIDs: 4, dropBadIDs: true},
+			[]string{"4 records with an ID out of range excluded by -drop-bad-ids"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.r.section()
+			if s.Title != "Record Errors" {
+				t.Errorf("title %q", s.Title)
+			}
+			if !reflect.DeepEqual(s.Notes, tt.notes) {
+				t.Errorf("notes %q, want %q", s.Notes, tt.notes)
+			}
+		})
+	}
+}
+
+// TestMissingDateTaxonomy runs the command on each way a date can be absent
+// and checks it is counted as missing, not unparseable, and never reaches a
+// year bucket.
+func TestMissingDateTaxonomy(t *testing.T) {
+	good := event(100, "2025-01-01T00:00:00Z", "n")
+	tests := []struct {
+		name             string
+		record           string
+		missing, unparse string
+	}{
+		{"field absent", `{"parentId":1,"firstChildId":2,"secondChildId":3,"leaderNodeInfo":"n"}`, "1", "0"},
+		{"null", `{"parentId":1,"firstChildId":2,"secondChildId":3,"date":null,"leaderNodeInfo":"n"}`, "1", "0"},
+		{"empty string", event(1, "", "n"), "1", "0"},
+		{"whitespace only", event(1, " \t ", "n"), "1", "0"},
+		{"zero time", event(1, "0001-01-01T00:00:00Z", "n"), "1", "0"},
+		{"unparseable", event(1, "garbage", "n"), "0", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "in.json", eventArray(tt.record, good))
+			out, errOut, status := runTool(t, dir, "-f", "in.json", "-a", "-output", "json")
+			if status != 0 {
+				t.Fatalf("exit %d\n%s", status, errOut)
+			}
+			var doc struct {
+				Sections []section
+				Errors   []reportError
+			}
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			rows := make(map[string]map[string]string)
+			for _, s := range doc.Sections {
+				rows[s.Key] = make(map[string]string)
+				for _, r := range s.Rows {
+					rows[s.Key][r[0]] = r[len(r)-1]
+				}
+			}
+			if got := rows["record_errors"]["Missing date"]; got != tt.missing {
+				t.Errorf("missing dates = %q, want %s", got, tt.missing)
+			}
+			if got := rows["record_errors"]["Unparseable date"]; got != tt.unparse {
+				t.Errorf("unparseable dates = %q, want %s", got, tt.unparse)
+			}
+			if len(rows["yearly"]) != 1 || rows["yearly"]["2025"] != "1" {
+				t.Errorf("yearly rows = %v, want only 2025: 1", rows["yearly"])
+			}
+			if len(doc.Errors) != 1 || doc.Errors[0].Code != codeDateParse || doc.Errors[0].Count != 1 {
+				t.Errorf("errors = %+v, want one %s with count 1", doc.Errors, codeDateParse)
+			}
+		})
+	}
+}
+
+// TestSelfRefs runs the command on each kind of record that may or may not
+// refer to itself and checks how the Record Errors section classifies it,
+// and that -drop-self-refs excludes exactly the ones it counts.
+func TestSelfRefs(t *testing.T) {
+	good := event(100, "2025-01-01T10:00:00Z", "n")
+	const date = `"date":"2025-01-02T10:00:00Z"`
+	tests := []struct {
+		name             string
+		record           string
+		parent, children string // the section's counts, "" when the row is absent
+	}{
+		{"distinct IDs", event(1, "2025-01-02T10:00:00Z", "n"), "", ""},
+		{"parent equals the leader", `{"parentId":5,"firstChildId":6,"secondChildId":7,` + date + `,"leaderNodeInfo":"5"}`, "", ""},
+		{"parent equals first child", `{"parentId":5,"firstChildId":5,"secondChildId":7,` + date + `}`, "1", "0"},
+		{"parent equals both children", `{"parentId":5,"firstChildId":5,"secondChildId":5,` + date + `}`, "1", "0"},
+		{"string parent, numeric child", `{"parentId":"5","firstChildId":6,"secondChildId":5.0,` + date + `}`, "1", "0"},
+		{"children equal as string and number", `{"parentId":5,"firstChildId":"6","secondChildId":6,` + date + `}`, "0", "1"},
+		{"children equal with an exponent", `{"parentId":5,"firstChildId":6e1,"secondChildId":"60",` + date + `}`, "0", "1"},
+		{"missing IDs", `{` + date + `,"leaderNodeInfo":"n"}`, "", ""},
+		{"missing children", `{"parentId":5,` + date + `}`, "", ""},
+		{"null children", `{"parentId":5,"firstChildId":null,"secondChildId":null,` + date + `}`, "", ""},
+		{"missing parent, equal children", `{"firstChildId":6,"secondChildId":6,` + date + `}`, "0", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "in.json", eventArray(tt.record, good))
+			for _, drop := range []bool{false, true} {
+				args := []string{"-f", "in.json", "-y", "2025", "-output", "json"}
+				if drop {
+					args = append(args, "-drop-self-refs")
+				}
+				out, errOut, status := runTool(t, dir, args...)
+				if status != 0 {
+					t.Fatalf("exit %d\n%s", status, errOut)
+				}
+				var doc struct{ Sections []section }
+				if err := json.Unmarshal([]byte(out), &doc); err != nil {
+					t.Fatalf("%v\n%s", err, out)
+				}
+				rows := make(map[string]map[string]string)
+				for _, s := range doc.Sections {
+					rows[s.Key] = make(map[string]string)
+					for _, r := range s.Rows {
+						rows[s.Key][r[0]] = r[len(r)-1]
+					}
+				}
+				if got := rows["record_errors"]["Parent equals a child"]; got != tt.parent {
+					t.Errorf("parent equals a child = %q, want %q", got, tt.parent)
+				}
+				if got := rows["record_errors"]["Children equal"]; got != tt.children {
+					t.Errorf("children equal = %q, want %q", got, tt.children)
+				}
+				want := "2"
+				if drop && tt.parent != "" {
+					want = "1"
+				}
+				if got := rows["year"]["2025"]; got != want {
+					t.Errorf("-drop-self-refs %v: 2025 count %q, want %s", drop, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// rankOf returns the fraction of sorted values below x, counting values
+// equal to x as half below, the rank the t-digest interpolates to.
+func rankOf(sorted []float64, x float64) float64 {
+	lo := sort.SearchFloat64s(sorted, x)
+	hi := sort.Search(len(sorted), func(i int) bool { return sorted[i] > x })
+	return (float64(lo) + float64(hi-lo)/2) / float64(len(sorted))
+}
+
+// TestQuantileRankError compares estimates with the exact quantiles of
+// synthetic distributions. The bounds allow twice the typical tail error
+// documented for the default compression; the median keeps its 0.5%.
+func TestQuantileRankError(t *testing.T) {
+	const n = 100000
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name string
+		gen  func(i int) float64
+	}{
+		{"uniform", func(int) float64 { return rng.Float64() }},
+		{"normal", func(int) float64 { return rng.NormFloat64()*10 + 100 }},
+		{"exponential", func(int) float64 { return rng.ExpFloat64() }},
+		{"lognormal", func(int) float64 { return math.Exp(rng.NormFloat64() * 2) }},
+		{"ascending", func(i int) float64 { return float64(i) }},
+		{"descending", func(i int) float64 { return float64(n - i) }},
+	}
+	bounds := []struct{ q, maxErr float64 }{
+		{0.001, 0.002}, {0.01, 0.002}, {0.1, 0.005}, {0.5, 0.005}, {0.9, 0.005}, {0.99, 0.002}, {0.999, 0.002},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := New(DefaultCompression)
+			values := make([]float64, n)
+			for i := range values {
+				values[i] = tt.gen(i)
+				td.Add(values[i])
+			}
+			sort.Float64s(values)
+			for _, b := range bounds {
+				got := td.Quantile(b.q)
+				if err := math.Abs(rankOf(values, got) - b.q); err > b.maxErr {
+					t.Errorf("Quantile(%g) = %g at rank %.5f, error %.5f above %g", b.q, got, rankOf(values, got), err, b.maxErr)
+				}
+			}
+			if td.Min() != values[0] || td.Max() != values[n-1] {
+				t.Errorf("Min, Max = %g, %g; want %g, %g", td.Min(), td.Max(), values[0], values[n-1])
+			}
+			if td.Count() != n {
+				t.Errorf("Count() = %g, want %d", td.Count(), n)
+			}
+			if l := td.Len(); l > 2*DefaultCompression {
+				t.Errorf("Len() = %d centroids, above twice the compression", l)
+			}
+		})
+	}
+}
+
+// TestQuantileSmallCounts checks the digest on the small integer counts the
+// stats report feeds it, with heavy ties.
+func TestQuantileSmallCounts(t *testing.T) {
+	td := New(DefaultCompression)
+	var values []float64
+	for i := 0; i < 50000; i++ {
+		v := float64(i%7 + i%3)
+		values = append(values, v)
+		td.Add(v)
+	}
+	sort.Float64s(values)
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		exact := values[int(math.Ceil(q*float64(len(values))))-1]
+		if got := td.Quantile(q); math.Abs(got-exact) > 1 {
+			t.Errorf("Quantile(%g) = %g, exact %g", q, got, exact)
+		}
+	}
+}
+
+func TestDigestEdges(t *testing.T) {
+	td := New(0)
+	if td.Quantile(0.5) != 0 || td.Min() != 0 || td.Max() != 0 || td.Count() != 0 {
+		t.Errorf("empty digest: Quantile %g, Min %g, Max %g, Count %g; want zeros", td.Quantile(0.5), td.Min(), td.Max(), td.Count())
+	}
+	td.Add(math.NaN())
+	td.AddWeighted(5, 0)
+	td.AddWeighted(5, -1)
+	if td.Count() != 0 {
+		t.Errorf("NaN and non-positive weights counted: Count %g", td.Count())
+	}
+	td.Add(3)
+	for _, q := range []float64{-1, 0, 0.5, 1, 2} {
+		if got := td.Quantile(q); got != 3 {
+			t.Errorf("single value: Quantile(%g) = %g, want 3", q, got)
+		}
+	}
+	td.AddWeighted(10, 3)
+	if td.Count() != 4 || td.Quantile(0) != 3 || td.Quantile(1) != 10 {
+		t.Errorf("Count %g, Quantile(0) %g, Quantile(1) %g; want 4, 3, 10", td.Count(), td.Quantile(0), td.Quantile(1))
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ── Ordered replay ────────────────────────────────────────────────────────────
+//
+// Reports that need filtered events in timestamp order (segments, and any
+// later interval/burst style report) do not buffer the input themselves.
+// During the single decode pass each filtered event is appended to the
+// replayer as a compact orderedRecord; after the pass the records are sorted
+// and handed to every registered consumer in one replay.
+//
+// Memory/disk envelope:
+//   - Up to -sort-mem records are held in memory (about 56 bytes plus the
+//     leader string each). Inputs that fit are sorted in memory and never
+//     touch disk.
+//   - Beyond that, each full buffer is sorted and spilled as a run file under
+//     -spill-dir (default $TMPDIR). A spilled record takes about 15 bytes
+//     plus the leader string, so disk use is roughly that times the number of
+//     filtered events; -spill-limit aborts the run when it would exceed the
+//     given number of bytes.
+//   - The final merge keeps one record and a 64 KiB read buffer per run.
+//
+// No replayer is created unless an ordered report is enabled, so runs without
+// one pay nothing.
+
+// orderedRecord is the compact form of a filtered event kept for replay.
+type orderedRecord struct {
+	T      int64 // Unix nanoseconds
+	Parent int
+	First  int
+	Second int
+	Leader string
+}
+
+func (r orderedRecord) time() time.Time {
+	return time.Unix(0, r.T).UTC()
+}
+
+type replayer struct {
+	dir       string
+	memLimit  int
+	diskLimit int64
+
+	buf       []orderedRecord
+	tmpDir    string
+	runs      []string
+	written   int64
+	err       error
+	consumers []func(orderedRecord)
+}
+
+func newReplayer(dir string, memLimit int, diskLimit int64) *replayer {
+	if memLimit < 1 {
+		memLimit = 1
+	}
+	return &replayer{dir: dir, memLimit: memLimit, diskLimit: diskLimit}
+}
+
+// consume registers fn to receive every record in timestamp order.
+func (r *replayer) consume(fn func(orderedRecord)) {
+	r.consumers = append(r.consumers, fn)
+}
+
+// add buffers one record, spilling a sorted run when the buffer is full. The
+// first spill error is kept and returned by replay.
+func (r *replayer) add(rec orderedRecord) {
+	if r.err != nil {
+		return
+	}
+	r.buf = append(r.buf, rec)
+	if len(r.buf) >= r.memLimit {
+		r.err = r.spill()
+	}
+}
+
+func (r *replayer) sortBuf() {
+	sort.SliceStable(r.buf, func(i, j int) bool { return r.buf[i].T < r.buf[j].T })
+}
+
+func (r *replayer) spill() error {
+	if r.tmpDir == "" {
+		d, err := os.MkdirTemp(r.dir, "partition_growth-replay-")
+		if err != nil {
+			return fmt.Errorf("creating spill directory: %v", err)
+		}
+		r.tmpDir = d
+	}
+	r.sortBuf()
+	path := filepath.Join(r.tmpDir, fmt.Sprintf("run-%05d", len(r.runs)))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating spill file: %v", err)
+	}
+	w := bufio.NewWriterSize(f, 64<<10)
+	var scratch []byte
+	for _, rec := range r.buf {
+		scratch = encodeRecord(scratch[:0], rec)
+		r.written += int64(len(scratch))
+		if r.diskLimit > 0 && r.written > r.diskLimit {
+			f.Close()
+			return fmt.Errorf("spill files exceed -spill-limit of %d bytes", r.diskLimit)
+		}
+		w.Write(scratch)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("writing spill file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing spill file: %v", err)
+	}
+	r.runs = append(r.runs, path)
+	r.buf = r.buf[:0]
+	return nil
+}
+
+// size reports the records buffered in memory and their approximate bytes
+// plus the bytes spilled to disk so far.
+func (r *replayer) size() (int, int64) {
+	b := r.written
+	for _, rec := range r.buf {
+		b += 56 + int64(len(rec.Leader))
+	}
+	return len(r.buf), b
+}
+
+// replay feeds all records to the consumers in timestamp order and removes
+// the spill files.
+func (r *replayer) replay() error {
+	defer r.cleanup()
+	if r.err != nil {
+		return r.err
+	}
+	emit := func(rec orderedRecord) {
+		for _, fn := range r.consumers {
+			fn(rec)
+		}
+	}
+	if len(r.runs) == 0 {
+		r.sortBuf()
+		for _, rec := range r.buf {
+			emit(rec)
+		}
+		return nil
+	}
+	if len(r.buf) > 0 {
+		if err := r.spill(); err != nil {
+			return err
+		}
+	}
+	return r.merge(emit)
+}
+
+func (r *replayer) cleanup() {
+	if r.tmpDir != "" {
+		os.RemoveAll(r.tmpDir)
+		r.tmpDir = ""
+	}
+	r.buf = nil
+}
+
+// merge performs a k-way merge of the sorted run files. Ties are broken by
+// run order, which keeps the overall order stable.
+func (r *replayer) merge(emit func(orderedRecord)) error {
+	h := &runHeap{}
+	for i, path := range r.runs {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("reopening spill file: %v", err)
+		}
+		defer f.Close()
+		c := &runCursor{idx: i, br: bufio.NewReaderSize(f, 64<<10)}
+		if ok, err := c.next(); err != nil {
+			return err
+		} else if ok {
+			heap.Push(h, c)
+		}
+	}
+	for h.Len() > 0 {
+		c := (*h)[0]
+		emit(c.cur)
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+func encodeRecord(b []byte, rec orderedRecord) []byte {
+	b = binary.AppendVarint(b, rec.T)
+	b = binary.AppendVarint(b, int64(rec.Parent))
+	b = binary.AppendVarint(b, int64(rec.First))
+	b = binary.AppendVarint(b, int64(rec.Second))
+	b = binary.AppendUvarint(b, uint64(len(rec.Leader)))
+	return append(b, rec.Leader...)
+}
+
+func decodeRecord(br *bufio.Reader) (orderedRecord, error) {
+	var rec orderedRecord
+	var v [4]int64
+	for i := range v {
+		n, err := binary.ReadVarint(br)
+		if err != nil {
+			if i == 0 && errors.Is(err, io.EOF) {
+				return rec, io.EOF
+			}
+			return rec, fmt.Errorf("corrupt spill file: %v", err)
+		}
+		v[i] = n
+	}
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return rec, fmt.Errorf("corrupt spill file: %v", err)
+	}
+	leader := make([]byte, l)
+	if _, err := io.ReadFull(br, leader); err != nil {
+		return rec, fmt.Errorf("corrupt spill file: %v", err)
+	}
+	rec.T, rec.Parent, rec.First, rec.Second = v[0], int(v[1]), int(v[2]), int(v[3])
+	rec.Leader = string(leader)
+	return rec, nil
+}
+
+type runCursor struct {
+	idx int
+	br  *bufio.Reader
+	cur orderedRecord
+}
+
+func (c *runCursor) next() (bool, error) {
+	rec, err := decodeRecord(c.br)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c.cur = rec
+	return true, nil
+}
+
+type runHeap []*runCursor
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if h[i].cur.T != h[j].cur.T {
+		return h[i].cur.T < h[j].cur.T
+	}
+	return h[i].idx < h[j].idx
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runCursor)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
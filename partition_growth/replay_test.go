@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReplayer(t *testing.T) {
+	const n = 1000
+	rng := rand.New(rand.NewSource(1))
+	var in []orderedRecord
+	for i := 0; i < n; i++ {
+		// Few distinct times, so ties are common and stability shows.
+		in = append(in, orderedRecord{T: int64(rng.Intn(50)), Parent: i, Leader: "n"})
+	}
+	tests := []struct {
+		name     string
+		memLimit int
+		spills   bool
+	}{
+		{"in memory", n + 1, false},
+		{"one spill and a tail", n - 1, true},
+		{"many runs", 64, true},
+		{"run per record", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			r := newReplayer(dir, tt.memLimit, 0)
+			var got []orderedRecord
+			r.consume(func(rec orderedRecord) { got = append(got, rec) })
+			for _, rec := range in {
+				r.add(rec)
+			}
+			if spilled := len(r.runs) > 0; spilled != tt.spills {
+				t.Errorf("spilled = %v, want %v", spilled, tt.spills)
+			}
+			if err := r.replay(); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != n {
+				t.Fatalf("replayed %d records, want %d", len(got), n)
+			}
+			for i := 1; i < n; i++ {
+				a, b := got[i-1], got[i]
+				if a.T > b.T || (a.T == b.T && a.Parent > b.Parent) {
+					t.Fatalf("records %d and %d out of order: %+v then %+v", i-1, i, a, b)
+				}
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("spill directory left behind: %v", entries)
+			}
+		})
+	}
+}
+
+func TestReplayerSpillLimit(t *testing.T) {
+	r := newReplayer(t.TempDir(), 2, 10)
+	for i := 0; i < 10; i++ {
+		r.add(orderedRecord{T: int64(i), Leader: "leader"})
+	}
+	err := r.replay()
+	if err == nil || !strings.Contains(err.Error(), "-spill-limit") {
+		t.Fatalf("replay() = %v, want a -spill-limit error", err)
+	}
+}
+
+func TestRecordEncoding(t *testing.T) {
+	for _, rec := range []orderedRecord{
+		{},
+		{T: -1, Parent: -5, First: 1 << 30, Second: 7, Leader: "node-1.example:9000"},
+		{T: 1 << 62, Leader: strings.Repeat("x", 300)},
+	} {
+		got, err := decodeRecord(bufioOf(encodeRecord(nil, rec)))
+		if err != nil || got != rec {
+			t.Errorf("round trip of %+v = %+v, %v", rec, got, err)
+		}
+	}
+}
+
+// TestOrderedReportsSpill runs -segments with the sort buffer far below the
+// input size and compares the output with an in-memory run.
+func TestOrderedReportsSpill(t *testing.T) {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(2))
+	var records []string
+	for i := 0; i < 500; i++ {
+		day, hour := 1+rng.Intn(28), rng.Intn(24)
+		records = append(records, event(i*3, fmtDate(day, hour), "n"))
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+	args := []string{"-f", "in.json", "-segments", "-gap", "2h", "-spill-dir", dir}
+	memOut, _, status := runTool(t, dir, args...)
+	if status != 0 {
+		t.Fatalf("in-memory run: exit %d", status)
+	}
+	spillOut, errOut, status := runTool(t, dir, append(args, "-sort-mem", "7")...)
+	if status != 0 {
+		t.Fatalf("spilling run: exit %d\n%s", status, errOut)
+	}
+	if spillOut != memOut {
+		t.Errorf("spilling run differs from the in-memory run\nspill:\n%s\nmemory:\n%s", spillOut, memOut)
+	}
+	if !strings.Contains(memOut, "Active Segments") {
+		t.Errorf("no segments in the output:\n%s", memOut)
+	}
+}
+
+func bufioOf(b []byte) *bufio.Reader {
+	return bufio.NewReader(bytes.NewReader(b))
+}
+
+// fmtDate is an RFC 3339 time in March 2025.
+func fmtDate(day, hour int) string {
+	return fmt.Sprintf("2025-03-%02dT%02d:00:00Z", day, hour)
+}
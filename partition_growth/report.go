@@ -0,0 +1,1229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -report flag ──────────────────────────────────────────────────────────────
+
+// reportSpec is one -report entry in name[=arg] form.
+type reportSpec struct {
+	Name string
+	Arg  string
+}
+
+// reportList collects -report values; the flag may be repeated and each value
+// may hold several comma-separated reports.
+type reportList []reportSpec
+
+func (r *reportList) String() string {
+	parts := make([]string, 0, len(*r))
+	for _, s := range *r {
+		if s.Arg != "" {
+			parts = append(parts, s.Name+"="+s.Arg)
+		} else {
+			parts = append(parts, s.Name)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *reportList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, arg := item, ""
+		if i := strings.Index(item, "="); i >= 0 {
+			name, arg = item[:i], item[i+1:]
+		}
+		if _, ok := reports[name]; !ok {
+			return fmt.Errorf("unknown report %q (available: %s)", name, strings.Join(reportNames(), ", "))
+		}
+		*r = append(*r, reportSpec{Name: name, Arg: arg})
+	}
+	return nil
+}
+
+// has reports whether name was requested.
+func (r reportList) has(name string) bool {
+	for _, s := range r {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ── Report registry ───────────────────────────────────────────────────────────
+
+// reportDef describes one -report. prepare validates the argument and enables
+// the aggregations the report reads; build turns them into output sections.
+type reportDef struct {
+	prepare func(a *aggregates, arg string) error
+	build   func(a *aggregates, arg string) []section
+}
+
+var reports = map[string]reportDef{
+	"parent-id-distribution":        {prepareParentDistribution, buildParentDistribution},
+	"event-rate-trend":              {requireYear, buildEventRateTrend},
+	"forecast":                      {prepareForecast, buildForecast},
+	"coverage":                      {prepareCoverage, buildCoverage},
+	"rolling-7d-percentile":         {prepareRollingPercentile, buildRollingPercentile},
+	"event-density":                 {prepareCoverage, buildEventDensity},
+	"leader-tenure":                 {prepareLeaderTenure, buildLeaderTenure},
+	"leader-health-score":           {prepareLeaderHealthScore, buildLeaderHealthScore},
+	"parent-id-histogram-by-leader": {prepareParentHistogramByLeader, buildParentHistogramByLeader},
+	"weekly-leader-transition":      {prepareWeeklyLeaderTransition, buildWeeklyLeaderTransition},
+	"daily-top-leaders":             {prepareDailyTopLeaders, buildDailyTopLeaders},
+	"event-count-parity":            {prepareEventCountParity, buildEventCountParity},
+	"monthly-leader-new-arrivals":   {prepareMonthlyLeaders, buildLeaderNewArrivals},
+	"monthly-leader-departures":     {prepareMonthlyLeaders, buildLeaderDepartures},
+	"parent-lifecycle":              {prepareParentLifecycle, buildParentLifecycle},
+	"parent-event-rate":             {prepareParentLifecycle, buildParentEventRate},
+	"event-overlap-window":          {prepareEventOverlapWindow, buildEventOverlapWindow},
+	"event-size-stats":              {prepareEventSizeStats, buildEventSizeStats},
+	"month-rank-history":            {requireMonth, buildMonthRankHistory},
+	"percentile-ladder":             {prepareNothing, buildPercentileLadder},
+	"full-year-weekly-grid":         {requireYear, buildWeeklyGrid},
+	"weekday-leader-affinity":       {prepareWeekdayLeaderAffinity, buildWeekdayLeaderAffinity},
+	"monthly-summary-json":          {prepareNothing, nil}, // replaces all output; see writeMonthlySummaryJSON
+	"monthly-percentile-calendar":   {prepareCoverage, buildMonthlyPercentileCalendar},
+	"inverted-index":                {prepareInvertedIndex, buildInvertedIndex},
+	"parent-child-matrix":           {prepareParentChildMatrix, buildParentChildMatrix},
+	"daily-change-rate":             {prepareDailyChangeRate, buildDailyChangeRate},
+	"leader-event-share":            {prepareLeaderEventShare, buildLeaderEventShare},
+	"event-frequency-table":         {prepareEventFrequencyTable, buildEventFrequencyTable},
+	"inactive-parents":              {prepareInactiveParents, buildInactiveParents},
+	"event-burst-sequence":          {prepareEventBurstSequence, buildEventBurstSequence},
+	"event-spike-context":           {prepareEventSpikeContext, buildEventSpikeContext},
+	"top-n-parents-by-month":        {prepareTopParentsByMonth, buildTopParentsByMonth},
+	"parent-transitions":            {prepareParentTransitions, buildParentTransitions},
+	"event-metadata-summary":        {prepareEventMetadataSummary, buildEventMetadataSummary},
+	"stats":                         {prepareStats, buildStats},
+	"leader-cooccurrence":           {prepareLeaderCooccurrence, buildLeaderCooccurrence},
+	"leader-event-gap":              {prepareLeaderEventGap, buildLeaderEventGap},
+	"day-of-month-heatmap":          {prepareCoverage, buildDayOfMonthHeatmap},
+	"week-completeness":             {prepareWeekCompleteness, buildWeekCompleteness},
+	"event-hour-of-week":            {prepareEventHourOfWeek, buildEventHourOfWeek},
+	"top-hours":                     {prepareTopHours, buildTopHours},
+}
+
+func reportNames() []string {
+	names := make([]string, 0, len(reports))
+	for n := range reports {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requireYear is the prepare step of reports that only make sense with -y.
+func requireYear(a *aggregates, _ string) error {
+	if a.opts.year == 0 {
+		return fmt.Errorf("requires -y")
+	}
+	return nil
+}
+
+func prepareNothing(*aggregates, string) error { return nil }
+
+// pct formats n/total as a one-decimal percentage.
+func pct(n, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return strconv.FormatFloat(float64(n)*100/float64(total), 'f', 1, 64) + "%"
+}
+
+// bar renders a proportional '#' bar of at most width characters.
+func bar(n, max, width int) string {
+	if max <= 0 || n <= 0 {
+		return ""
+	}
+	l := n * width / max
+	if l == 0 {
+		l = 1
+	}
+	return strings.Repeat("#", l)
+}
+
+// ── parent-id-distribution ────────────────────────────────────────────────────
+
+// countBucket is an inclusive range of per-parent event counts; Max 0 means open-ended.
+type countBucket struct {
+	Min, Max int
+	Label    string
+}
+
+// parseCountBuckets parses "1,2,3-10,11-100,101+" into ascending, non-overlapping buckets.
+func parseCountBuckets(spec string) ([]countBucket, error) {
+	var out []countBucket
+	for _, tok := range strings.Split(spec, ",") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		var b countBucket
+		var err error
+		switch {
+		case strings.HasSuffix(tok, "+"):
+			b.Min, err = strconv.Atoi(strings.TrimSuffix(tok, "+"))
+		case strings.Contains(tok, "-"):
+			lo, hi, _ := strings.Cut(tok, "-")
+			if b.Min, err = strconv.Atoi(lo); err == nil {
+				b.Max, err = strconv.Atoi(hi)
+			}
+		default:
+			b.Min, err = strconv.Atoi(tok)
+			b.Max = b.Min
+		}
+		if err != nil || b.Min < 1 || (b.Max != 0 && b.Max < b.Min) {
+			return nil, fmt.Errorf("invalid bucket %q", tok)
+		}
+		if n := len(out); n > 0 && (out[n-1].Max == 0 || b.Min <= out[n-1].Max) {
+			return nil, fmt.Errorf("bucket %q overlaps or is out of order", tok)
+		}
+		b.Label = tok
+		out = append(out, b)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no buckets in %q", spec)
+	}
+	return out, nil
+}
+
+func (b countBucket) contains(n int) bool {
+	return n >= b.Min && (b.Max == 0 || n <= b.Max)
+}
+
+func prepareParentDistribution(a *aggregates, _ string) error {
+	buckets, err := parseCountBuckets(a.opts.parentDistBuckets)
+	if err != nil {
+		return fmt.Errorf("-parent-dist-buckets: %v", err)
+	}
+	a.parentBuckets = buckets
+	a.enablePerParent()
+	return nil
+}
+
+func buildParentDistribution(a *aggregates, _ string) []section {
+	parents := make([]int, len(a.parentBuckets))
+	events := make([]int, len(a.parentBuckets))
+	otherParents, otherEvents := 0, 0
+	for _, n := range a.perParent {
+		matched := false
+		for i, b := range a.parentBuckets {
+			if b.contains(n) {
+				parents[i]++
+				events[i] += n
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			otherParents++
+			otherEvents += n
+		}
+	}
+	totalParents := len(a.perParent)
+	max := otherParents
+	for _, p := range parents {
+		if p > max {
+			max = p
+		}
+	}
+
+	s := section{
+		Key:       "parent_id_distribution",
+		Title:     "ParentID Event Distribution",
+		Columns:   []string{"Events/parent", "Parents", "Share", "Events", "Histogram"},
+		BarColumn: 5,
+	}
+	for i, b := range a.parentBuckets {
+		s.Rows = append(s.Rows, []string{b.Label, strconv.Itoa(parents[i]), pct(parents[i], totalParents),
+			strconv.Itoa(events[i]), bar(parents[i], max, 40)})
+	}
+	if otherParents > 0 {
+		s.Rows = append(s.Rows, []string{"(unbucketed)", strconv.Itoa(otherParents), pct(otherParents, totalParents),
+			strconv.Itoa(otherEvents), bar(otherParents, max, 40)})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Distinct parents: %d", totalParents))
+	}
+	return []section{s}
+}
+
+// ── event-rate-trend / forecast ───────────────────────────────────────────────
+
+// monthSeries returns "YYYY-MM" keys and counts from the first to the last
+// month with events, zero-filling the gaps. year 0 spans the whole dataset.
+func (a *aggregates) monthSeries(year int) (keys []string, counts []int) {
+	var first, last string
+	for k := range a.perMonth {
+		if year != 0 && !strings.HasPrefix(k, fmt.Sprintf("%04d-", year)) {
+			continue
+		}
+		if first == "" || k < first {
+			first = k
+		}
+		if k > last {
+			last = k
+		}
+	}
+	if first == "" {
+		return nil, nil
+	}
+	t, _ := time.Parse("2006-01", first)
+	for k := first; k <= last; k = t.Format("2006-01") {
+		keys = append(keys, k)
+		counts = append(counts, a.perMonth[k])
+		t = t.AddDate(0, 1, 0)
+	}
+	return keys, counts
+}
+
+// daySeries returns the "YYYY-MM-DD" keys and counts of a per-day map from
+// the first to the last day with events, zero-filling the gaps.
+func daySeries(perDay map[string]int) (keys []string, counts []int) {
+	var first, last string
+	for k := range perDay {
+		if first == "" || k < first {
+			first = k
+		}
+		if k > last {
+			last = k
+		}
+	}
+	if first == "" {
+		return nil, nil
+	}
+	t, _ := time.Parse("2006-01-02", first)
+	for k := first; k <= last; k = t.Format("2006-01-02") {
+		keys = append(keys, k)
+		counts = append(counts, perDay[k])
+		t = t.AddDate(0, 0, 1)
+	}
+	return keys, counts
+}
+
+// fitMonthSeries regresses counts against their month index.
+func fitMonthSeries(counts []int) (slope, intercept, r2 float64) {
+	xs := make([]float64, len(counts))
+	ys := make([]float64, len(counts))
+	for i, c := range counts {
+		xs[i], ys[i] = float64(i), float64(c)
+	}
+	return regressSlope(xs, ys)
+}
+
+func buildEventRateTrend(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "event_rate_trend",
+		Title:   fmt.Sprintf("Event Rate Trend %d", a.opts.year),
+		Columns: []string{"Month", "Events"},
+	}
+	keys, counts := a.monthSeries(a.opts.year)
+	for i, k := range keys {
+		s.Rows = append(s.Rows, []string{k, strconv.Itoa(counts[i])})
+	}
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", a.opts.year))
+		return []section{s}
+	}
+	if len(keys) < 2 {
+		s.Notes = append(s.Notes, "Trend: n/a (needs at least two months with data)")
+		return []section{s}
+	}
+	slope, _, r2 := fitMonthSeries(counts)
+	s.Notes = append(s.Notes,
+		fmt.Sprintf("Trend: %+.1f events/month", slope),
+		fmt.Sprintf("R²: %.3f", r2))
+	return []section{s}
+}
+
+// parseForecastMonths accepts "3m" or "3"; empty means 3.
+func parseForecastMonths(arg string) (int, error) {
+	if arg == "" {
+		return 3, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(arg, "m"))
+	if err != nil || n < 1 || n > 120 {
+		return 0, fmt.Errorf("invalid horizon %q (want e.g. 3m)", arg)
+	}
+	return n, nil
+}
+
+func prepareForecast(_ *aggregates, arg string) error {
+	_, err := parseForecastMonths(arg)
+	return err
+}
+
+// buildForecast extends the fitted trend line past the last month of data
+// (of the -y year when given) and brackets each projection with ±1 standard
+// deviation of the fit residuals.
+func buildForecast(a *aggregates, arg string) []section {
+	n, _ := parseForecastMonths(arg)
+	s := section{
+		Key:     "forecast",
+		Title:   fmt.Sprintf("Event Forecast (next %d months)", n),
+		Columns: []string{"Month", "Events", "Kind", "Range"},
+	}
+	keys, counts := a.monthSeries(a.opts.year)
+	for i, k := range keys {
+		s.Rows = append(s.Rows, []string{k, strconv.Itoa(counts[i]), "actual", ""})
+	}
+	if len(keys) == 0 && a.opts.year != 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", a.opts.year))
+		return []section{s}
+	}
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, "No events.")
+		return []section{s}
+	}
+	if len(keys) < 2 {
+		s.Notes = append(s.Notes, "Forecast: n/a (needs at least two months with data)")
+		return []section{s}
+	}
+	slope, intercept, _ := fitMonthSeries(counts)
+	var ss float64
+	for i, c := range counts {
+		r := float64(c) - (slope*float64(i) + intercept)
+		ss += r * r
+	}
+	sd := math.Sqrt(ss / float64(len(counts)))
+	t, _ := time.Parse("2006-01", keys[len(keys)-1])
+	for h := 1; h <= n; h++ {
+		y := slope*float64(len(counts)-1+h) + intercept
+		lo, hi := math.Max(0, y-sd), math.Max(0, y+sd)
+		s.Rows = append(s.Rows, []string{
+			t.AddDate(0, h, 0).Format("2006-01"),
+			strconv.Itoa(int(math.Round(math.Max(0, y)))),
+			"FORECAST",
+			fmt.Sprintf("%d–%d", int(math.Round(lo)), int(math.Round(hi))),
+		})
+	}
+	s.Notes = append(s.Notes,
+		fmt.Sprintf("Trend: %+.1f events/month; residual stddev: %.1f", slope, sd),
+		"FORECAST rows are projections from the fitted line, not observed data.")
+	return []section{s}
+}
+
+// ── coverage ──────────────────────────────────────────────────────────────────
+
+// periodDays returns every calendar day of the -y year, or of the -m month
+// within it.
+func (a *aggregates) periodDays() []time.Time {
+	start := time.Date(a.opts.year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	if a.opts.month != 0 {
+		start = time.Date(a.opts.year, time.Month(a.opts.month), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, 0)
+	}
+	var days []time.Time
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days
+}
+
+func prepareCoverage(a *aggregates, _ string) error {
+	if a.opts.year == 0 {
+		return fmt.Errorf("requires -y")
+	}
+	if a.opts.day != 0 {
+		return fmt.Errorf("cannot be combined with -d")
+	}
+	return nil
+}
+
+// dayRanges compresses ascending days into ranges of consecutive days,
+// labelled in the given style.
+func dayRanges(days []time.Time, style dateStyle) []string {
+	var out []string
+	for i := 0; i < len(days); {
+		j := i
+		for j+1 < len(days) && days[j+1].Sub(days[j]) == 24*time.Hour {
+			j++
+		}
+		if i == j {
+			out = append(out, style.day(days[i]))
+		} else {
+			out = append(out, style.span(days[i], days[j]))
+		}
+		i = j + 1
+	}
+	return out
+}
+
+func buildCoverage(a *aggregates, _ string) []section {
+	days := a.periodDays()
+	period := strconv.Itoa(a.opts.year)
+	if a.opts.month != 0 {
+		period = a.opts.dates.month(a.opts.year, a.opts.month)
+	}
+	covered := 0
+	var uncovered []time.Time
+	inPeriod := make(map[string]bool, len(days))
+	for _, d := range days {
+		k := d.Format("2006-01-02")
+		inPeriod[k] = true
+		if a.perDay[k] > 0 {
+			covered++
+		} else {
+			uncovered = append(uncovered, d)
+		}
+	}
+
+	// ISO weeks whose seven days all fall inside the period.
+	weeks, fullWeeks := 0, 0
+	for _, d := range days {
+		if d.Weekday() != time.Monday {
+			continue
+		}
+		full, inside := true, true
+		for i := 0; i < 7; i++ {
+			k := d.AddDate(0, 0, i).Format("2006-01-02")
+			if !inPeriod[k] {
+				inside = false
+				break
+			}
+			if a.perDay[k] == 0 {
+				full = false
+			}
+		}
+		if !inside {
+			continue
+		}
+		weeks++
+		if full {
+			fullWeeks++
+		}
+	}
+
+	s := section{
+		Key:     "coverage",
+		Title:   fmt.Sprintf("Coverage %s", period),
+		Columns: []string{"Measure", "Covered", "Total", "Percent"},
+		Rows: [][]string{
+			{"Days with events", strconv.Itoa(covered), strconv.Itoa(len(days)), pct(covered, len(days))},
+			{"ISO weeks fully covered", strconv.Itoa(fullWeeks), strconv.Itoa(weeks), pct(fullWeeks, weeks)},
+		},
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	if len(uncovered) == 0 {
+		s.Details = append(s.Details, "Uncovered days: none")
+	} else {
+		s.Details = append(s.Details, fmt.Sprintf("Uncovered days (%d): %s", len(uncovered), strings.Join(dayRanges(uncovered, a.opts.dates), "; ")))
+	}
+	return []section{s}
+}
+
+// ── week-completeness ─────────────────────────────────────────────────────────
+
+func prepareWeekCompleteness(a *aggregates, arg string) error {
+	if err := requireYear(a, arg); err != nil {
+		return err
+	}
+	a.isoWeekDays = new([54]uint8)
+	return nil
+}
+
+// buildWeekCompleteness lists every ISO week of the ISO year -y with the
+// number of its days that had events. Days are judged on all events, so the
+// weeks that straddle New Year are complete when all seven days are, whatever
+// the calendar year of each day.
+func buildWeekCompleteness(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "week_completeness",
+		Title:   fmt.Sprintf("ISO Week Completeness %d", a.opts.year),
+		Columns: []string{"ISO week", "Dates", "Days with events", "Complete"},
+
+		Truncatable: true,
+	}
+	// ISO week 1 is the week containing January 4.
+	jan4 := time.Date(a.opts.year, time.January, 4, 0, 0, 0, 0, a.opts.loc)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	complete, weeks, seen := 0, 0, 0
+	for d := monday; ; d = d.AddDate(0, 0, 7) {
+		y, w := d.ISOWeek()
+		if y != a.opts.year {
+			break
+		}
+		weeks++
+		n := bits.OnesCount8(a.isoWeekDays[w])
+		seen += n
+		flag := "no"
+		if n == 7 {
+			flag = "yes"
+			complete++
+		}
+		s.Rows = append(s.Rows, []string{fmt.Sprintf("%04d-W%02d", y, w), a.opts.dates.span(d, d.AddDate(0, 0, 6)),
+			strconv.Itoa(n), flag})
+	}
+	if seen == 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("No events in %d.", a.opts.year))
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Complete weeks: %d of %d (%s)", complete, weeks, pct(complete, weeks)))
+	}
+	return []section{s}
+}
+
+// ── rolling-7d-percentile ─────────────────────────────────────────────────────
+
+// parsePercentileArg accepts a percentile in (0, 100]; empty means def.
+func parsePercentileArg(arg string, def float64) (float64, error) {
+	if arg == "" {
+		return def, nil
+	}
+	p, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToLower(arg), "p"), 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid percentile %q", arg)
+	}
+	return p, nil
+}
+
+func prepareRollingPercentile(_ *aggregates, arg string) error {
+	_, err := parsePercentileArg(arg, 95)
+	return err
+}
+
+// buildRollingPercentile lists every day of the filtered range with the
+// percentile of the seven days before it.
+func buildRollingPercentile(a *aggregates, arg string) []section {
+	p, _ := parsePercentileArg(arg, 95)
+	label := "P" + strconv.FormatFloat(p, 'f', -1, 64)
+	s := section{
+		Key:     "rolling_7d_percentile",
+		Title:   fmt.Sprintf("Rolling 7-Day %s", label),
+		Columns: []string{"Date", "Count", "7d " + label},
+
+		Truncatable: true,
+	}
+	keys, counts := daySeries(a.perDay)
+	w := &slidingWindow{size: 7}
+	for i, k := range keys {
+		v := "n/a"
+		if w.full() {
+			v = strconv.Itoa(percentile(w.sorted, p))
+		}
+		s.Rows = append(s.Rows, []string{k, strconv.Itoa(counts[i]), v})
+		w.push(counts[i])
+	}
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, "The window is the 7 days before each date; n/a until 7 days of history exist.")
+	}
+	return []section{s}
+}
+
+// ── event-density ─────────────────────────────────────────────────────────────
+
+// buildEventDensity shows, per month of -y (or the -m month), events per
+// active day next to the uniform baseline of events per calendar day. A
+// density well above the baseline means events bunch up on few days.
+func buildEventDensity(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "event_density",
+		Title:   fmt.Sprintf("Event Density %d", a.opts.year),
+		Columns: []string{"Month", "Count", "Active days", "Density", "Uniform", "Ratio"},
+	}
+	first, last := 1, 12
+	if a.opts.month != 0 {
+		first, last = a.opts.month, a.opts.month
+	}
+	for m := first; m <= last; m++ {
+		prefix := fmt.Sprintf("%04d-%02d-", a.opts.year, m)
+		count, active := 0, 0
+		for k, n := range a.perDay {
+			if strings.HasPrefix(k, prefix) && n > 0 {
+				count += n
+				active++
+			}
+		}
+		row := []string{monthName(m), strconv.Itoa(count), strconv.Itoa(active), "-", "-", "-"}
+		if active > 0 {
+			density := float64(count) / float64(active)
+			uniform := float64(count) / float64(daysInMonth(a.opts.year, m))
+			row[3] = strconv.FormatFloat(density, 'f', 2, 64)
+			row[4] = strconv.FormatFloat(uniform, 'f', 2, 64)
+			row[5] = strconv.FormatFloat(density/uniform, 'f', 2, 64) + "x"
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, "Density = events / days with events; Uniform = events / days in month.")
+	}
+	return []section{s}
+}
+
+// ── day-of-month-heatmap ──────────────────────────────────────────────────────
+
+// buildDayOfMonthHeatmap averages the filtered count of each day of the month
+// over the months of -y (or its -m month) that have that day, so day 31 is
+// averaged over seven months and Feb 29 only counts in leap years.
+func buildDayOfMonthHeatmap(a *aggregates, _ string) []section {
+	s := section{
+		Key:       "day_of_month_heatmap",
+		Title:     fmt.Sprintf("Day-of-Month Heatmap %d", a.opts.year),
+		Columns:   []string{"Day", "Months", "Events", "Average", "Heat"},
+		BarColumn: 5,
+	}
+	first, last := 1, 12
+	if a.opts.month != 0 {
+		first, last = a.opts.month, a.opts.month
+	}
+	var avgs [31]float64
+	var months, totals [31]int
+	max := 0.0
+	for d := 1; d <= 31; d++ {
+		for m := first; m <= last; m++ {
+			if d > daysInMonth(a.opts.year, m) {
+				continue
+			}
+			months[d-1]++
+			totals[d-1] += a.perDay[fmt.Sprintf("%04d-%02d-%02d", a.opts.year, m, d)]
+		}
+		if months[d-1] > 0 {
+			avgs[d-1] = float64(totals[d-1]) / float64(months[d-1])
+		}
+		max = math.Max(max, avgs[d-1])
+	}
+	for d := 1; d <= 31; d++ {
+		avg, heat := "-", ""
+		if months[d-1] > 0 {
+			avg = strconv.FormatFloat(avgs[d-1], 'f', 2, 64)
+			if max > 0 {
+				heat = bar(int(math.Round(avgs[d-1]*100)), int(math.Round(max*100)), 40)
+			}
+		}
+		s.Rows = append(s.Rows, []string{strconv.Itoa(d), strconv.Itoa(months[d-1]), strconv.Itoa(totals[d-1]), avg, heat})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, "Average = events on that day / months of the period having that day.")
+	}
+	return []section{s}
+}
+
+// ── monthly-summary-json ──────────────────────────────────────────────────────
+
+// writeMonthlySummaryJSON writes the per-month counts, limited to -y when
+// given, as one compact JSON object line: {"2024-01":342,...}. It is the whole
+// output when -report=monthly-summary-json is selected.
+func writeMonthlySummaryJSON(w io.Writer, a *aggregates) error {
+	months := make(map[string]int)
+	prefix := ""
+	if a.opts.year != 0 {
+		prefix = fmt.Sprintf("%04d-", a.opts.year)
+	}
+	for k, n := range a.perMonth {
+		if strings.HasPrefix(k, prefix) {
+			months[k] = n
+		}
+	}
+	b, err := json.Marshal(months) // map keys are sorted
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// ── monthly-percentile-calendar ───────────────────────────────────────────────
+
+// dailyCountsForMonth returns the count of every calendar day of the month in
+// ascending order of count, zero for days without events.
+func dailyCountsForMonth(year, month int, perDay map[string]int) []int {
+	n := daysInMonth(year, month)
+	counts := make([]int, n)
+	for d := 1; d <= n; d++ {
+		counts[d-1] = perDay[fmt.Sprintf("%04d-%02d-%02d", year, month, d)]
+	}
+	sort.Ints(counts)
+	return counts
+}
+
+// buildMonthlyPercentileCalendar summarizes each month of -y (or the -m
+// month) by its total and the median and P95 of its daily counts.
+func buildMonthlyPercentileCalendar(a *aggregates, _ string) []section {
+	s := section{
+		Key:     "monthly_percentile_calendar",
+		Title:   fmt.Sprintf("Daily Percentiles by Month %d", a.opts.year),
+		Columns: []string{"Month", "Total", "P50/day", "P95/day"},
+	}
+	first, last := 1, 12
+	if a.opts.month != 0 {
+		first, last = a.opts.month, a.opts.month
+	}
+	for m := first; m <= last; m++ {
+		counts := dailyCountsForMonth(a.opts.year, m, a.perDay)
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		s.Rows = append(s.Rows, []string{monthName(m), strconv.Itoa(total),
+			strconv.Itoa(percentile(counts, 50)), strconv.Itoa(percentile(counts, 95))})
+	}
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, "Percentiles are nearest-rank over every calendar day, days without events counting 0.")
+	}
+	return []section{s}
+}
+
+// ── daily-change-rate ─────────────────────────────────────────────────────────
+
+const defaultChangeThreshold = 10
+
+// parseChangeThreshold reads the daily-change-rate argument, the absolute
+// day-over-day change above which a row is flagged.
+func parseChangeThreshold(arg string) (int, error) {
+	if arg == "" {
+		return defaultChangeThreshold, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid threshold %q", arg)
+	}
+	return n, nil
+}
+
+func prepareDailyChangeRate(_ *aggregates, arg string) error {
+	_, err := parseChangeThreshold(arg)
+	return err
+}
+
+// buildDailyChangeRate lists the first difference of the filtered daily
+// counts. Every day after the first has a delta, so a jump from a day without
+// events is flagged like any other; only its percentage is N/A, having no
+// base to divide by.
+func buildDailyChangeRate(a *aggregates, arg string) []section {
+	threshold, _ := parseChangeThreshold(arg)
+	s := section{
+		Key:         "daily_change_rate",
+		Title:       "Daily Change Rate",
+		Columns:     []string{"Date", "Count", "Delta", "Change", "Flag"},
+		Truncatable: true,
+	}
+	keys, counts := daySeries(a.perDay)
+	flagged := 0
+	for i, k := range keys {
+		delta, change, mark := 0, "N/A", ""
+		if i > 0 {
+			delta = counts[i] - counts[i-1]
+			if counts[i-1] > 0 {
+				change = fmt.Sprintf("%+.1f%%", float64(delta)*100/float64(counts[i-1]))
+			}
+		}
+		if delta > threshold || -delta > threshold {
+			mark = "!"
+			flagged++
+		}
+		s.Rows = append(s.Rows, []string{k, strconv.Itoa(counts[i]), fmt.Sprintf("%+d", delta), change, mark})
+	}
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Days flagged (!) with |delta| > %d: %d", threshold, flagged))
+	}
+	return []section{s}
+}
+
+// ── event-frequency-table ─────────────────────────────────────────────────────
+
+func prepareEventFrequencyTable(_ *aggregates, arg string) error {
+	switch arg {
+	case "", "day", "week", "month":
+		return nil
+	}
+	return fmt.Errorf("invalid period %q (want day, week or month)", arg)
+}
+
+// periodCounts buckets the filtered daily counts into periods ("day", "week"
+// as ISO weeks, or "month"). The periods span -y (or its -m month) when given,
+// else the first to the last day with events, so empty periods count as 0.
+func (a *aggregates) periodCounts(period string) (keys []string, counts []int) {
+	var days []string
+	if a.opts.year != 0 && a.opts.day == 0 {
+		for _, d := range a.periodDays() {
+			days = append(days, d.Format("2006-01-02"))
+		}
+	} else {
+		days, _ = daySeries(a.perDay)
+	}
+	for _, k := range days {
+		t, _ := time.Parse("2006-01-02", k)
+		switch period {
+		case "week":
+			y, w := t.ISOWeek()
+			k = fmt.Sprintf("%04d-W%02d", y, w)
+		case "month":
+			k = k[:7]
+		}
+		if n := len(keys); n == 0 || keys[n-1] != k {
+			keys = append(keys, k)
+			counts = append(counts, 0)
+		}
+		counts[len(counts)-1] += a.perDay[t.Format("2006-01-02")]
+	}
+	return keys, counts
+}
+
+// buildEventFrequencyTable shows how many periods had exactly K events, one
+// row per distinct K in ascending order.
+func buildEventFrequencyTable(a *aggregates, arg string) []section {
+	period := arg
+	if period == "" {
+		period = "month"
+	}
+	s := section{
+		Key:       "event_frequency_table",
+		Title:     fmt.Sprintf("Event Frequency Table (per %s)", period),
+		Columns:   []string{"Events", "Periods", "Share", "Histogram"},
+		BarColumn: 4,
+
+		Truncatable: true,
+	}
+	_, counts := a.periodCounts(period)
+	if len(counts) == 0 || a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	freq := make(map[int]int)
+	for _, c := range counts {
+		freq[c]++
+	}
+	values := make([]int, 0, len(freq))
+	max := 0
+	for k, n := range freq {
+		values = append(values, k)
+		if n > max {
+			max = n
+		}
+	}
+	sort.Ints(values)
+	for _, k := range values {
+		s.Rows = append(s.Rows, []string{strconv.Itoa(k), strconv.Itoa(freq[k]), pct(freq[k], len(counts)), bar(freq[k], max, 40)})
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Periods: %d, distinct counts: %d", len(counts), len(values)))
+	return []section{s}
+}
+
+// ── event-hour-of-week ────────────────────────────────────────────────────────
+
+// heatRamp are the intensity characters of text heatmaps, from none to the
+// maximum.
+const heatRamp = " .:-=+*#%@"
+
+// heatChar maps n on a 0..max scale to a heatRamp character; any non-zero
+// count gets at least the faintest mark.
+func heatChar(n, max int) string {
+	if n <= 0 || max <= 0 {
+		return string(heatRamp[0])
+	}
+	i := 1 + (n*(len(heatRamp)-1)-1)/max
+	if i >= len(heatRamp) {
+		i = len(heatRamp) - 1
+	}
+	return string(heatRamp[i])
+}
+
+func prepareEventHourOfWeek(a *aggregates, _ string) error {
+	if a.perHourWeekday == nil {
+		a.perHourWeekday = new([24][7]int)
+	}
+	return nil
+}
+
+// buildEventHourOfWeek draws filtered events by hour of day (rows, in -tz)
+// and weekday (columns) as a heatmap; JSON has the counts in data.
+func buildEventHourOfWeek(a *aggregates, _ string) []section {
+	m := a.perHourWeekday
+	s := section{
+		Key:     "event_hour_of_week",
+		Title:   "Events by Hour of Week",
+		Columns: []string{"Hour", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun", "Events"},
+		Data:    m,
+	}
+	max, total := 0, 0
+	for h := range m {
+		for _, n := range m[h] {
+			if n > max {
+				max = n
+			}
+			total += n
+		}
+	}
+	for h := range m {
+		row := []string{fmt.Sprintf("%02d", h)}
+		sum := 0
+		for _, n := range m[h] {
+			row = append(row, heatChar(n, max))
+			sum += n
+		}
+		s.Rows = append(s.Rows, append(row, strconv.Itoa(sum)))
+	}
+	if total == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Scale %q: blank is no events, %q is the busiest cell (%d events).",
+		heatRamp, heatRamp[len(heatRamp)-1:], max))
+	return []section{s}
+}
+
+// ── top-hours ─────────────────────────────────────────────────────────────────
+
+const defaultTopHours = 10
+
+func parseTopHours(arg string) (int, error) {
+	if arg == "" {
+		return defaultTopHours, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid count %q", arg)
+	}
+	return n, nil
+}
+
+func prepareTopHours(a *aggregates, arg string) error {
+	if _, err := parseTopHours(arg); err != nil {
+		return err
+	}
+	return prepareEventHourOfWeek(a, "")
+}
+
+// buildTopHours ranks the hours of day (in -tz) by filtered events. Each
+// hour occurs once per calendar day from the first to the last day with
+// events, which is what the average is taken over.
+func buildTopHours(a *aggregates, arg string) []section {
+	n, _ := parseTopHours(arg)
+	var perHour [24]int
+	total := 0
+	for h, days := range a.perHourWeekday {
+		for _, c := range days {
+			perHour[h] += c
+		}
+		total += perHour[h]
+	}
+	var first, last string
+	for k := range a.perDay {
+		if first == "" || k < first {
+			first = k
+		}
+		if k > last {
+			last = k
+		}
+	}
+	s := section{
+		Key:     "top_hours",
+		Title:   fmt.Sprintf("Top %d Hours of Day", n),
+		Columns: []string{"Hour", "Events", "Share", "Avg per day"},
+	}
+	if total == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	f, _ := time.Parse("2006-01-02", first)
+	l, _ := time.Parse("2006-01-02", last)
+	days := int(l.Sub(f).Hours()/24) + 1
+	hours := make([]int, 0, 24)
+	for h, c := range perHour {
+		if c > 0 {
+			hours = append(hours, h)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool { return perHour[hours[i]] > perHour[hours[j]] })
+	if len(hours) > n {
+		hours = hours[:n]
+	}
+	for _, h := range hours {
+		s.Rows = append(s.Rows, []string{fmt.Sprintf("%02d:00", h), strconv.Itoa(perHour[h]), pct(perHour[h], total),
+			strconv.FormatFloat(float64(perHour[h])/float64(days), 'f', 2, 64)})
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("%d events over %d days, %s to %s", total, days, first, last))
+	return []section{s}
+}
+
+// ── month-rank-history ────────────────────────────────────────────────────────
+
+func requireMonth(a *aggregates, _ string) error {
+	if a.opts.month == 0 {
+		return fmt.Errorf("requires -m")
+	}
+	return nil
+}
+
+// buildMonthRankHistory ranks the -m month of every year in the data by its
+// event count, busiest first. Equal counts share a rank and the next rank is
+// skipped (1, 2, 2, 4). Years are listed in order; a year whose month has
+// no events is ranked with a count of zero.
+func buildMonthRankHistory(a *aggregates, _ string) []section {
+	name := time.Month(a.opts.month).String()
+	s := section{
+		Key:     "month_rank_history",
+		Title:   fmt.Sprintf("%s Rank History", name),
+		Columns: []string{"Year", "Events", "Rank"},
+	}
+	years := make([]int, 0, len(a.perYear))
+	for y := range a.perYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	counts := make(map[int]int, len(years))
+	for _, y := range years {
+		counts[y] = a.perMonth[fmt.Sprintf("%04d-%02d", y, a.opts.month)]
+	}
+	data := make(map[string]int, len(years))
+	for _, y := range years {
+		rank := 1
+		for _, o := range years {
+			if counts[o] > counts[y] {
+				rank++
+			}
+		}
+		s.Rows = append(s.Rows, []string{strconv.Itoa(y), strconv.Itoa(counts[y]), fmt.Sprintf("%d of %d", rank, len(years))})
+		data[strconv.Itoa(y)] = rank
+	}
+	s.Data = data
+	if len(years) == 0 {
+		s.Notes = append(s.Notes, "No events.")
+	} else {
+		s.Notes = append(s.Notes, fmt.Sprintf("Rank 1 is the busiest %s; all events are counted, whatever -y and -d.", name))
+	}
+	return []section{s}
+}
+
+// ── percentile-ladder ─────────────────────────────────────────────────────────
+
+var ladderPercentiles = []float64{10, 25, 50, 75, 90, 95, 99}
+
+// ladderPeriod is the bucket the ladder ranks: days of the -m month, weeks of
+// the -y year, or months otherwise.
+func ladderPeriod(o reportOptions) string {
+	switch {
+	case o.month != 0:
+		return "day"
+	case o.year != 0:
+		return "week"
+	}
+	return "month"
+}
+
+// buildPercentileLadder prints nearest-rank percentiles of the filtered
+// counts per bucket on one line. Only buckets with events are ranked.
+func buildPercentileLadder(a *aggregates, _ string) []section {
+	period := ladderPeriod(a.opts)
+	_, counts := bucketCounts(a, period)
+	values := make([]int, 0, len(counts))
+	for _, n := range counts {
+		values = append(values, n)
+	}
+	sort.Ints(values)
+	s := section{
+		Key:   "percentile_ladder",
+		Title: "Percentile Ladder (events per " + period + ")",
+		Bare:  true,
+	}
+	var cells, parts []string
+	for _, p := range ladderPercentiles {
+		label := "P" + strconv.FormatFloat(p, 'f', -1, 64)
+		v := strconv.Itoa(percentile(values, p))
+		s.Columns = append(s.Columns, label)
+		cells = append(cells, v)
+		parts = append(parts, label+":%s")
+	}
+	s.Rows = [][]string{cells}
+	s.LineFmt = strings.Join(parts, " ")
+	if len(values) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
+
+// ── full-year-weekly-grid ─────────────────────────────────────────────────────
+
+// termWidth is the terminal width from $COLUMNS, or 80 when it is not set.
+func termWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// buildWeeklyGrid lays out the filtered count of every ISO week of -y in a
+// grid filled column by column: 4 columns when they fit the terminal width,
+// else 2. Days of week 1 or the last week that fall outside -y are not
+// counted, as -y filters them out.
+func buildWeeklyGrid(a *aggregates, _ string) []section {
+	counts := make(map[int]int)
+	for d, n := range a.perDay {
+		t, _ := time.Parse("2006-01-02", d)
+		if y, w := t.ISOWeek(); y == a.opts.year {
+			counts[w] += n
+		}
+	}
+	jan4 := time.Date(a.opts.year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	weeks, width := 0, 1
+	for d := monday; ; d = d.AddDate(0, 0, 7) {
+		if y, _ := d.ISOWeek(); y != a.opts.year {
+			break
+		}
+		weeks++
+		width = max(width, len(strconv.Itoa(counts[weeks])))
+	}
+
+	const gap = "    "
+	cell := func(w int) string { return fmt.Sprintf("W%02d %*d", w, width, counts[w]) }
+	cols := 4
+	if cols*len(cell(1))+(cols-1)*len(gap) > termWidth() {
+		cols = 2
+	}
+	rows := (weeks + cols - 1) / cols
+	s := section{
+		Key:     "full_year_weekly_grid",
+		Title:   fmt.Sprintf("ISO Weeks %d", a.opts.year),
+		Columns: []string{"Weeks"},
+		LineFmt: "%s",
+	}
+	for r := 0; r < rows; r++ {
+		var line []string
+		for c := 0; c < cols; c++ {
+			if w := c*rows + r + 1; w <= weeks {
+				line = append(line, cell(w))
+			}
+		}
+		s.Rows = append(s.Rows, []string{strings.Join(line, gap)})
+	}
+	data := make(map[string]int, weeks)
+	for w := 1; w <= weeks; w++ {
+		data[fmt.Sprintf("%04d-W%02d", a.opts.year, w)] = counts[w]
+	}
+	s.Data = data
+	if a.recordsFiltered == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return []section{s}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestDailyChangeRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		perDay  map[string]int
+		rows    [][]string
+		flagged string
+	}{
+		{
+			name:   "jump from an empty day",
+			arg:    "10",
+			perDay: map[string]int{"2025-03-01": 5, "2025-03-03": 500},
+			rows: [][]string{
+				{"2025-03-01", "5", "+0", "N/A", ""},
+				{"2025-03-02", "0", "-5", "-100.0%", ""},
+				{"2025-03-03", "500", "+500", "N/A", "!"},
+			},
+			flagged: "Days flagged (!) with |delta| > 10: 1",
+		},
+		{
+			name:   "drop to an empty day",
+			arg:    "3",
+			perDay: map[string]int{"2025-03-01": 4, "2025-03-02": 8, "2025-03-04": 1},
+			rows: [][]string{
+				{"2025-03-01", "4", "+0", "N/A", ""},
+				{"2025-03-02", "8", "+4", "+100.0%", "!"},
+				{"2025-03-03", "0", "-8", "-100.0%", "!"},
+				{"2025-03-04", "1", "+1", "N/A", ""},
+			},
+			flagged: "Days flagged (!) with |delta| > 3: 2",
+		},
+		{
+			name:    "default threshold is exclusive",
+			perDay:  map[string]int{"2025-03-01": 10, "2025-03-02": 20},
+			rows:    [][]string{{"2025-03-01", "10", "+0", "N/A", ""}, {"2025-03-02", "20", "+10", "+100.0%", ""}},
+			flagged: "Days flagged (!) with |delta| > 10: 0",
+		},
+		{
+			name:    "no events",
+			perDay:  map[string]int{},
+			flagged: "No events matched.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := buildDailyChangeRate(&aggregates{perDay: tt.perDay}, tt.arg)[0]
+			if !reflect.DeepEqual(s.Rows, tt.rows) {
+				t.Errorf("rows = %q, want %q", s.Rows, tt.rows)
+			}
+			if len(s.Notes) != 1 || s.Notes[0] != tt.flagged {
+				t.Errorf("notes = %q, want %q", s.Notes, tt.flagged)
+			}
+		})
+	}
+}
+
+func TestEventRateTrend(t *testing.T) {
+	tests := []struct {
+		name     string
+		perMonth map[string]int
+		rows     int
+		notes    []string
+	}{
+		{
+			name:     "rising, with an empty month filled in",
+			perMonth: map[string]int{"2025-01": 10, "2025-03": 30, "2024-12": 99},
+			rows:     3,
+			notes:    []string{"Trend: +10.0 events/month", "R²: 0.429"},
+		},
+		{
+			name:     "falling",
+			perMonth: map[string]int{"2025-01": 30, "2025-02": 25, "2025-03": 20},
+			rows:     3,
+			notes:    []string{"Trend: -5.0 events/month", "R²: 1.000"},
+		},
+		{
+			name:     "one month",
+			perMonth: map[string]int{"2025-06": 4},
+			rows:     1,
+			notes:    []string{"Trend: n/a (needs at least two months with data)"},
+		},
+		{
+			name:     "no events in the year",
+			perMonth: map[string]int{"2024-06": 4},
+			rows:     0,
+			notes:    []string{"No events in 2025."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &aggregates{perMonth: tt.perMonth, opts: reportOptions{year: 2025}}
+			s := buildEventRateTrend(a, "")[0]
+			if len(s.Rows) != tt.rows {
+				t.Errorf("rows = %q, want %d", s.Rows, tt.rows)
+			}
+			if !reflect.DeepEqual(s.Notes, tt.notes) {
+				t.Errorf("notes = %q, want %q", s.Notes, tt.notes)
+			}
+		})
+	}
+}
+
+func TestDailyCountsForMonth(t *testing.T) {
+	perDay := map[string]int{
+		"2024-02-29": 7, "2024-02-01": 3, "2024-03-01": 100, "2024-01-31": 100,
+		"2025-02-28": 2, "2025-02-14": 5,
+	}
+	tests := []struct {
+		name        string
+		year, month int
+		want        []int
+	}{
+		{"leap February", 2024, 2, append(make([]int, 27), 3, 7)},
+		{"plain February", 2025, 2, append(make([]int, 26), 2, 5)},
+		{"month without events", 2024, 4, make([]int, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dailyCountsForMonth(tt.year, tt.month, perDay); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dailyCountsForMonth(%d, %d) = %v, want %v", tt.year, tt.month, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyPercentileCalendar(t *testing.T) {
+	perDay := make(map[string]int)
+	for d := 1; d <= 31; d++ {
+		perDay[fmt.Sprintf("2025-01-%02d", d)] = d // P50 16, P95 30
+	}
+	perDay["2025-02-10"] = 4
+	a := &aggregates{perDay: perDay, opts: reportOptions{year: 2025, month: 0}}
+	rows := buildMonthlyPercentileCalendar(a, "")[0].Rows
+	if len(rows) != 12 {
+		t.Fatalf("%d rows, want 12", len(rows))
+	}
+	want := [][]string{{"Jan", "496", "16", "30"}, {"Feb", "4", "0", "0"}, {"Mar", "0", "0", "0"}}
+	if !reflect.DeepEqual(rows[:3], want) {
+		t.Errorf("rows = %q, want %q", rows[:3], want)
+	}
+	a.opts.month = 2
+	if rows := buildMonthlyPercentileCalendar(a, "")[0].Rows; !reflect.DeepEqual(rows, want[1:2]) {
+		t.Errorf("with -m 2: rows = %q, want %q", rows, want[1:2])
+	}
+}
+
+// emptyStateExempt are the sections whose zeros are the answer, or that count
+// events outside the filters: the year total and the month's rank among all
+// years.
+var emptyStateExempt = map[string]bool{"year": true, "month_rank_history": true}
+
+// TestEmptyStates runs every report on one record, once as an object and
+// once as a one-element array, with filters it misses and with filters it
+// matches. Both inputs must give the same output, and with no match every
+// section says so.
+func TestEmptyStates(t *testing.T) {
+	dir := t.TempDir()
+	record := event(1, "2025-03-14T12:00:00Z", "n")
+	writeFile(t, dir, "object.json", record)
+	writeFile(t, dir, "array.json", eventArray(record))
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		// monthly-summary-json replaces the report; event-size-stats is
+		// skipped without a size field.
+		if name != "monthly-summary-json" && name != "event-size-stats" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			for _, year := range []string{"2024", "2025"} {
+				var outs []string
+				for _, file := range []string{"object.json", "array.json"} {
+					out, errOut, status := runTool(t, dir, "-f", file, "-y", year, "-m", "3", "-report="+name, "-output", "json")
+					if status != 0 {
+						t.Fatalf("%s, -y %s: exit %d\n%s", file, year, status, errOut)
+					}
+					outs = append(outs, out)
+				}
+				if outs[0] != outs[1] {
+					t.Errorf("-y %s: an object and a one-element array differ:\n%s\n%s", year, outs[0], outs[1])
+				}
+				if year != "2024" {
+					continue
+				}
+				var doc struct{ Sections []section }
+				if err := json.Unmarshal([]byte(outs[0]), &doc); err != nil {
+					t.Fatalf("%v\n%s", err, outs[0])
+				}
+				for _, s := range doc.Sections {
+					if !emptyStateExempt[s.Key] && !slices.Contains(s.Notes, "No events matched.") && !slices.Contains(s.Notes, "No events in 2024.") {
+						t.Errorf("section %s has no empty-state note: %q", s.Key, s.Notes)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -report-dir ───────────────────────────────────────────────────────────────
+//
+// Each run writes its outputs into a new subdirectory of -report-dir named
+// after the UTC start time, points "latest" at it and removes the oldest runs
+// beyond -keep. The run is written under a ".tmp-" name and renamed once
+// complete, and "latest" is replaced by renaming a new link over it (a copy
+// on Windows; see replaceLatest), so after
+// a crash at any step "latest" still names a complete run; at worst a stale
+// ".tmp-" directory is left, which the next run removes. Pruning only ever
+// removes run directories, and is skipped with a warning when the directory
+// holds anything this code did not create.
+
+const (
+	reportDirLayout = "20060102T150405Z"
+	reportDirTemp   = ".tmp-"
+	reportDirLatest = "latest"
+	reportDirNew    = "latest.new" // "latest" being replaced
+)
+
+type reportDir struct {
+	path  string
+	keep  int
+	name  string // this run's subdirectory
+	files []reportFile
+}
+
+type reportFile struct {
+	name string
+	buf  *bytes.Buffer
+}
+
+// newReportDir creates path if needed and names this run's subdirectory
+// after started, with a -N suffix above any used by earlier runs in the
+// same second.
+func newReportDir(path string, keep int, started time.Time) (*reportDir, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("-keep must be at least 1")
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	base := started.UTC().Format(reportDirLayout)
+	last := 0
+	for _, e := range entries {
+		if stamp, n, ok := parseRunName(strings.TrimPrefix(e.Name(), reportDirTemp)); ok && stamp == base {
+			last = max(last, n)
+		}
+	}
+	name := base
+	if last > 0 {
+		name = fmt.Sprintf("%s-%d", base, last+1)
+	}
+	return &reportDir{path: path, keep: keep, name: name}, nil
+}
+
+// tee returns a writer that writes to w and keeps a copy for the file name
+// of this run. A nil reportDir returns w.
+func (d *reportDir) tee(w io.Writer, name string) io.Writer {
+	if d == nil {
+		return w
+	}
+	buf := new(bytes.Buffer)
+	d.files = append(d.files, reportFile{name, buf})
+	return io.MultiWriter(w, buf)
+}
+
+// reportFileName is the file a -output format is saved as.
+func reportFileName(format string) string {
+	switch format {
+	case "json":
+		return "report.json"
+	case "jira":
+		return "report.jira"
+	case "csv":
+		return "report.csv"
+	case "tsv":
+		return "report.tsv"
+	case "html", "html-full":
+		return "report.html"
+	}
+	return "report.txt"
+}
+
+// publish writes the kept outputs, moves "latest" to them and prunes.
+func (d *reportDir) publish() error {
+	if d == nil {
+		return nil
+	}
+	tmp := filepath.Join(d.path, reportDirTemp+d.name)
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		return err
+	}
+	for _, f := range d.files {
+		if err := writeSynced(filepath.Join(tmp, f.name), f.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := syncDir(tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(d.path, d.name)); err != nil {
+		return err
+	}
+	if err := replaceLatest(d.path, d.name); err != nil {
+		return err
+	}
+	if err := syncDir(d.path); err != nil {
+		return err
+	}
+	return d.prune()
+}
+
+// prune removes the oldest run directories beyond keep, and the ".tmp-"
+// directories of earlier runs that did not finish.
+func (d *reportDir) prune() error {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return err
+	}
+	var runs, stale []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case name == reportDirLatest || name == reportDirNew:
+		case strings.HasPrefix(name, reportDirTemp) && e.IsDir() && isRunName(strings.TrimPrefix(name, reportDirTemp)):
+			stale = append(stale, name)
+		case e.IsDir() && isRunName(name):
+			runs = append(runs, name)
+		default:
+			fmt.Fprintf(noteOut, "warning: -report-dir: unexpected entry %q in %s; not pruning\n", name, d.path)
+			return nil
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		si, ni, _ := parseRunName(runs[i])
+		sj, nj, _ := parseRunName(runs[j])
+		if si != sj {
+			return si < sj
+		}
+		return ni < nj
+	})
+	if len(runs) > d.keep {
+		stale = append(stale, runs[:len(runs)-d.keep]...)
+	}
+	for _, name := range stale {
+		if name == d.name {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(d.path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRunName splits a run directory name into its start time stamp and
+// its -N suffix, 1 when there is none.
+func parseRunName(name string) (stamp string, n int, ok bool) {
+	stamp, suffix, found := strings.Cut(name, "-")
+	if _, err := time.Parse(reportDirLayout, stamp); err != nil {
+		return "", 0, false
+	}
+	n = 1
+	if found {
+		var err error
+		if n, err = strconv.Atoi(suffix); err != nil || n < 2 {
+			return "", 0, false
+		}
+	}
+	return stamp, n, true
+}
+
+func isRunName(name string) bool {
+	_, _, ok := parseRunName(name)
+	return ok
+}
+
+func writeSynced(path string, b []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
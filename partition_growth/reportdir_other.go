@@ -0,0 +1,31 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// replaceLatest points the "latest" symlink at the run directory name by
+// renaming a new relative link over it, which is atomic.
+func replaceLatest(dir, name string) error {
+	tmp := filepath.Join(dir, reportDirNew)
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(name, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, reportDirLatest))
+}
+
+// syncDir flushes the entries of dir, so renames in it survive a crash.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
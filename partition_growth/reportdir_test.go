@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// publishRun simulates one run started at started: it saves content as its
+// text report in dir and publishes it.
+func publishRun(t *testing.T, dir string, keep int, started time.Time, content string) string {
+	t.Helper()
+	d, err := newReportDir(dir, keep, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(d.tee(io.Discard, "report.txt"), content)
+	if err := d.publish(); err != nil {
+		t.Fatal(err)
+	}
+	return d.name
+}
+
+// entries lists the names in dir, sorted.
+func entries(t *testing.T, dir string) []string {
+	t.Helper()
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range es {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func latestReport(t *testing.T, dir string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, reportDirLatest, "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestReportDirRetention publishes seven runs with -keep 3, two of them in
+// the same second: after each, the newest three runs and "latest" remain,
+// and "latest" holds the run just published.
+func TestReportDirRetention(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	t0 := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+	starts := []time.Time{
+		t0, t0.Add(24 * time.Hour), t0.Add(48 * time.Hour), t0.Add(48*time.Hour + 300*time.Millisecond),
+		t0.Add(72 * time.Hour), t0.Add(96 * time.Hour), t0.Add(120 * time.Hour),
+	}
+	var runs []string
+	for i, started := range starts {
+		content := fmt.Sprintf("run %d\n", i)
+		name := publishRun(t, dir, 3, started, content)
+		runs = append(runs, name)
+		want := append([]string(nil), runs[max(0, len(runs)-3):]...)
+		want = append(want, reportDirLatest)
+		sort.Strings(want)
+		if got := entries(t, dir); !reflect.DeepEqual(got, want) {
+			t.Errorf("after run %d: entries %q, want %q", i, got, want)
+		}
+		if got := latestReport(t, dir); got != content {
+			t.Errorf("after run %d: latest holds %q", i, got)
+		}
+	}
+	if runs[3] != "20250303T020000Z-2" {
+		t.Errorf("second run in the same second named %q, want a -2 suffix", runs[3])
+	}
+}
+
+// TestReportDirUnexpectedEntry leaves every run in place when the directory
+// holds something this code did not create, but still publishes.
+func TestReportDirUnexpectedEntry(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+	publishRun(t, dir, 1, t0, "first\n")
+	for _, name := range []string{"notes.txt", ".tmp-notes"} {
+		writeFile(t, dir, name, "keep me")
+	}
+	publishRun(t, dir, 1, t0.Add(time.Hour), "second\n")
+	want := []string{".tmp-notes", "20250301T020000Z", "20250301T030000Z", reportDirLatest, "notes.txt"}
+	if got := entries(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %q, want %q", got, want)
+	}
+	if got := latestReport(t, dir); got != "second\n" {
+		t.Errorf("latest holds %q", got)
+	}
+}
+
+// TestReportDirStaleTemp removes what a crashed run left behind: its ".tmp-"
+// run directory and a half-made "latest.new".
+func TestReportDirStaleTemp(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+	publishRun(t, dir, 5, t0, "first\n")
+	stale := filepath.Join(dir, reportDirTemp+"20250301T020500Z")
+	if err := os.Mkdir(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, stale, "report.txt", "partial")
+	if err := os.Symlink("20250301T020500Z", filepath.Join(dir, reportDirNew)); err != nil {
+		t.Logf("no symlinks here (%v); testing the .tmp- directory only", err)
+	}
+	if got := latestReport(t, dir); got != "first\n" {
+		t.Errorf("latest after the crash holds %q, want the last complete run", got)
+	}
+	publishRun(t, dir, 5, t0.Add(time.Hour), "second\n")
+	want := []string{"20250301T020000Z", "20250301T030000Z", reportDirLatest}
+	if got := entries(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %q, want %q", got, want)
+	}
+}
+
+func TestParseRunName(t *testing.T) {
+	tests := []struct {
+		name  string
+		stamp string
+		n     int
+		ok    bool
+	}{
+		{"20250301T020000Z", "20250301T020000Z", 1, true},
+		{"20250301T020000Z-2", "20250301T020000Z", 2, true},
+		{"20250301T020000Z-1", "", 0, false},
+		{"20250301T020000Z-x", "", 0, false},
+		{"20250301", "", 0, false},
+		{"latest", "", 0, false},
+	}
+	for _, tt := range tests {
+		stamp, n, ok := parseRunName(tt.name)
+		if stamp != tt.stamp || n != tt.n || ok != tt.ok {
+			t.Errorf("parseRunName(%q) = %q, %d, %v; want %q, %d, %v", tt.name, stamp, n, ok, tt.stamp, tt.n, tt.ok)
+		}
+	}
+}
+
+// TestReportDirCommand runs the command with -report-dir: the saved report
+// matches stdout, under the name of its format.
+func TestReportDirCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "in.json", eventArray(event(1, "2025-01-01T00:00:00Z", "n")))
+	for _, tt := range []struct{ format, file string }{{"text", "report.txt"}, {"json", "report.json"}} {
+		out, errOut, status := runTool(t, dir, "-f", "in.json", "-a", "-output", tt.format, "-report-dir", "reports", "-keep", "1")
+		if status != 0 {
+			t.Fatalf("exit %d\n%s", status, errOut)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "reports", reportDirLatest, tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != out {
+			t.Errorf("saved %s differs from stdout:\n%s\n%s", tt.file, b, out)
+		}
+	}
+	var runs int
+	for _, name := range entries(t, filepath.Join(dir, "reports")) {
+		if isRunName(name) {
+			runs++
+		} else if name != reportDirLatest {
+			t.Errorf("unexpected entry %q", name)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("%d runs kept with -keep 1", runs)
+	}
+}
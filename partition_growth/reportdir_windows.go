@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// replaceLatest copies the run directory name to "latest", since creating
+// symlinks needs privileges on Windows. The copy is made under another name
+// first, but replacing a directory is not atomic here: a crash between the
+// removal and the rename leaves no "latest" until the next run.
+func replaceLatest(dir, name string) error {
+	tmp := filepath.Join(dir, reportDirNew)
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, name, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := writeSynced(filepath.Join(tmp, e.Name()), b); err != nil {
+			return err
+		}
+	}
+	latest := filepath.Join(dir, reportDirLatest)
+	if err := os.RemoveAll(latest); err != nil {
+		return err
+	}
+	return os.Rename(tmp, latest)
+}
+
+// syncDir is a no-op: directories cannot be opened for syncing on Windows.
+func syncDir(string) error {
+	return nil
+}
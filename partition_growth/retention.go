@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/bits"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ── -retention ────────────────────────────────────────────────────────────────
+//
+// Each ParentID with an event in -y is assigned to the cohort of the first
+// month it was active that year. The table shows, for every cohort, the share
+// of its parents active again 1, 2, 3… months later. Memory is one 16-bit
+// month mask per distinct parent of the year.
+
+func (a *aggregates) enableParentMonths() {
+	if a.parentMonths == nil {
+		a.parentMonths = make(map[int]uint16)
+	}
+}
+
+// addParentMonth marks parent active in the month of dt when dt is in -y.
+func (a *aggregates) addParentMonth(dt time.Time, parent int) {
+	if dt.Year() == a.opts.year {
+		a.parentMonths[parent] |= 1 << (uint(dt.Month()) - 1)
+	}
+}
+
+// retentionTable returns the rows of the cohort triangle: cohort month, size
+// and the retained percentage for each later month of the year.
+func (a *aggregates) retentionTable() (rows [][]string, suppressed int) {
+	var size [12]int
+	var active [12][12]int // [cohort][offset]
+	for _, mask := range a.parentMonths {
+		c := bits.TrailingZeros16(mask)
+		size[c]++
+		for off := 1; c+off < 12; off++ {
+			if mask&(1<<uint(c+off)) != 0 {
+				active[c][off]++
+			}
+		}
+	}
+	for c := 0; c < 12; c++ {
+		if size[c] == 0 {
+			continue
+		}
+		if size[c] < a.opts.minCohort {
+			suppressed++
+			continue
+		}
+		row := []string{fmt.Sprintf("%04d-%02d", a.opts.year, c+1), strconv.Itoa(size[c])}
+		for off := 1; c+off < 12; off++ {
+			row = append(row, pct(active[c][off], size[c]))
+		}
+		rows = append(rows, row)
+	}
+	return rows, suppressed
+}
+
+func retentionColumns() []string {
+	cols := []string{"Cohort", "Parents"}
+	for off := 1; off < 12; off++ {
+		cols = append(cols, fmt.Sprintf("+%d", off))
+	}
+	return cols
+}
+
+func buildRetention(a *aggregates) []section {
+	rows, suppressed := a.retentionTable()
+	s := section{
+		Key:     "retention",
+		Title:   fmt.Sprintf("Parent Retention by Monthly Cohort %d", a.opts.year),
+		Columns: retentionColumns(),
+		Rows:    rows,
+	}
+	s.Notes = append(s.Notes, "+n: share of the cohort's parents with an event n months after their first month in the year.")
+	if suppressed > 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d cohorts with fewer than %d parents suppressed (-min-cohort).", suppressed, a.opts.minCohort))
+	}
+	return []section{s}
+}
+
+// writeRetentionCSV writes the same triangle as CSV, leaving the months after
+// the end of the year empty.
+func writeRetentionCSV(path string, a *aggregates) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	cols := retentionColumns()
+	w.Write(cols)
+	rows, _ := a.retentionTable()
+	for _, r := range rows {
+		for len(r) < len(cols) {
+			r = append(r, "")
+		}
+		w.Write(r)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+package main
+
+import "time"
+
+// ── -a / -a-filtered ──────────────────────────────────────────────────────────
+
+// rollup is the data behind the -a sections. Plain -a reads the maps kept
+// for all events; -a-filtered fills one from the events passing the report
+// filters, so the yearly rows and the grand total describe the same events.
+type rollup struct {
+	perYear    map[int]int
+	perQuarter map[string]int
+	perMonth   map[string]int
+	sideMonth  map[string]int // -with-weight sums per month
+	dates      []time.Time
+	weights    []int // with -weight-field, parallel to dates
+}
+
+func newRollup() *rollup {
+	return &rollup{
+		perYear:    make(map[int]int),
+		perQuarter: make(map[string]int),
+		perMonth:   make(map[string]int),
+		sideMonth:  make(map[string]int),
+	}
+}
+
+// add records one filtered event of count weight w and -with-weight sum sw.
+func (r *rollup) add(dt time.Time, k eventKeys, w, sw int, weighting *weightTotals) {
+	r.perYear[k.Year] += w
+	r.perQuarter[k.Quarter] += w
+	r.perMonth[k.Month] += w
+	r.dates = append(r.dates, dt)
+	if weighting.field != "" {
+		if weighting.dual {
+			r.sideMonth[k.Month] += sw
+		} else {
+			r.weights = append(r.weights, w)
+		}
+	}
+}
+
+// rollupScope heads the -a sections with the events they cover; filters
+// describes the active report filters and is empty when there are none.
+func rollupScope(filtered bool, filters string) section {
+	note := "All-years rollup of all events; the report filters (" + filters + ") are not applied, see -a-filtered"
+	switch {
+	case filters == "":
+		note = "All-years rollup of all events (no filters set)"
+	case filtered:
+		note = "All-years rollup of events matching " + filters
+	}
+	return section{
+		Key:   "rollup_scope",
+		Title: "Rollup scope",
+		Bare:  true,
+		Notes: []string{note},
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestRollupFiltered checks that under -a-filtered the yearly, quarterly and
+// monthly rollups and the grand total all add up to the filtered total, and
+// that -a still covers every event and says so.
+func TestRollupFiltered(t *testing.T) {
+	dir := t.TempDir()
+	var records []string
+	for i, date := range []string{"2023-12-31T23:00:00Z", "2024-01-15T10:00:00Z", "2024-02-01T00:00:00Z",
+		"2024-02-29T12:00:00Z", "2024-02-29T13:00:00Z", "2024-03-01T00:00:00Z", "2024-07-04T10:00:00Z", "2025-02-28T10:00:00Z"} {
+		records = append(records, event(10*i, date, "n"))
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+	tests := []struct {
+		args  []string
+		total int
+		scope string
+	}{
+		{[]string{"-y", "2024", "-m", "2", "-a-filtered"}, 3, "events matching year 2024, month Feb"},
+		{[]string{"-y", "2024", "-a-filtered"}, 6, "events matching year 2024"},
+		{[]string{"-y", "2024", "-m", "2", "-d", "29", "-a-filtered"}, 2, "events matching"},
+		{[]string{"-from", "2024-01-01", "-to", "2024-02-29", "-a-filtered"}, 4, "events matching"},
+		{[]string{"-y", "2024", "-m", "2", "-a"}, 8, "report filters (year 2024, month Feb) are not applied, see -a-filtered"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, errOut, status := runTool(t, dir, append([]string{"-f", "in.json", "-output", "json"}, tt.args...)...)
+			if status != 0 {
+				t.Fatalf("exit %d\n%s", status, errOut)
+			}
+			var doc struct{ Sections []section }
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatal(err)
+			}
+			sums := map[string]int{}
+			for _, s := range doc.Sections {
+				switch s.Key {
+				case "yearly", "quarterly", "monthly":
+					for _, r := range s.Rows {
+						n, err := strconv.Atoi(r[1])
+						if err != nil {
+							t.Fatal(err)
+						}
+						sums[s.Key] += n
+					}
+				case "grand_total":
+					var n int
+					fmt.Sscanf(s.Notes[0], "Grand Total (All Years): %d splits", &n)
+					sums[s.Key] = n
+				case "month_weekly":
+					note := s.Notes[len(s.Notes)-1]
+					n, err := strconv.Atoi(note[strings.LastIndex(note, " ")+1:])
+					if err != nil || (slices.Contains(tt.args, "-a-filtered") && n != tt.total) {
+						t.Errorf("month total %q, want %d", note, tt.total)
+					}
+				case "rollup_scope":
+					if !strings.Contains(s.Notes[0], tt.scope) {
+						t.Errorf("scope note %q lacks %q", s.Notes[0], tt.scope)
+					}
+				}
+			}
+			for _, key := range []string{"yearly", "quarterly", "monthly", "grand_total"} {
+				if sums[key] != tt.total {
+					t.Errorf("%s adds up to %d, want %d", key, sums[key], tt.total)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ── -show-samples ─────────────────────────────────────────────────────────────
+
+// sampledEvent is one event held in the reservoir.
+type sampledEvent struct {
+	dt  time.Time
+	k   eventKeys
+	evt Event
+}
+
+// reservoir keeps a uniform random sample of n of the filtered events
+// (Algorithm R), so memory is O(n) however long the input. The same seed and
+// input give the same sample as long as events arrive in the same order,
+// which holds with -workers 1.
+type reservoir struct {
+	n     int
+	seed  int64
+	rng   *rand.Rand
+	seen  int
+	items []sampledEvent
+}
+
+// newReservoir returns a reservoir of n events; seed 0 picks one from the
+// clock, which the section reports so the run can be repeated with -seed.
+func newReservoir(n int, seed int64) *reservoir {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &reservoir{n: n, seed: seed, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (r *reservoir) add(dt time.Time, k eventKeys, evt Event) {
+	r.seen++
+	if len(r.items) < r.n {
+		r.items = append(r.items, sampledEvent{dt, k, evt})
+		return
+	}
+	if j := r.rng.Intn(r.seen); j < r.n {
+		r.items[j] = sampledEvent{dt, k, evt}
+	}
+}
+
+// section lists the sampled events in time order with the day, ISO week and
+// month they were counted in and their canonical JSON.
+func (r *reservoir) section() section {
+	items := append([]sampledEvent(nil), r.items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].dt.Before(items[j].dt) })
+	s := section{
+		Key:     "samples",
+		Title:   "Sampled Events",
+		Columns: []string{"Time", "Day", "ISO week", "Month", "Record"},
+	}
+	for _, it := range items {
+		evt := it.evt
+		evt.Date = it.dt.Format(time.RFC3339Nano)
+		rec, err := evt.Canonical()
+		if err != nil {
+			rec = []byte(err.Error())
+		}
+		s.Rows = append(s.Rows, []string{it.dt.Format(time.RFC3339), it.k.Day, it.k.ISOWeek, it.k.Month, string(rec)})
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("%d of %d counted events, -seed %d", len(items), r.seen, r.seed))
+	return s
+}
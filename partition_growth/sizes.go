@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"partition_growth/quantile"
+)
+
+// ── event-size-stats ──────────────────────────────────────────────────────────
+//
+// Per-month statistics of the optional "size" field (payload bytes) of the
+// filtered events. Events without a size, or with size 0, are counted apart
+// and left out of the statistics. Percentiles come from a t-digest per month,
+// so memory stays bounded; total, average and max are exact.
+
+// sizeStats accumulates the sizes of the events in one month.
+type sizeStats struct {
+	count  int
+	total  int64
+	max    int64
+	digest *quantile.TDigest
+}
+
+// eventSizes is the event-size-stats aggregate.
+type eventSizes struct {
+	months  map[string]*sizeStats // "YYYY-MM"
+	unsized int                   // filtered events without a size
+}
+
+func prepareEventSizeStats(a *aggregates, _ string) error {
+	if a.eventSizes == nil {
+		a.eventSizes = &eventSizes{months: make(map[string]*sizeStats)}
+	}
+	return nil
+}
+
+func (e *eventSizes) add(dt time.Time, size int64) {
+	if size <= 0 {
+		e.unsized++
+		return
+	}
+	mk := dt.Format("2006-01")
+	s := e.months[mk]
+	if s == nil {
+		s = &sizeStats{digest: quantile.New(quantile.DefaultCompression)}
+		e.months[mk] = s
+	}
+	s.count++
+	s.total += size
+	s.max = max(s.max, size)
+	s.digest.Add(float64(size))
+}
+
+func (e *eventSizes) size() (int, int64) {
+	var b int64
+	for k, s := range e.months {
+		b += int64(stringHeader+len(k)) + 8 + 8 + 8 + int64(s.digest.Len())*16 + mapEntryOverhead
+	}
+	return len(e.months), b
+}
+
+// buildEventSizeStats reports bytes per month. When no filtered event has a
+// size it warns and adds nothing, so inputs from before the field existed
+// still run.
+func buildEventSizeStats(a *aggregates, _ string) []section {
+	e := a.eventSizes
+	if len(e.months) == 0 {
+		fmt.Fprintln(noteOut, "warning: -report=event-size-stats: no matching event has a size field; report skipped")
+		return nil
+	}
+	s := section{
+		Key:     "event_size_stats",
+		Title:   "Event Size by Month",
+		Columns: []string{"Month", "Events", "Total bytes", "Avg", "P50", "P95", "P99", "Max"},
+	}
+	months := make([]string, 0, len(e.months))
+	for m := range e.months {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	q := func(st *sizeStats, p float64) string {
+		return strconv.FormatInt(int64(math.Round(st.digest.Quantile(p))), 10)
+	}
+	for _, m := range months {
+		st := e.months[m]
+		s.Rows = append(s.Rows, []string{m, strconv.Itoa(st.count), strconv.FormatInt(st.total, 10),
+			strconv.FormatFloat(float64(st.total)/float64(st.count), 'f', 1, 64),
+			q(st, 0.50), q(st, 0.95), q(st, 0.99), strconv.FormatInt(st.max, 10)})
+	}
+	s.Notes = append(s.Notes, "Percentiles are t-digest estimates; total, average and max are exact.")
+	if e.unsized > 0 {
+		s.Notes = append(s.Notes, fmt.Sprintf("%d matching events without a size are not included.", e.unsized))
+	}
+	return []section{s}
+}
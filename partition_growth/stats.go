@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// regressSlope fits y = slope*x + intercept by ordinary least squares and
+// returns the coefficient of determination r2 alongside the fit.
+func regressSlope(xs, ys []float64) (slope, intercept, r2 float64) {
+	n := float64(len(xs))
+	if len(xs) < 2 || len(xs) != len(ys) {
+		if len(ys) == 1 {
+			return 0, ys[0], 0
+		}
+		return 0, 0, 0
+	}
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, my, 0
+	}
+	slope = sxy / sxx
+	intercept = my - slope*mx
+	if syy == 0 {
+		return slope, intercept, 1
+	}
+	r2 = sxy * sxy / (sxx * syy)
+	return slope, intercept, math.Min(r2, 1)
+}
+
+// percentile returns the nearest-rank p-th percentile (0 < p <= 100) of an
+// ascending slice, or 0 when it is empty.
+func percentile(sorted []int, p float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// slidingWindow keeps the last size values in ascending order so that
+// percentiles of the window cost one lookup.
+type slidingWindow struct {
+	size   int
+	fifo   []int
+	sorted []int
+}
+
+func (w *slidingWindow) push(v int) {
+	if len(w.fifo) == w.size {
+		old := w.fifo[0]
+		w.fifo = w.fifo[1:]
+		i := sort.SearchInts(w.sorted, old)
+		w.sorted = append(w.sorted[:i], w.sorted[i+1:]...)
+	}
+	w.fifo = append(w.fifo, v)
+	i := sort.SearchInts(w.sorted, v)
+	w.sorted = append(w.sorted, 0)
+	copy(w.sorted[i+1:], w.sorted[i:])
+	w.sorted[i] = v
+}
+
+func (w *slidingWindow) full() bool { return len(w.fifo) == w.size }
+
+// meanStddev returns the mean and population standard deviation of counts.
+func meanStddev(counts []int) (mean, stddev float64) {
+	if len(counts) == 0 {
+		return 0, 0
+	}
+	for _, c := range counts {
+		mean += float64(c)
+	}
+	mean /= float64(len(counts))
+	for _, c := range counts {
+		d := float64(c) - mean
+		stddev += d * d
+	}
+	return mean, math.Sqrt(stddev / float64(len(counts)))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegressSlope(t *testing.T) {
+	tests := []struct {
+		name                string
+		xs, ys              []float64
+		slope, intercept, r float64
+	}{
+		{"perfect rise", []float64{0, 1, 2, 3}, []float64{10, 22, 34, 46}, 12, 10, 1},
+		{"perfect fall", []float64{0, 1, 2}, []float64{9, 6, 3}, -3, 9, 1},
+		{"flat", []float64{0, 1, 2}, []float64{5, 5, 5}, 0, 5, 1},
+		{"noisy", []float64{0, 1, 2, 3}, []float64{1, 3, 2, 4}, 0.8, 1.3, 0.64},
+		{"single point", []float64{0}, []float64{7}, 0, 7, 0},
+		{"no points", nil, nil, 0, 0, 0},
+		{"same x", []float64{2, 2}, []float64{1, 3}, 0, 2, 0},
+		{"length mismatch", []float64{0, 1}, []float64{1}, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slope, intercept, r2 := regressSlope(tt.xs, tt.ys)
+			if !near(slope, tt.slope) || !near(intercept, tt.intercept) || !near(r2, tt.r) {
+				t.Errorf("regressSlope = (%g, %g, %g), want (%g, %g, %g)", slope, intercept, r2, tt.slope, tt.intercept, tt.r)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		p    float64
+		want int
+	}{
+		{10, 1}, {50, 5}, {55, 6}, {95, 10}, {100, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(1..10, %g) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil, 50) = %d, want 0", got)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ── -streaming-output / -assert-sorted ────────────────────────────────────────
+
+// orderCheck fails on the first event that is earlier than the one before
+// it. Streaming output depends on it: a period is only known to be complete
+// when an event of a later period arrives.
+type orderCheck struct {
+	last time.Time
+	n    int
+}
+
+func (o *orderCheck) check(dt time.Time) error {
+	o.n++
+	if dt.Before(o.last) {
+		return fmt.Errorf("event %d at %s is earlier than the event before it (%s); input is not sorted",
+			o.n, dt.Format(time.RFC3339), o.last.Format(time.RFC3339))
+	}
+	o.last = dt
+	return nil
+}
+
+// streamPeriods lists the -stream-period values.
+var streamPeriods = map[string]func(time.Time) string{
+	"day":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"week":  func(t time.Time) string { y, w := t.ISOWeek(); return fmt.Sprintf("%04d-W%02d", y, w) },
+	"month": func(t time.Time) string { return t.Format("2006-01") },
+	"year":  func(t time.Time) string { return t.Format("2006") },
+}
+
+// periodStreamer prints the filtered count of each period as soon as an
+// event of a later period shows that it is closed, and the last period at
+// the end of the input.
+type periodStreamer struct {
+	w   io.Writer
+	key func(time.Time) string
+	cur string
+	n   int
+}
+
+func newPeriodStreamer(w io.Writer, period string) *periodStreamer {
+	return &periodStreamer{w: w, key: streamPeriods[period]}
+}
+
+// add counts an event of weight w.
+func (s *periodStreamer) add(dt time.Time, w int) {
+	k := s.key(dt)
+	if k != s.cur {
+		s.flush()
+		s.cur = k
+	}
+	s.n += w
+}
+
+func (s *periodStreamer) flush() {
+	if s.n > 0 {
+		fmt.Fprintf(s.w, "%s: %d\n", s.cur, s.n)
+	}
+	s.n = 0
+}
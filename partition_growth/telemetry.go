@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ── -telemetry ────────────────────────────────────────────────────────────────
+//
+// Sizes are estimated from what each aggregation holds, not measured on the
+// heap: a map entry costs its key and value plus mapEntryOverhead for the
+// bucket slot, hash and load factor slack. The figures are meant for comparing
+// aggregations and spotting growth, not for exact accounting.
+
+const (
+	mapEntryOverhead = 16 // per entry, amortized bucket and tophash cost
+	stringHeader     = 16
+	timeSize         = 24
+)
+
+// aggTelemetry describes the retained size of one active aggregation.
+type aggTelemetry struct {
+	Name    string `json:"name"`
+	Buckets int    `json:"buckets"`
+	Bytes   int64  `json:"approx_bytes"`
+	Records int    `json:"records"` // events offered to the aggregation
+}
+
+func stringIntMapBytes(m map[string]int) int64 {
+	var b int64
+	for k := range m {
+		b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead
+	}
+	return b
+}
+
+func stringSetMapBytes(m map[string]map[string]bool) (buckets int, b int64) {
+	for k, set := range m {
+		b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead
+		for s := range set {
+			b += int64(stringHeader+len(s)) + 1 + mapEntryOverhead
+		}
+		buckets += len(set)
+	}
+	return buckets, b
+}
+
+// telemetry lists every aggregation that is enabled for this run.
+func (a *aggregates) telemetry() []aggTelemetry {
+	all, filtered := a.recordsAll, a.recordsFiltered
+	t := []aggTelemetry{
+		{"per-day (filtered)", len(a.perDay), stringIntMapBytes(a.perDay), filtered},
+		{"per-day (all)", len(a.perDayAll), stringIntMapBytes(a.perDayAll), all},
+		{"per-week (filtered)", len(a.perWeek), stringIntMapBytes(a.perWeek), filtered},
+		{"per-ISO-week (all)", len(a.perISOWeekAll), stringIntMapBytes(a.perISOWeekAll), all},
+		{"per-month (all)", len(a.perMonth), stringIntMapBytes(a.perMonth), all},
+		{"per-quarter (all)", len(a.perQuarter), stringIntMapBytes(a.perQuarter), all},
+		{"per-year (all)", len(a.perYear), int64(len(a.perYear)) * (16 + mapEntryOverhead), all},
+	}
+	if a.perParent != nil {
+		t = append(t, aggTelemetry{"per-parent", len(a.perParent), int64(len(a.perParent)) * (16 + mapEntryOverhead), filtered})
+	}
+	if a.parentSpans != nil {
+		t = append(t, aggTelemetry{"parent spans", len(a.parentSpans),
+			int64(len(a.parentSpans)) * (16 + 2*timeSize + 8 + mapEntryOverhead), filtered})
+	}
+	if a.monthParentCounts != nil {
+		n, b := 0, int64(0)
+		for k, m := range a.monthParentCounts {
+			n += len(m)
+			b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead + int64(len(m))*(16+mapEntryOverhead)
+		}
+		t = append(t, aggTelemetry{"month parent counts", n, b, filtered})
+	}
+	if a.parentMonthLeaders != nil {
+		n, b := 0, int64(0)
+		for _, months := range a.parentMonthLeaders {
+			b += 16 + mapEntryOverhead
+			for k, m := range months {
+				n += len(m)
+				b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead + stringIntMapBytes(m)
+			}
+		}
+		t = append(t, aggTelemetry{"parent month leaders", n, b, filtered})
+	}
+	if a.monthLeaders != nil {
+		n, b := stringSetMapBytes(a.monthLeaders)
+		t = append(t, aggTelemetry{"month leaders (all)", n, b, all})
+	}
+	if a.leaderDays != nil {
+		n, b := stringSetMapBytes(a.leaderDays)
+		t = append(t, aggTelemetry{"leader days", n, b, filtered})
+	}
+	if a.monthLeaderCounts != nil {
+		n, b := 0, int64(0)
+		for k, m := range a.monthLeaderCounts {
+			n += len(m)
+			b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead + stringIntMapBytes(m)
+		}
+		t = append(t, aggTelemetry{"month leader counts", n, b, filtered})
+	}
+	if a.leaderDayCounts != nil {
+		n, b := 0, int64(0)
+		for k, m := range a.leaderDayCounts {
+			n += len(m)
+			b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead + stringIntMapBytes(m)
+		}
+		t = append(t, aggTelemetry{"leader day counts", n, b, filtered})
+	}
+	if a.weekLeaders != nil {
+		n, b := 0, int64(0)
+		for _, m := range a.weekLeaders {
+			n += len(m)
+			b += 8 + 8 + mapEntryOverhead + stringIntMapBytes(m)
+		}
+		t = append(t, aggTelemetry{"week leader counts", n, b, filtered})
+	}
+	if a.leaderParents != nil {
+		n, b := 0, int64(0)
+		for k, m := range a.leaderParents {
+			n += len(m)
+			b += int64(stringHeader+len(k)) + 8 + mapEntryOverhead + int64(len(m))*(16+mapEntryOverhead)
+		}
+		t = append(t, aggTelemetry{"leader parent counts", n, b, filtered})
+	}
+	if a.perWeekdayLeader != nil {
+		n, b := 0, int64(0)
+		for _, m := range a.perWeekdayLeader {
+			n += len(m)
+			b += stringIntMapBytes(m)
+		}
+		t = append(t, aggTelemetry{"weekday leaders", n, b, filtered})
+	}
+	if a.perHourWeekday != nil {
+		t = append(t, aggTelemetry{"hour of week", 24 * 7, 24 * 7 * 8, filtered})
+	}
+	if a.perDayLeaders != nil {
+		n, b := 0, int64(0)
+		for d, list := range a.perDayLeaders {
+			b += int64(stringHeader+len(d)) + 24 + mapEntryOverhead
+			for _, l := range list {
+				b += int64(stringHeader + len(l))
+			}
+			n += len(list)
+		}
+		t = append(t, aggTelemetry{"day leaders", n, b, filtered})
+	}
+	if a.leaderLastSeen != nil {
+		var b int64
+		for k := range a.leaderLastSeen {
+			b += int64(stringHeader+len(k)) + timeSize + mapEntryOverhead
+		}
+		t = append(t, aggTelemetry{"leader last seen (all)", len(a.leaderLastSeen), b, all})
+	}
+	if a.groups != nil {
+		n, b := a.groups.size()
+		t = append(t, aggTelemetry{"group-by " + a.groups.dim, n, b, filtered})
+	}
+	if a.values != nil {
+		var b int64
+		for k := range a.values {
+			b += int64(stringHeader+len(k)) + 32 + mapEntryOverhead
+		}
+		t = append(t, aggTelemetry{"value aggregates", len(a.values), b, filtered})
+	}
+	if a.replay != nil {
+		n, b := a.replay.size()
+		t = append(t, aggTelemetry{"ordered replay", n, b, filtered})
+	}
+	if a.parentMonths != nil {
+		n := len(a.parentMonths)
+		t = append(t, aggTelemetry{"parent months (all)", n, int64(n) * (8 + 2 + mapEntryOverhead), all})
+	}
+	if a.isoWeekDays != nil {
+		t = append(t, aggTelemetry{"ISO week days (all)", len(a.isoWeekDays), int64(len(a.isoWeekDays)), all})
+	}
+	if a.coActivity != nil {
+		n := len(a.coActivity.pairs)
+		t = append(t, aggTelemetry{"parent co-activity", n, int64(n) * (16 + 8 + mapEntryOverhead), filtered})
+	}
+	if a.movers != nil {
+		c := a.movers.counts
+		t = append(t, aggTelemetry{"movers", len(c[0]) + len(c[1]), stringIntMapBytes(c[0]) + stringIntMapBytes(c[1]), filtered})
+	}
+	if a.overlap != nil {
+		o := a.overlap
+		n := len(o.window) + len(o.counts) + len(o.storms)
+		b := int64(len(o.window))*56 + int64(len(o.counts))*(16+mapEntryOverhead) + int64(len(o.storms))*(2*timeSize+16)
+		t = append(t, aggTelemetry{"overlap windows", n, b, filtered})
+	}
+	if a.fieldSummary != nil {
+		n, b := a.fieldSummary.size()
+		t = append(t, aggTelemetry{"field values", n, b, filtered})
+	}
+	if a.dailyQuantiles != nil {
+		n, b := a.dailyQuantiles.size()
+		t = append(t, aggTelemetry{"daily quantiles", n, b, filtered})
+	}
+	if a.eventSizes != nil {
+		n, b := a.eventSizes.size()
+		t = append(t, aggTelemetry{"event sizes", n, b, filtered})
+	}
+	if a.leaderCoActivity != nil {
+		var b int64
+		for k := range a.leaderCoActivity.pairs {
+			b += int64(2*stringHeader+len(k[0])+len(k[1])) + 8 + mapEntryOverhead
+		}
+		t = append(t, aggTelemetry{"leader co-occurrence", len(a.leaderCoActivity.pairs), b, filtered})
+	}
+	if a.leaderGaps != nil {
+		var b int64
+		for k := range a.leaderGaps {
+			b += int64(stringHeader+len(k)) + 8 + 2*timeSize + 8 + 8 + timeSize + mapEntryOverhead
+		}
+		t = append(t, aggTelemetry{"leader gaps", len(a.leaderGaps), b, filtered})
+	}
+	if a.segments != nil {
+		n, b := a.segments.size()
+		t = append(t, aggTelemetry{"segments", n, b, filtered})
+	}
+	return t
+}
+
+// buildTelemetry reports the aggregations of this run; extra adds state held
+// outside aggregates.
+func buildTelemetry(a *aggregates, extra ...aggTelemetry) []section {
+	t := append(a.telemetry(), extra...)
+	s := section{
+		Key:     "telemetry",
+		Title:   "Aggregation Telemetry",
+		Columns: []string{"Aggregation", "Buckets", "Approx bytes", "Records"},
+		Data:    t,
+	}
+	var total int64
+	for _, e := range t {
+		s.Rows = append(s.Rows, []string{e.Name, strconv.Itoa(e.Buckets), strconv.FormatInt(e.Bytes, 10), strconv.Itoa(e.Records)})
+		total += e.Bytes
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("Total approx bytes: %d (estimated from contents, not heap profiling)", total))
+	return []section{s}
+}
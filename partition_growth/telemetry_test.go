@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// TestTelemetryRecords reads records that -y/-m filters out and records with
+// no usable date, and checks the telemetry record counts against the growth
+// totals: every record read is either counted in all years or a record
+// error, and the filtered aggregations see exactly the month total.
+func TestTelemetryRecords(t *testing.T) {
+	dir := t.TempDir()
+	records := []string{
+		event(1, "2025-03-01T10:00:00Z", "a"),
+		event(10, "2025-03-02T10:00:00Z", "a"),
+		event(20, "2025-03-31T23:00:00Z", "b"),
+		event(30, "2025-04-02T10:00:00Z", "b"),
+		event(40, "2024-03-02T10:00:00Z", "b"),
+		event(50, "garbage", "b"),
+		event(60, "", "b"),
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+	out, errOut, status := runTool(t, dir, "-f", "in.json", "-y", "2025", "-m", "3", "-a", "-telemetry",
+		"-group-by", "leader", "-output", "json")
+	if status != 0 {
+		t.Fatalf("exit %d\n%s", status, errOut)
+	}
+	var doc struct{ Sections []section }
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatal(err)
+	}
+	atoi := func(s string) int {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	var monthTotal, grandTotal, yearly, recordErrors int
+	var telemetry [][]string
+	for _, s := range doc.Sections {
+		switch s.Key {
+		case "month_weekly":
+			fmt.Sscanf(s.Notes[len(s.Notes)-1], "Total for 2025-03: %d", &monthTotal)
+		case "grand_total":
+			fmt.Sscanf(s.Notes[0], "Grand Total (All Years): %d splits", &grandTotal)
+		case "yearly":
+			for _, r := range s.Rows {
+				yearly += atoi(r[1])
+			}
+		case "record_errors":
+			for _, r := range s.Rows {
+				recordErrors += atoi(r[1])
+			}
+		case "telemetry":
+			telemetry = s.Rows
+		}
+	}
+	if monthTotal != 3 || grandTotal != 5 || yearly != grandTotal || recordErrors != 2 {
+		t.Fatalf("month %d, grand total %d, yearly %d, record errors %d", monthTotal, grandTotal, yearly, recordErrors)
+	}
+	seen := map[string]int{}
+	for _, r := range telemetry {
+		seen[r[0]] = atoi(r[3])
+	}
+	all, filtered := seen["per-day (all)"], seen["per-day (filtered)"]
+	if all+recordErrors != len(records) || all != grandTotal || filtered != monthTotal {
+		t.Errorf("telemetry records all %d, filtered %d; read %d, grand total %d, month total %d",
+			all, filtered, len(records), grandTotal, monthTotal)
+	}
+	if seen["group-by leader"] != filtered || seen["all dates"] != all {
+		t.Errorf("telemetry rows disagree:\n%v", telemetry)
+	}
+	for _, r := range telemetry {
+		if n := atoi(r[3]); n != all && n != filtered {
+			t.Errorf("%s: %d records, neither all (%d) nor filtered (%d)", r[0], n, all, filtered)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"partition_growth/events"
+)
+
+// ── -from / -to ───────────────────────────────────────────────────────────────
+
+// timeRange is the resolved -from/-to filter as a half-open interval
+// [From, To). A zero bound is open.
+type timeRange struct {
+	From, To time.Time
+}
+
+// resolveRange turns the -from/-to values into absolute instants in loc.
+//
+//   - -from is inclusive. A date without a time means the start of that day.
+//   - -to is inclusive by default: a date without a time covers the whole
+//     day, so -to 2025-02-28 ends at 2025-03-01 00:00 (exclusive), and a
+//     timestamp includes that instant.
+//   - With toExclusive, -to is the exclusive end as written (a bare date is
+//     the start of that day), giving a half-open range.
+func resolveRange(from, to string, toExclusive bool, loc *time.Location) (timeRange, error) {
+	var r timeRange
+	if from != "" {
+		t, err := events.ParseDateIn(from, loc)
+		if err != nil {
+			return r, fmt.Errorf("-from: %v", err)
+		}
+		r.From = t
+	}
+	if to != "" {
+		t, err := events.ParseDateIn(to, loc)
+		if err != nil {
+			return r, fmt.Errorf("-to: %v", err)
+		}
+		switch {
+		case toExclusive:
+		case isBareDate(to):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		default:
+			t = t.Add(time.Nanosecond)
+		}
+		r.To = t
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.To.After(r.From) {
+		return r, fmt.Errorf("-to resolves to %s, not after -from %s", r.To.Format(time.RFC3339Nano), r.From.Format(time.RFC3339Nano))
+	}
+	return r, nil
+}
+
+func isBareDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func (r timeRange) active() bool { return !r.From.IsZero() || !r.To.IsZero() }
+
+func (r timeRange) contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// buildRangeHeader states the resolved bounds so there is no doubt about
+// which events a report covers.
+func buildRangeHeader(r timeRange, loc *time.Location) section {
+	from, to := "(open)", "(open)"
+	if !r.From.IsZero() {
+		from = r.From.In(loc).Format(time.RFC3339Nano) + " inclusive"
+	}
+	if !r.To.IsZero() {
+		to = r.To.In(loc).Format(time.RFC3339Nano) + " exclusive"
+	}
+	return section{
+		Key:     "range",
+		Title:   "Range",
+		Columns: []string{"Bound", "Timestamp"},
+		Rows:    [][]string{{"From", from}, {"To", to}},
+	}
+}
+
+// ── -date-trunc-offset ────────────────────────────────────────────────────────
+
+// parseTruncOffset reads a fixed UTC offset such as "+5:30", "-08:00" or
+// "+0530" and returns a zone for it. Unlike -tz it needs no time zone
+// database, and being fixed it has no daylight-saving changes.
+func parseTruncOffset(s string) (*time.Location, error) {
+	v := s
+	sign := 1
+	switch {
+	case len(v) > 0 && v[0] == '+':
+		v = v[1:]
+	case len(v) > 0 && v[0] == '-':
+		sign, v = -1, v[1:]
+	}
+	var h, m int
+	var err error
+	switch {
+	case len(v) == 4 && !strings.Contains(v, ":"):
+		_, err = fmt.Sscanf(v, "%02d%02d", &h, &m)
+	default:
+		hs, ms, found := strings.Cut(v, ":")
+		if h, err = strconv.Atoi(hs); err == nil && found {
+			m, err = strconv.Atoi(ms)
+		}
+	}
+	if err != nil || h < 0 || h > 14 || m < 0 || m > 59 || (h == 14 && m > 0) {
+		return nil, fmt.Errorf("invalid offset %q (want e.g. +5:30 or -08:00)", s)
+	}
+	secs := sign * (h*3600 + m*60)
+	name := "UTC"
+	if secs != 0 {
+		name = fmt.Sprintf("UTC%+03d:%02d", sign*h, m)
+		if sign < 0 && h == 0 {
+			name = fmt.Sprintf("UTC-00:%02d", m)
+		}
+	}
+	return time.FixedZone(name, secs), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveRange(t *testing.T) {
+	plus2 := time.FixedZone("UTC+02:00", 2*3600)
+	tests := []struct {
+		name      string
+		from, to  string
+		exclusive bool
+		loc       *time.Location
+		wantFrom  string // RFC 3339, "" for open
+		wantTo    string
+	}{
+		{"open", "", "", false, time.UTC, "", ""},
+		{"bare dates", "2025-01-01", "2025-01-31", false, time.UTC, "2025-01-01T00:00:00Z", "2025-02-01T00:00:00Z"},
+		{"leap day", "", "2024-02-29", false, time.UTC, "", "2024-03-01T00:00:00Z"},
+		{"end of February", "", "2025-02-28", false, time.UTC, "", "2025-03-01T00:00:00Z"},
+		{"end of year", "", "2025-12-31", false, time.UTC, "", "2026-01-01T00:00:00Z"},
+		{"bare date in loc", "2025-03-01", "2025-03-01", false, plus2, "2025-03-01T00:00:00+02:00", "2025-03-02T00:00:00+02:00"},
+		{"exclusive bare date", "", "2025-02-28", true, time.UTC, "", "2025-02-28T00:00:00Z"},
+		{"exclusive timestamp", "", "2025-02-28T12:00:00Z", true, time.UTC, "", "2025-02-28T12:00:00Z"},
+		{"timestamp", "2025-02-28T12:00:00Z", "2025-02-28T12:00:00Z", false, time.UTC,
+			"2025-02-28T12:00:00Z", "2025-02-28T12:00:00.000000001Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := resolveRange(tt.from, tt.to, tt.exclusive, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			format := func(t time.Time) string {
+				if t.IsZero() {
+					return ""
+				}
+				return t.Format(time.RFC3339Nano)
+			}
+			if got := format(r.From); got != tt.wantFrom {
+				t.Errorf("From = %s, want %s", got, tt.wantFrom)
+			}
+			if got := format(r.To); got != tt.wantTo {
+				t.Errorf("To = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestResolveRangeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		exclusive bool
+		msg       string
+	}{
+		{"to before from", "2025-03-02", "2025-03-01", false, "not after -from"},
+		{"exclusive to equals from", "2025-03-01", "2025-03-01", true, "not after -from"},
+		{"timestamp to before from", "2025-03-01T12:00:00Z", "2025-03-01T11:59:59Z", false, "not after -from"},
+		{"bad from", "March", "", false, "-from:"},
+		{"bad to", "", "2025-02-30", false, "-to:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveRange(tt.from, tt.to, tt.exclusive, time.UTC)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("error %v, want one mentioning %q", err, tt.msg)
+			}
+		})
+	}
+}
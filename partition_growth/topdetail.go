@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ── -top-detail ───────────────────────────────────────────────────────────────
+
+const topDetailN = 3
+
+// topDetail counts, for every month and ISO week of -y, the events of each
+// parent or leader, so the -t lists can name who made a bucket busy. Each
+// bucket is a groupCounter, so -approx bounds its distinct values as it does
+// for -group-by.
+type topDetail struct {
+	dim       string
+	year      int
+	threshold int
+	order     sortOrder                // -sort of each bucket's listing
+	months    map[string]*groupCounter // "YYYY-MM"
+	weeks     map[string]*groupCounter // "YYYY-Www", ISO week-year
+}
+
+func newTopDetail(dim string, year, threshold int, order sortOrder) *topDetail {
+	return &topDetail{dim: dim, year: year, threshold: threshold, order: order,
+		months: make(map[string]*groupCounter), weeks: make(map[string]*groupCounter)}
+}
+
+// add counts an event of any date; like the -t lists it ignores -m and -d.
+func (d *topDetail) add(dt time.Time, k eventKeys, evt Event) {
+	if dt.Year() == d.year {
+		d.counter(d.months, k.Month).add(evt)
+	}
+	if y, _ := dt.ISOWeek(); y == d.year {
+		d.counter(d.weeks, k.ISOWeek).add(evt)
+	}
+}
+
+func (d *topDetail) counter(m map[string]*groupCounter, key string) *groupCounter {
+	g := m[key]
+	if g == nil {
+		g = newGroupCounter(d.dim, d.threshold)
+		m[key] = g
+	}
+	return g
+}
+
+// attach nests the top contributors of each bucket under the rows of s,
+// whose i-th row is bucket keys[i]. Approximate counts are shown at their
+// lower bound, so the shares of a bucket never sum past 100%.
+func (d *topDetail) attach(s *section, m map[string]*groupCounter, keys []string) {
+	label := "parentId"
+	if d.dim == "leader" {
+		label = "leader"
+	}
+	s.SubFmt = "    " + label + " %s: %s (%s)"
+	approx := false
+	for _, k := range keys {
+		var sub [][]string
+		if g := m[k]; g != nil {
+			total := g.total()
+			items := g.top(topDetailN)
+			d.order.sort(items)
+			for _, it := range items {
+				n := it.Count - it.Err
+				sub = append(sub, []string{it.Key, strconv.Itoa(n), pct(n, total)})
+			}
+			approx = approx || g.approx != nil
+		}
+		s.SubRows = append(s.SubRows, sub)
+	}
+	if approx {
+		s.Notes = append(s.Notes, fmt.Sprintf("Some buckets had more than %d distinct %s values; their counts are lower bounds.",
+			d.threshold, d.dim))
+	}
+}
@@ -0,0 +1,155 @@
+// Package topk provides bounded-memory heavy-hitter counting.
+//
+// SpaceSaving implements the Space-Saving algorithm (Metwally, Agrawal and
+// El Abbadi, 2005). It monitors at most Capacity keys; when a new key arrives
+// and the table is full, the key with the smallest count is evicted and the
+// new key inherits that count as its error. For every monitored key
+//
+//	Count - Err <= true count <= Count
+//
+// and every unmonitored key has a true count no larger than the smallest
+// monitored Count, which is itself at most N/Capacity for N observations.
+package topk
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Item is one estimated key.
+type Item struct {
+	Key   string
+	Count int // upper bound of the true count
+	Err   int // maximum overestimation; Count-Err is a lower bound
+}
+
+// SpaceSaving is a fixed-capacity heavy-hitter counter. The zero value is
+// not usable; call New.
+type SpaceSaving struct {
+	capacity int
+	total    int
+	index    map[string]*entry
+	h        minHeap
+}
+
+type entry struct {
+	Item
+	pos int
+}
+
+// New returns a counter monitoring at most capacity keys.
+func New(capacity int) *SpaceSaving {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &SpaceSaving{capacity: capacity, index: make(map[string]*entry, capacity)}
+}
+
+// FromCounts builds a counter from exact counts, keeping the capacity
+// largest keys. The dropped keys are no larger than any kept one, so the
+// error guarantees hold from the start.
+func FromCounts(capacity int, counts map[string]int) *SpaceSaving {
+	s := New(capacity)
+	items := make([]Item, 0, len(counts))
+	for k, n := range counts {
+		items = append(items, Item{Key: k, Count: n})
+		s.total += n
+	}
+	sortItems(items)
+	if len(items) > s.capacity {
+		items = items[:s.capacity]
+	}
+	for _, it := range items {
+		e := &entry{Item: it}
+		s.index[it.Key] = e
+		heap.Push(&s.h, e)
+	}
+	return s
+}
+
+// Add counts one observation of key.
+func (s *SpaceSaving) Add(key string) {
+	s.total++
+	if e, ok := s.index[key]; ok {
+		e.Count++
+		heap.Fix(&s.h, e.pos)
+		return
+	}
+	if len(s.h) < s.capacity {
+		e := &entry{Item: Item{Key: key, Count: 1}}
+		s.index[key] = e
+		heap.Push(&s.h, e)
+		return
+	}
+	min := s.h[0]
+	delete(s.index, min.Key)
+	min.Err = min.Count
+	min.Key = key
+	min.Count++
+	s.index[key] = min
+	heap.Fix(&s.h, 0)
+}
+
+// Total returns the number of observations.
+func (s *SpaceSaving) Total() int { return s.total }
+
+// Len returns the number of monitored keys.
+func (s *SpaceSaving) Len() int { return len(s.h) }
+
+// MaxError returns the bound on the count of any unmonitored key, which is
+// also the largest possible Err.
+func (s *SpaceSaving) MaxError() int {
+	if len(s.h) < s.capacity {
+		return 0
+	}
+	return s.h[0].Count
+}
+
+// Top returns the k monitored keys with the highest counts, ordered by count
+// descending and key ascending.
+func (s *SpaceSaving) Top(k int) []Item {
+	items := make([]Item, 0, len(s.h))
+	for _, e := range s.h {
+		items = append(items, e.Item)
+	}
+	sortItems(items)
+	if k >= 0 && len(items) > k {
+		items = items[:k]
+	}
+	return items
+}
+
+func sortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+}
+
+type minHeap []*entry
+
+func (h minHeap) Len() int { return len(h) }
+func (h minHeap) Less(i, j int) bool {
+	if h[i].Count != h[j].Count {
+		return h[i].Count < h[j].Count
+	}
+	return h[i].Key > h[j].Key
+}
+func (h minHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+func (h *minHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.pos = len(*h)
+	*h = append(*h, e)
+}
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
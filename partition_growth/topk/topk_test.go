@@ -0,0 +1,129 @@
+package topk
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// zipfStream returns n keys drawn from a Zipf distribution over distinct
+// keys, and their exact counts.
+func zipfStream(seed int64, s float64, distinct uint64, n int) ([]string, map[string]int) {
+	rng := rand.New(rand.NewSource(seed))
+	z := rand.NewZipf(rng, s, 1, distinct-1)
+	keys := make([]string, n)
+	exact := make(map[string]int)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", z.Uint64())
+		exact[keys[i]]++
+	}
+	return keys, exact
+}
+
+func TestSpaceSavingBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        float64
+		distinct uint64
+		n        int
+		capacity int
+	}{
+		{"heavy skew", 2.0, 10000, 50000, 20},
+		{"mild skew", 1.1, 10000, 50000, 100},
+		{"near uniform", 1.01, 500, 20000, 50},
+		{"capacity of one", 1.5, 100, 1000, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, exact := zipfStream(1, tt.s, tt.distinct, tt.n)
+			ss := New(tt.capacity)
+			for _, k := range keys {
+				ss.Add(k)
+			}
+			if ss.Total() != tt.n {
+				t.Errorf("Total() = %d, want %d", ss.Total(), tt.n)
+			}
+			if ss.Len() > tt.capacity {
+				t.Errorf("Len() = %d above capacity %d", ss.Len(), tt.capacity)
+			}
+			maxErr := ss.MaxError()
+			if maxErr > tt.n/tt.capacity {
+				t.Errorf("MaxError() = %d above N/capacity = %d", maxErr, tt.n/tt.capacity)
+			}
+			monitored := make(map[string]bool)
+			for _, it := range ss.Top(-1) {
+				monitored[it.Key] = true
+				if it.Count-it.Err > exact[it.Key] || exact[it.Key] > it.Count {
+					t.Errorf("%s: exact %d outside [%d, %d]", it.Key, exact[it.Key], it.Count-it.Err, it.Count)
+				}
+				if it.Err > maxErr {
+					t.Errorf("%s: Err %d above MaxError %d", it.Key, it.Err, maxErr)
+				}
+			}
+			for k, n := range exact {
+				if !monitored[k] && n > maxErr {
+					t.Errorf("unmonitored %s has %d events, above MaxError %d", k, n, maxErr)
+				}
+				if n > tt.n/tt.capacity && !monitored[k] {
+					t.Errorf("heavy hitter %s (%d) not monitored", k, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSpaceSavingExactBelowCapacity(t *testing.T) {
+	keys, exact := zipfStream(2, 1.5, 40, 5000)
+	ss := New(len(exact) + 1)
+	for _, k := range keys {
+		ss.Add(k)
+	}
+	if ss.MaxError() != 0 {
+		t.Errorf("MaxError() = %d with room for every key", ss.MaxError())
+	}
+	got := make(map[string]int)
+	for _, it := range ss.Top(-1) {
+		if it.Err != 0 {
+			t.Errorf("%s: Err %d with room for every key", it.Key, it.Err)
+		}
+		got[it.Key] = it.Count
+	}
+	if !reflect.DeepEqual(got, exact) {
+		t.Errorf("counts differ from exact:\n%v\n%v", got, exact)
+	}
+}
+
+func TestTopOrder(t *testing.T) {
+	ss := New(10)
+	for _, k := range []string{"b", "a", "c", "b", "a", "d", "b"} {
+		ss.Add(k)
+	}
+	want := []Item{{"b", 3, 0}, {"a", 2, 0}, {"c", 1, 0}}
+	if got := ss.Top(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top(3) = %v, want %v", got, want)
+	}
+	if got := ss.Top(0); len(got) != 0 {
+		t.Errorf("Top(0) = %v, want none", got)
+	}
+}
+
+func TestFromCounts(t *testing.T) {
+	counts := map[string]int{"a": 5, "b": 9, "c": 1, "d": 5}
+	ss := FromCounts(3, counts)
+	if ss.Total() != 20 {
+		t.Errorf("Total() = %d, want 20", ss.Total())
+	}
+	want := []Item{{"b", 9, 0}, {"a", 5, 0}, {"d", 5, 0}}
+	if got := ss.Top(-1); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top(-1) = %v, want %v", got, want)
+	}
+	if ss.MaxError() != 5 {
+		t.Errorf("MaxError() = %d, want 5", ss.MaxError())
+	}
+	ss.Add("c") // evicts d, the higher key of the two smallest
+	want = []Item{{"b", 9, 0}, {"c", 6, 5}, {"a", 5, 0}}
+	if got := ss.Top(-1); !reflect.DeepEqual(got, want) {
+		t.Errorf("after adding an unmonitored key: %v, want %v", got, want)
+	}
+}
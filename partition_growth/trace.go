@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"partition_growth/events"
+)
+
+// ── -trace-record ─────────────────────────────────────────────────────────────
+
+// eventKeys are the bucket keys of one event. processEvent and the tracer
+// both derive them here so a trace shows exactly the buckets that are counted.
+type eventKeys struct {
+	Day, Month, Quarter string
+	Week                string // calendar year and ISO week number
+	ISOWeek             string // ISO week-year and week number
+	Year                int
+}
+
+func keysFor(dt time.Time) eventKeys {
+	isoYear, isoWeek := dt.ISOWeek()
+	return eventKeys{
+		Day:     dt.Format("2006-01-02"),
+		Month:   dt.Format("2006-01"),
+		Quarter: fmt.Sprintf("%d-Q%d", dt.Year(), getQuarter(dt.Month())),
+		Week:    fmt.Sprintf("%d-W%02d", dt.Year(), isoWeek),
+		ISOWeek: fmt.Sprintf("%04d-W%02d", isoYear, isoWeek),
+		Year:    dt.Year(),
+	}
+}
+
+// traceFilter is one report filter as the tracer evaluates it.
+type traceFilter struct {
+	name string
+	pass func(dt time.Time, evt Event) bool
+}
+
+// tracer explains, for up to limit records matching expr, how each record
+// was parsed, which filters it passed and which buckets it was counted in.
+// It sees records as they are decoded, before aggregation, and evaluates the
+// same parse and filter steps; files restored from a -resume journal are not
+// traced.
+type tracer struct {
+	expr     whereExpr
+	limit    int
+	n        int
+	w        io.Writer
+	read     *time.Location // -tz, for zone-less dates
+	loc      *time.Location // bucket zone: -tz or -date-trunc-offset
+	midnight *midnightPolicy
+	offsets  *isoOffsets
+	filters  []traceFilter
+	dump     *eventDump // nil without -dump
+}
+
+// record traces one decoded record if it matches. Records whose date cannot
+// be parsed are matched with the zero time, so date fields never match them.
+func (t *tracer) record(raw []byte, prov events.Provenance, evt Event, yopts events.YearOptions) {
+	if t.n >= t.limit {
+		return
+	}
+	dt, how, err := events.ParseDateYears(evt.Date, t.read, yopts)
+	midnight := false
+	if err != nil {
+		dt = time.Time{}
+	} else {
+		off, zoned := events.DateOffset(evt.Date)
+		dt, midnight, _ = t.midnight.place(dt, off, zoned, t.offsets.zone(off, zoned, t.loc))
+	}
+	if !t.expr.matches(dt, evt) {
+		return
+	}
+	t.n++
+	w := t.w
+	fmt.Fprintf(w, "trace %d: %s (byte offset %d)\n", t.n, prov, prov.Offset)
+	var compact bytes.Buffer
+	if json.Compact(&compact, raw) != nil {
+		compact.Reset()
+		compact.Write(raw)
+	}
+	fmt.Fprintf(w, "  raw:      %s\n", compact.String())
+	fmt.Fprintf(w, "  date:     %q\n", evt.Date)
+	if err != nil || dt.IsZero() {
+		fmt.Fprintf(w, "  skipped:  %v; not counted; listed under Record Errors\n", err)
+		return
+	}
+	if parsed, perr := events.ParseDate(evt.Date); perr == nil {
+		fmt.Fprintf(w, "  parsed:   %s (before -tz)\n", parsed.Format(time.RFC3339Nano))
+	} else {
+		fmt.Fprintf(w, "  parsed:   year inferred (%s)\n", inferenceName(how))
+	}
+	if midnight {
+		fmt.Fprintf(w, "  midnight: written at 00:00:00; -midnight-policy %s\n", t.midnight.name)
+	}
+	fmt.Fprintf(w, "  bucketed: %s (%s)\n", dt.Format(time.RFC3339Nano), t.loc)
+	k := keysFor(dt)
+	fmt.Fprintf(w, "  all-event buckets: day %s, month %s, quarter %s, ISO week %s, year %d\n",
+		k.Day, k.Month, k.Quarter, k.ISOWeek, k.Year)
+	failed := ""
+	for _, f := range t.filters {
+		outcome := "pass"
+		if !f.pass(dt, evt) {
+			outcome = "fail"
+			if failed == "" {
+				failed = f.name
+			}
+		}
+		fmt.Fprintf(w, "  filter %s: %s\n", f.name, outcome)
+	}
+	if failed != "" {
+		fmt.Fprintf(w, "  skipped:  fails %s; not in filtered buckets or reports\n", failed)
+		return
+	}
+	fmt.Fprintf(w, "  filtered buckets: day %s, week %s\n", k.Day, k.Week)
+	if t.dump != nil {
+		dumped := "yes"
+		if !t.dump.where.matches(dt, evt) {
+			dumped = "no, fails -dump-where"
+		}
+		fmt.Fprintf(w, "  dumped:   %s\n", dumped)
+	}
+}
+
+func inferenceName(how events.Inference) string {
+	switch how {
+	case events.InferTwoDigitYear:
+		return "two-digit year"
+	case events.InferAssumedYear:
+		return "assumed year"
+	}
+	return "explicit year"
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ── -type / -by-type ──────────────────────────────────────────────────────────
+//
+// Unified exports mix event kinds in one file, tagged by a type field. -type
+// counts only records of one kind; the others are tallied, not rejected, and
+// never reach date parsing. -by-type keeps every kind and adds a per-type
+// monthly breakdown of the filtered events.
+
+// noType labels records without the type field under -type-missing own-bucket
+// (and include, when no -type names the type they join).
+const noType = "(none)"
+
+var typeMissingPolicies = []string{"include", "exclude", "own-bucket"}
+
+// typeFilter selects records by their -type-field value.
+type typeFilter struct {
+	field   string // empty unless -type or -by-type is in use
+	want    string // -type; empty keeps every type
+	missing string // -type-missing policy
+
+	skipped  map[string]int            // type -> records not counted
+	perMonth map[string]map[string]int // "YYYY-MM" -> type -> filtered count, -by-type
+}
+
+func newTypeFilter(field, want, missing string, byType bool) (*typeFilter, error) {
+	valid := false
+	for _, p := range typeMissingPolicies {
+		valid = valid || p == missing
+	}
+	switch {
+	case !valid:
+		return nil, fmt.Errorf("unknown -type-missing %q (want include, exclude or own-bucket)", missing)
+	case want != "" && byType:
+		return nil, fmt.Errorf("-by-type needs all types; drop -type %s", want)
+	case field == "" && (want != "" || byType):
+		return nil, fmt.Errorf("-type and -by-type need -type-field")
+	}
+	t := &typeFilter{want: want, missing: missing, skipped: make(map[string]int)}
+	if want != "" || byType {
+		t.field = field
+	}
+	if byType {
+		t.perMonth = make(map[string]map[string]int)
+	}
+	return t, nil
+}
+
+// keep returns the type label of a record and whether it is counted.
+func (t *typeFilter) keep(evt Event) (string, bool) {
+	if evt.HasType {
+		return evt.Type, t.want == "" || evt.Type == t.want
+	}
+	switch t.missing {
+	case "include":
+		if t.want != "" {
+			return t.want, true
+		}
+		return noType, true
+	case "own-bucket":
+		return noType, t.want == ""
+	}
+	return noType, false
+}
+
+func (t *typeFilter) skip(label string) {
+	t.skipped[label]++
+}
+
+// add counts a filtered event of the given type for -by-type.
+func (t *typeFilter) add(monthKey, label string, w int) {
+	m := t.perMonth[monthKey]
+	if m == nil {
+		m = make(map[string]int)
+		t.perMonth[monthKey] = m
+	}
+	m[label] += w
+}
+
+// skippedSection lists the records that -type left out, by type.
+func (t *typeFilter) skippedSection() section {
+	s := section{
+		Key:     "skipped_types",
+		Title:   fmt.Sprintf("Records of Other Types (-type %s)", t.want),
+		Columns: []string{"Type", "Records"},
+	}
+	for _, l := range sortedByCount(t.skipped) {
+		s.Rows = append(s.Rows, []string{l, strconv.Itoa(t.skipped[l])})
+	}
+	return s
+}
+
+// byTypeSection is the -by-type month x type table.
+func (t *typeFilter) byTypeSection(num func(int) string) section {
+	totals := make(map[string]int)
+	months := make([]string, 0, len(t.perMonth))
+	for m, counts := range t.perMonth {
+		months = append(months, m)
+		for l, c := range counts {
+			totals[l] += c
+		}
+	}
+	sort.Strings(months)
+	types := sortedByCount(totals)
+	s := section{
+		Key:     "by_type",
+		Title:   "Events by Type per Month",
+		Columns: append(append([]string{"Month"}, types...), "Total"),
+	}
+	for _, m := range months {
+		row := []string{m}
+		total := 0
+		for _, l := range types {
+			row = append(row, num(t.perMonth[m][l]))
+			total += t.perMonth[m][l]
+		}
+		s.Rows = append(s.Rows, append(row, num(total)))
+	}
+	if len(months) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+	}
+	return s
+}
+
+// sortedByCount returns the keys of counts, largest count first.
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -aggregate ────────────────────────────────────────────────────────────────
+
+var aggregateFuncs = []string{"count", "sum", "max", "min"}
+
+// parseAggregateFuncs validates a comma-separated -aggregate list.
+func parseAggregateFuncs(spec string) ([]string, error) {
+	var out []string
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		ok := false
+		for _, known := range aggregateFuncs {
+			ok = ok || f == known
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown aggregate %q (available: %s)", f, strings.Join(aggregateFuncs, ", "))
+		}
+		out = append(out, f)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no aggregate functions in %q", spec)
+	}
+	return out, nil
+}
+
+// valueStats accumulates the Value field of the events in one period.
+type valueStats struct {
+	Count, Sum, Min, Max int
+}
+
+func (v *valueStats) add(n int) {
+	if v.Count == 0 || n < v.Min {
+		v.Min = n
+	}
+	if v.Count == 0 || n > v.Max {
+		v.Max = n
+	}
+	v.Count++
+	v.Sum += n
+}
+
+func (v valueStats) get(fn string) int {
+	switch fn {
+	case "sum":
+		return v.Sum
+	case "max":
+		return v.Max
+	case "min":
+		return v.Min
+	}
+	return v.Count
+}
+
+// valuePeriod picks the aggregation period from the filters: days within a
+// selected month, months within a selected year, years otherwise.
+func (a *aggregates) valuePeriod() (name, layout string) {
+	switch {
+	case a.opts.month != 0 && a.opts.year != 0:
+		return "Day", "2006-01-02"
+	case a.opts.year != 0:
+		return "Month", "2006-01"
+	}
+	return "Year", "2006"
+}
+
+func (a *aggregates) addValue(dt time.Time, evt Event) {
+	_, layout := a.valuePeriod()
+	k := dt.Format(layout)
+	v := a.values[k]
+	v.add(evt.Value)
+	a.values[k] = v
+}
+
+func buildValueAggregates(a *aggregates) []section {
+	period, _ := a.valuePeriod()
+	s := section{
+		Key:     "value_aggregates",
+		Title:   fmt.Sprintf("Value Aggregates by %s", strings.ToLower(period)),
+		Columns: []string{period},
+	}
+	for _, fn := range a.opts.aggregates {
+		s.Columns = append(s.Columns, fn)
+	}
+	keys := make([]string, 0, len(a.values))
+	for k := range a.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var all valueStats
+	for _, k := range keys {
+		v := a.values[k]
+		row := []string{k}
+		for _, fn := range a.opts.aggregates {
+			row = append(row, strconv.Itoa(v.get(fn)))
+		}
+		s.Rows = append(s.Rows, row)
+		if all.Count == 0 || v.Min < all.Min {
+			all.Min = v.Min
+		}
+		if all.Count == 0 || v.Max > all.Max {
+			all.Max = v.Max
+		}
+		all.Count += v.Count
+		all.Sum += v.Sum
+	}
+	if len(keys) == 0 {
+		s.Notes = append(s.Notes, "No events matched.")
+		return []section{s}
+	}
+	row := []string{"Total"}
+	for _, fn := range a.opts.aggregates {
+		row = append(row, strconv.Itoa(all.get(fn)))
+	}
+	s.Rows = append(s.Rows, row)
+	return []section{s}
+}
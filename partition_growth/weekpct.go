@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ── -week-percentiles ─────────────────────────────────────────────────────────
+
+// weekPercentilesData is the JSON payload of the week percentiles section.
+type weekPercentilesData struct {
+	Weeks   int    `json:"weeks"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	P50     int    `json:"p50"`
+	P90     int    `json:"p90"`
+	P99     int    `json:"p99"`
+	Max     int    `json:"max"`
+	P99Week string `json:"p99_week"`
+	MaxWeek string `json:"max_week"`
+}
+
+// isoWeeksBetween lists the ISO weeks ("YYYY-Www") from the one holding first
+// to the one holding last, in order.
+func isoWeeksBetween(first, last time.Time) []string {
+	monday := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	var weeks []string
+	for t := monday; !t.After(last); t = t.AddDate(0, 0, 7) {
+		weeks = append(weeks, keysFor(t).ISOWeek)
+	}
+	return weeks
+}
+
+// buildWeekPercentiles summarizes the per-ISO-week counts of all events, with
+// the weeks between the first and the last event that had none counted as
+// zero. The exemplar of a value is the earliest week with exactly that count.
+func buildWeekPercentiles(a *aggregates) []section {
+	s := section{
+		Key:     "week_percentiles",
+		Title:   "Events per ISO Week",
+		Columns: []string{"Measure", "Events", "Week"},
+	}
+	days := make([]string, 0, len(a.perDayAll))
+	for d := range a.perDayAll {
+		days = append(days, d)
+	}
+	if len(days) == 0 {
+		s.Notes = append(s.Notes, "No events.")
+		return []section{s}
+	}
+	sort.Strings(days)
+	first, _ := time.Parse("2006-01-02", days[0])
+	last, _ := time.Parse("2006-01-02", days[len(days)-1])
+	weeks := isoWeeksBetween(first, last)
+
+	counts := make([]int, len(weeks))
+	for i, w := range weeks {
+		counts[i] = a.perISOWeekAll[w]
+	}
+	sorted := append([]int(nil), counts...)
+	sort.Ints(sorted)
+	exemplar := func(v int) string {
+		for i, n := range counts {
+			if n == v {
+				return weeks[i]
+			}
+		}
+		return ""
+	}
+
+	d := weekPercentilesData{
+		Weeks: len(weeks),
+		From:  weeks[0],
+		To:    weeks[len(weeks)-1],
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+	d.P99Week, d.MaxWeek = exemplar(d.P99), exemplar(d.Max)
+	s.Rows = [][]string{
+		{"p50", strconv.Itoa(d.P50), ""},
+		{"p90", strconv.Itoa(d.P90), ""},
+		{"p99", strconv.Itoa(d.P99), d.P99Week},
+		{"max", strconv.Itoa(d.Max), d.MaxWeek},
+	}
+	zero := 0
+	for _, n := range counts {
+		if n == 0 {
+			zero++
+		}
+	}
+	s.Notes = append(s.Notes, fmt.Sprintf("%d ISO weeks from %s to %s, %d without events; filters are not applied.",
+		d.Weeks, d.From, d.To, zero))
+	s.Data = d
+	return []section{s}
+}
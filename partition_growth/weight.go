@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── -weight-field ─────────────────────────────────────────────────────────────
+
+// weightTotals keeps the weighted and the plain record totals of a
+// -weight-field or -with-weight run, so both reach the output.
+type weightTotals struct {
+	field     string // empty when every event counts 1
+	missingAs int    // -weight-default
+	dual      bool   // -with-weight: counts stay counts, sums go to side
+
+	records, recordsFiltered int
+	total, totalFiltered     int
+	missing                  int
+
+	side sideWeights
+}
+
+// sideWeights are the -with-weight sums kept beside the count maps of the
+// same names, filled in the same pass.
+type sideWeights struct {
+	perMonth      map[string]int // "YYYY-MM", all events
+	perISOWeekAll map[string]int // "YYYY-Www", all events
+	monthWeeks    map[int]int    // week 1..5 of -y -m, filtered
+}
+
+func newWeightTotals(field, dualField string, missingAs int) weightTotals {
+	w := weightTotals{field: field, missingAs: missingAs}
+	if dualField != "" {
+		w.field, w.dual = dualField, true
+		w.side = sideWeights{
+			perMonth:      make(map[string]int),
+			perISOWeekAll: make(map[string]int),
+			monthWeeks:    make(map[int]int),
+		}
+	}
+	return w
+}
+
+// addAll counts a record of the given weight; has is false when the weight
+// is -weight-default because the record lacked the field.
+func (w *weightTotals) addAll(weight int, has bool, k eventKeys) {
+	w.records++
+	w.total += weight
+	if !has {
+		w.missing++
+	}
+	if w.dual {
+		w.side.perMonth[k.Month] += weight
+		w.side.perISOWeekAll[k.ISOWeek] += weight
+	}
+}
+
+func (w *weightTotals) add(weight int) {
+	w.recordsFiltered++
+	w.totalFiltered += weight
+}
+
+// extend adds the -with-weight sum and average columns to a count section
+// whose rows have the given counts and sums.
+func (w *weightTotals) extend(s *section, counts, sums []int, num func(int) string) {
+	s.Columns = append(s.Columns, "Sum "+w.field, "Avg "+w.field)
+	for i := range s.Rows {
+		avg := "-"
+		if counts[i] > 0 {
+			avg = strconv.FormatFloat(float64(sums[i])/float64(counts[i]), 'f', 1, 64)
+		}
+		s.Rows[i] = append(s.Rows[i], num(sums[i]), avg)
+	}
+	if s.LineFmt != "" {
+		s.LineFmt += ", %s " + strings.ReplaceAll(w.field, "%", "%%") + " (avg %s)"
+	}
+}
+
+// byRank orders a top list by count, or by weight under -rank-by weight.
+// Ties go to the earlier key, so the list does not depend on map order.
+func byRank(weight bool, ci, cj, wi, wj int, ki, kj string) bool {
+	if weight && wi != wj {
+		return wi > wj
+	}
+	if !weight && ci != cj {
+		return ci > cj
+	}
+	return ki < kj
+}
+
+// section lists the totals both ways and how many records lacked the field.
+func (w *weightTotals) section(num func(int) string) section {
+	return section{
+		Key:     "weight",
+		Title:   "Weighting by " + w.field,
+		Columns: []string{"Measure", "All", "Filtered"},
+		Rows: [][]string{
+			{"Sum of " + w.field, num(w.total), num(w.totalFiltered)},
+			{"Records", strconv.Itoa(w.records), strconv.Itoa(w.recordsFiltered)},
+		},
+		Notes: []string{"Records without " + w.field + ": " + strconv.Itoa(w.missing) +
+			", each counted as " + strconv.Itoa(w.missingAs)},
+	}
+}
+
+// weightedLast30 returns the latest date, the date 30 days before it and the
+// sum of the weights on or after that date.
+func weightedLast30(dates []time.Time, weights []int) (latest, from time.Time, sum int) {
+	for _, d := range dates {
+		if d.After(latest) {
+			latest = d
+		}
+	}
+	from = latest.AddDate(0, 0, -30)
+	for i, d := range dates {
+		if !d.Before(from) {
+			sum += weights[i]
+		}
+	}
+	return latest, from, sum
+}
+
+// humanizeCount abbreviates n with a decimal unit suffix: 1234 -> 1.2K,
+// 5600000 -> 5.6M. Values below 1000 are unchanged.
+func humanizeCount(n int) string {
+	const units = "KMGTPE"
+	if n < 1000 && n > -1000 {
+		return strconv.Itoa(n)
+	}
+	v := float64(n)
+	i := -1
+	for (v >= 1000 || v <= -1000) && i < len(units)-1 {
+		v /= 1000
+		i++
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64) + units[i:i+1]
+}
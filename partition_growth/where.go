@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ── -where / -dump-where ──────────────────────────────────────────────────────
+//
+// A filter expression is a boolean combination of field comparisons:
+//
+//	leader == "node-3.example:9000" and (parent < 100 or value >= 500)
+//	not leader ~ "node-[12]*" && date >= 2024-06-01
+//
+// Fields: parent, first, second and value compare as integers, as do year,
+// month, day and hour of the timestamp in -tz; leader, date (YYYY-MM-DD in
+// -tz) and weekday (Mon..Sun) as strings. The JSON names parentId,
+// firstChildId, secondChildId and leaderNodeInfo are accepted as aliases.
+// enrich.<name> compares a field added by -enrich-cmd as a string; it is
+// empty for events the command gave no such field.
+// Operators are == != < <= > >= and, for strings, ~ and !~ which match a
+// path.Match glob. Strings may be quoted or bare; and/or/not may also be
+// written && || !. Expressions are compiled at startup so a typo fails before
+// any input is read.
+
+// whereExpr is a compiled filter expression; the zero value matches everything.
+type whereExpr struct {
+	src      string
+	match    func(dt time.Time, evt Event) bool
+	enriched bool // reads enrich.<name> fields
+}
+
+// compileWhere parses src; an empty src yields an expression that matches all.
+func compileWhere(src string) (whereExpr, error) {
+	if strings.TrimSpace(src) == "" {
+		return whereExpr{}, nil
+	}
+	toks, err := lexWhere(src)
+	if err != nil {
+		return whereExpr{}, err
+	}
+	p := &whereParser{toks: toks}
+	fn, err := p.or()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err != nil {
+		return whereExpr{}, err
+	}
+	return whereExpr{src: src, match: fn, enriched: p.enriched}, nil
+}
+
+// matches reports whether the event passes; the zero expression always does.
+func (w whereExpr) matches(dt time.Time, evt Event) bool {
+	return w.match == nil || w.match(dt, evt)
+}
+
+func (w whereExpr) String() string { return w.src }
+
+// whereValue implements flag.Value so the expression is checked at flag parse time.
+type whereValue struct{ expr whereExpr }
+
+func (v *whereValue) String() string { return v.expr.src }
+
+func (v *whereValue) Set(s string) error {
+	e, err := compileWhere(s)
+	if err != nil {
+		return err
+	}
+	v.expr = e
+	return nil
+}
+
+type whereToken struct {
+	text   string
+	quoted bool
+}
+
+func lexWhere(src string) ([]whereToken, error) {
+	var toks []whereToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			toks = append(toks, whereToken{text: string(c)})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad string at offset %d", i)
+			}
+			toks = append(toks, whereToken{text: s, quoted: true})
+			i = j + 1
+		case strings.ContainsRune("=!<>~&|", rune(c)):
+			op := string(c)
+			if i+1 < len(src) && strings.Contains("=~&|", string(src[i+1])) {
+				op = src[i : i+2]
+			}
+			switch op {
+			case "==", "!=", "<", "<=", ">", ">=", "~", "!~", "!", "&&", "||":
+			default:
+				return nil, fmt.Errorf("unknown operator %q at offset %d", op, i)
+			}
+			toks = append(toks, whereToken{text: op})
+			i += len(op)
+		default:
+			j := i
+			for j < len(src) && !unicode.IsSpace(rune(src[j])) && !strings.ContainsRune("()\"=!<>~&|", rune(src[j])) {
+				j++
+			}
+			toks = append(toks, whereToken{text: src[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+type whereParser struct {
+	toks     []whereToken
+	pos      int
+	enriched bool
+}
+
+type wherePred = func(dt time.Time, evt Event) bool
+
+// accept consumes the next token if it is an unquoted keyword in words.
+func (p *whereParser) accept(words ...string) bool {
+	if p.pos >= len(p.toks) || p.toks[p.pos].quoted {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(p.toks[p.pos].text, w) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *whereParser) next() (whereToken, error) {
+	if p.pos >= len(p.toks) {
+		return whereToken{}, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.toks[p.pos-1], nil
+}
+
+func (p *whereParser) or() (wherePred, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or", "||") {
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		a := l
+		l = func(dt time.Time, e Event) bool { return a(dt, e) || r(dt, e) }
+	}
+	return l, nil
+}
+
+func (p *whereParser) and() (wherePred, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and", "&&") {
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		a := l
+		l = func(dt time.Time, e Event) bool { return a(dt, e) && r(dt, e) }
+	}
+	return l, nil
+}
+
+func (p *whereParser) unary() (wherePred, error) {
+	if p.accept("not", "!") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(dt time.Time, e Event) bool { return !x(dt, e) }, nil
+	}
+	if p.accept("(") {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return x, nil
+	}
+	return p.comparison()
+}
+
+var whereIntFields = map[string]func(time.Time, Event) int{
+	"parent": func(_ time.Time, e Event) int { return e.ParentID },
+	"first":  func(_ time.Time, e Event) int { return e.FirstChildID },
+	"second": func(_ time.Time, e Event) int { return e.SecondChildID },
+	"value":  func(_ time.Time, e Event) int { return e.Value },
+	"year":   func(dt time.Time, _ Event) int { return dt.Year() },
+	"month":  func(dt time.Time, _ Event) int { return int(dt.Month()) },
+	"day":    func(dt time.Time, _ Event) int { return dt.Day() },
+	"hour":   func(dt time.Time, _ Event) int { return dt.Hour() },
+}
+
+var whereAliases = map[string]string{
+	"parentid":       "parent",
+	"firstchildid":   "first",
+	"secondchildid":  "second",
+	"leadernodeinfo": "leader",
+}
+
+var whereStringFields = map[string]func(time.Time, Event) string{
+	"leader":  func(_ time.Time, e Event) string { return e.LeaderNodeInfo },
+	"date":    func(dt time.Time, _ Event) string { return dt.Format("2006-01-02") },
+	"weekday": func(dt time.Time, _ Event) string { return dt.Format("Mon") },
+}
+
+func (p *whereParser) comparison() (wherePred, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	lit, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(field.text)
+	if alias, ok := whereAliases[name]; ok {
+		name = alias
+	}
+	if get, ok := whereIntFields[name]; ok && !field.quoted {
+		n, err := strconv.Atoi(lit.text)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs an integer, got %q", name, lit.text)
+		}
+		cmp, err := intComparison(op.text)
+		if err != nil {
+			return nil, err
+		}
+		return func(dt time.Time, e Event) bool { return cmp(get(dt, e), n) }, nil
+	}
+	get, ok := whereStringFields[name]
+	if f, isEnrich := enrichField(field.text); isEnrich && !field.quoted {
+		get, ok = func(_ time.Time, e Event) string { return e.Enriched[f] }, true
+		p.enriched = true
+	}
+	if ok && !field.quoted {
+		s := lit.text
+		switch op.text {
+		case "~", "!~":
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %v", s, err)
+			}
+			want := op.text == "~"
+			return func(dt time.Time, e Event) bool {
+				ok, _ := path.Match(s, get(dt, e))
+				return ok == want
+			}, nil
+		}
+		cmp, err := stringComparison(op.text)
+		if err != nil {
+			return nil, err
+		}
+		return func(dt time.Time, e Event) bool { return cmp(get(dt, e), s) }, nil
+	}
+	return nil, fmt.Errorf("unknown field %q (want parent, first, second, value, year, month, day, hour, leader, date, weekday or enrich.<name>)", field.text)
+}
+
+func intComparison(op string) (func(a, b int) bool, error) {
+	switch op {
+	case "==":
+		return func(a, b int) bool { return a == b }, nil
+	case "!=":
+		return func(a, b int) bool { return a != b }, nil
+	case "<":
+		return func(a, b int) bool { return a < b }, nil
+	case "<=":
+		return func(a, b int) bool { return a <= b }, nil
+	case ">":
+		return func(a, b int) bool { return a > b }, nil
+	case ">=":
+		return func(a, b int) bool { return a >= b }, nil
+	}
+	return nil, fmt.Errorf("operator %q does not apply to numbers", op)
+}
+
+func stringComparison(op string) (func(a, b string) bool, error) {
+	switch op {
+	case "==":
+		return func(a, b string) bool { return a == b }, nil
+	case "!=":
+		return func(a, b string) bool { return a != b }, nil
+	case "<":
+		return func(a, b string) bool { return a < b }, nil
+	case "<=":
+		return func(a, b string) bool { return a <= b }, nil
+	case ">":
+		return func(a, b string) bool { return a > b }, nil
+	case ">=":
+		return func(a, b string) bool { return a >= b }, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestYearCounter(t *testing.T) {
+	var c yearCounter
+	for _, tt := range []struct {
+		year, n int
+		ok      bool
+	}{
+		{1969, 1, false},
+		{1970, 2, true},
+		{2025, 3, true},
+		{2025, 4, true},
+		{4969, 5, true},
+		{4970, 6, false},
+		{2030, -3, true},
+		{2031, 2, true},
+		{2031, -2, true},
+	} {
+		if got := c.add(tt.year, tt.n); got != tt.ok {
+			t.Errorf("add(%d) = %v, want %v", tt.year, got, tt.ok)
+		}
+	}
+	m := map[int]int{2025: 1}
+	c.mergeInto(m)
+	want := map[int]int{1970: 2, 2025: 8, 2030: -3, 2031: 0, 4969: 5}
+	if fmt.Sprint(m) != fmt.Sprint(want) {
+		t.Errorf("merged %v, want %v", m, want)
+	}
+}
+
+func TestRunWorkersCountsEveryEvent(t *testing.T) {
+	for _, n := range []int{1, 2, 8} {
+		var years yearCounter
+		i := 0
+		next := func() (Event, bool, error) {
+			i++
+			return Event{ParentID: i}, i <= 10000, nil
+		}
+		if err := runWorkers(n, next, func(evt Event) { years.add(2000+evt.ParentID%3, 1) }); err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[int]int)
+		years.mergeInto(m)
+		if total := m[2000] + m[2001] + m[2002]; total != 10000 {
+			t.Errorf("-workers %d: counted %d of 10000 events", n, total)
+		}
+	}
+}
+
+// TestWorkersNegativeWeights sums -weight-field values that are negative in
+// one year and cancel out in another, and checks -workers 8 reports every
+// year with the sums -workers 1 gives.
+func TestWorkersNegativeWeights(t *testing.T) {
+	dir := t.TempDir()
+	var records []string
+	for i := 0; i < 3000; i++ {
+		w := []int{-3, 1, 2 - 4*(i/3%2)}[i%3] // 2023: -3 each; 2024: 1 each; 2025: +2 and -2 in turn
+		records = append(records, fmt.Sprintf(`{"parentId":%d,"date":"%d-%02d-%02dT10:00:00Z","leaderNodeInfo":"n","w":%d}`,
+			i, 2023+i%3, 1+i%12, 1+i%28, w))
+	}
+	writeFile(t, dir, "in.json", eventArray(records...))
+	var outs []string
+	for _, n := range []string{"1", "8"} {
+		out, errOut, status := runTool(t, dir, "-f", "in.json", "-weight-field", "w", "-a", "-workers", n)
+		if status != 0 {
+			t.Fatalf("-workers %s: exit %d\n%s", n, status, errOut)
+		}
+		for _, want := range []string{"2023: -3000 w\n", "2024: 1000 w\n", "2025: 0 w\n"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("-workers %s: output lacks %q:\n%s", n, want, out)
+			}
+		}
+		outs = append(outs, out)
+	}
+	if outs[0] != outs[1] {
+		t.Errorf("-workers 8 differs from -workers 1:\n%s\nwant:\n%s", outs[1], outs[0])
+	}
+}
+
+// BenchmarkRunWorkers runs events through the shape of processEvent: date
+// parsing and key formatting outside any lock, the year counted in
+// yearCounter ("atomic-year") or under the mutex ("locked-year"), then the
+// day, month and ISO week maps under the mutex. Compare the ns/op across
+// worker counts with -cpu set to the machine's cores.
+func BenchmarkRunWorkers(b *testing.B) {
+	dates := make([]string, 4096)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range dates {
+		dates[i] = t0.Add(time.Duration(i) * 97 * time.Minute).Format(time.RFC3339)
+	}
+	for _, mode := range []string{"atomic-year", "locked-year"} {
+		for _, n := range []int{1, 2, 4, 8, 16} {
+			b.Run(fmt.Sprintf("%s/workers=%d", mode, n), func(b *testing.B) {
+				var years yearCounter
+				var mu sync.Mutex
+				perYear := make(map[int]int)
+				perDay := make(map[string]int)
+				perMonth := make(map[string]int)
+				perWeek := make(map[string]int)
+				i := 0
+				next := func() (Event, bool, error) {
+					if i == b.N {
+						return Event{}, false, nil
+					}
+					evt := Event{Date: dates[i%len(dates)]}
+					i++
+					return evt, true, nil
+				}
+				process := func(evt Event) {
+					dt, err := time.Parse(time.RFC3339, evt.Date)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					y, w := dt.ISOWeek()
+					day, month, week := dt.Format("2006-01-02"), dt.Format("2006-01"), fmt.Sprintf("%04d-W%02d", y, w)
+					locked := mode == "locked-year" || !years.add(dt.Year(), 1)
+					mu.Lock()
+					if locked {
+						perYear[dt.Year()]++
+					}
+					perDay[day]++
+					perMonth[month]++
+					perWeek[week]++
+					mu.Unlock()
+				}
+				b.ResetTimer()
+				if err := runWorkers(n, next, process); err != nil {
+					b.Fatal(err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"partition_growth/events"
+)
+
+// ── -two-digit-year-pivot / -assume-year ──────────────────────────────────────
+
+// assumeYear is the parsed -assume-year: a fixed year or a pattern whose
+// first group captures the year from each input path.
+type assumeYear struct {
+	year    int
+	pattern *regexp.Regexp
+}
+
+func parseAssumeYear(v string) (assumeYear, error) {
+	if v == "" {
+		return assumeYear{}, nil
+	}
+	if expr, ok := strings.CutPrefix(v, "from-filename:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return assumeYear{}, err
+		}
+		if re.NumSubexp() < 1 {
+			return assumeYear{}, fmt.Errorf("pattern %q needs a group capturing the year", expr)
+		}
+		return assumeYear{pattern: re}, nil
+	}
+	y, err := strconv.Atoi(v)
+	if err != nil || y < 1 {
+		return assumeYear{}, fmt.Errorf("invalid year %q", v)
+	}
+	return assumeYear{year: y}, nil
+}
+
+// yearFor returns the year to assume for dates in path; 0 when none applies.
+func (a assumeYear) yearFor(path string) (int, error) {
+	if a.pattern == nil {
+		return a.year, nil
+	}
+	m := a.pattern.FindStringSubmatch(path)
+	if m == nil {
+		return 0, fmt.Errorf("no year in %s", path)
+	}
+	y, err := strconv.Atoi(m[1])
+	if err != nil || y < 1 {
+		return 0, fmt.Errorf("%q captured from %s is not a year", m[1], path)
+	}
+	return y, nil
+}
+
+// yearInference counts records whose year had to be inferred.
+type yearInference struct {
+	twoDigit, assumed int
+}
+
+func (y *yearInference) count(how events.Inference) {
+	switch how {
+	case events.InferTwoDigitYear:
+		y.twoDigit++
+	case events.InferAssumedYear:
+		y.assumed++
+	}
+}
+
+// section shows how much of the report rests on inferred years.
+func (y *yearInference) section(total int) section {
+	return section{
+		Key:     "year_inference",
+		Title:   "Inferred Years",
+		Columns: []string{"Source", "Records", "Share"},
+		Rows: [][]string{
+			{"Two-digit year", strconv.Itoa(y.twoDigit), pct(y.twoDigit, total)},
+			{"Assumed year", strconv.Itoa(y.assumed), pct(y.assumed, total)},
+			{"Explicit year", strconv.Itoa(total - y.twoDigit - y.assumed), pct(total-y.twoDigit-y.assumed, total)},
+		},
+	}
+}